@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+const auditUsage = `用法: heliox-mon audit <命令> [参数]
+
+  export [-since 日期] [-until 日期] [-event 事件] [-user 用户] [-ip IP] [-format csv|jsonl]
+                                按时间从旧到新导出审计日志到标准输出
+
+日期格式为 2006-01-02（按 TIMEZONE）或 RFC 3339，-until 不含当天以后的记录。
+`
+
+// auditCommand 审计日志子命令
+func auditCommand(db *storage.DB, cfg *config.Config, cmd string, args []string) error {
+	if cmd != "export" {
+		fmt.Fprint(os.Stderr, auditUsage)
+		return fmt.Errorf("未知命令: %s", cmd)
+	}
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	since := fs.String("since", "", "起始时间")
+	until := fs.String("until", "", "截止时间（不含）")
+	event := fs.String("event", "", "只导出该事件")
+	user := fs.String("user", "", "只导出该操作者")
+	ip := fs.String("ip", "", "只导出该来源 IP")
+	format := fs.String("format", "csv", "输出格式：csv 或 jsonl")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := auth.AuditQuery{Event: *event, Username: *user, IP: *ip}
+	var err error
+	if q.Since, err = parseCLITime(*since, cfg.Timezone); err != nil {
+		return err
+	}
+	if q.Until, err = parseCLITime(*until, cfg.Timezone); err != nil {
+		return err
+	}
+
+	switch *format {
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"id", "time", "event", "username", "target", "ip", "user_agent", "detail"})
+		err = auth.ExportAudit(db, q, func(rec auth.AuditRecord) error {
+			return w.Write([]string{
+				strconv.FormatInt(rec.ID, 10), rec.Time.In(cfg.Timezone).Format(time.RFC3339),
+				rec.Event, rec.Username, rec.Target, rec.IP, rec.UserAgent, rec.Detail,
+			})
+		})
+		w.Flush()
+		if err == nil {
+			err = w.Error()
+		}
+		return err
+
+	case "jsonl":
+		enc := json.NewEncoder(os.Stdout)
+		return auth.ExportAudit(db, q, func(rec auth.AuditRecord) error {
+			return enc.Encode(map[string]interface{}{
+				"id":         rec.ID,
+				"time":       rec.Time.In(cfg.Timezone).Format(time.RFC3339),
+				"event":      rec.Event,
+				"username":   rec.Username,
+				"target":     rec.Target,
+				"ip":         rec.IP,
+				"user_agent": rec.UserAgent,
+				"detail":     rec.Detail,
+			})
+		})
+	}
+	return errors.New("-format 只能是 csv 或 jsonl")
+}
+
+// parseCLITime 解析日期（按配置时区）或 RFC 3339，空字符串返回零值
+func parseCLITime(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无效的时间 %q（格式 2006-01-02 或 RFC 3339）", s)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/hh/heliox-mon/internal/auth"
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// runSubcommand 加载配置、打开数据库后执行管理子命令，返回进程退出码
+func runSubcommand(usage string, args []string, fn func(db *storage.DB, cfg *config.Config, cmd string, args []string) error) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return 1
+	}
+	db, err := storage.NewDB(cfg.DataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开数据库失败: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	// 与服务启动时一致，保证引导管理员存在（首次部署时可直接为其创建 Token）
+	if err := auth.EnsureBootstrapAdmin(db, cfg.Username, cfg.Password); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化管理员失败: %v\n", err)
+		return 1
+	}
+
+	if err := fn(db, cfg, args[0], args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// cliAudit 记录命令行执行的管理操作，操作者为 cli:<系统用户>（sudo 时取原用户）
+func cliAudit(db *storage.DB, event, target, detail string) {
+	actor := os.Getenv("SUDO_USER")
+	if actor == "" {
+		if u, err := user.Current(); err == nil {
+			actor = u.Username
+		}
+	}
+	auth.Audit(db, auth.AuditEntry{Event: event, Username: "cli:" + actor, Target: target, Detail: detail})
+}
@@ -7,47 +7,104 @@ import (
 	"syscall"
 
 	"github.com/hh/heliox-mon/internal/api"
+	"github.com/hh/heliox-mon/internal/auth"
 	"github.com/hh/heliox-mon/internal/collector"
 	"github.com/hh/heliox-mon/internal/config"
 	"github.com/hh/heliox-mon/internal/notifier"
 	"github.com/hh/heliox-mon/internal/storage"
+	"github.com/hh/heliox-mon/internal/systemd"
 )
 
 func main() {
+	// 管理子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "user":
+			os.Exit(runSubcommand(userUsage, os.Args[2:], userCommand))
+		case "token":
+			os.Exit(runSubcommand(tokenUsage, os.Args[2:], tokenCommand))
+		case "audit":
+			os.Exit(runSubcommand(auditUsage, os.Args[2:], auditCommand))
+		}
+	}
+
+	os.Exit(run())
+}
+
+// run 启动监控服务，返回进程退出码（先于 os.Exit 执行完所有 defer）
+func run() int {
 	// 加载配置
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("加载配置失败: %v", err)
+		log.Printf("加载配置失败: %v", err)
+		return 1
 	}
 
 	// 初始化数据库
 	db, err := storage.NewDB(cfg.DataDir)
 	if err != nil {
-		log.Fatalf("初始化数据库失败: %v", err)
+		log.Printf("初始化数据库失败: %v", err)
+		return 1
 	}
 	defer db.Close()
 
+	// 引导管理员（兼容 HELIOX_MON_USER/HELIOX_MON_PASS）
+	if err := auth.EnsureBootstrapAdmin(db, cfg.Username, cfg.Password); err != nil {
+		log.Printf("初始化管理员失败: %v", err)
+		return 1
+	}
+	if n, err := auth.CountUsers(db); err != nil || n == 0 {
+		log.Printf("没有可登录的用户: 请设置 HELIOX_MON_PASS 或执行 heliox-mon user add -role admin <用户名>")
+		return 1
+	}
+
+	// 与上次启动时的配置比较，变化写入审计日志
+	auth.AuditConfigChanges(db, cfg.Snapshot())
+
 	// 初始化通知器
 	ntf := notifier.New(cfg, db)
+	ntf.Start()
+	defer ntf.Stop()
 
 	// 初始化采集器
 	col := collector.New(cfg, db, ntf)
 	col.Start()
 	defer col.Stop()
 
-	// 启动 HTTP 服务
+	// 启动 HTTP 服务：先同步绑定端口，绑定失败时正常清理后退出
 	server := api.NewServer(cfg, db)
+	server.SetRealtime(col.Realtime())
+	server.SetNotifier(ntf)
+	if err := server.Listen(); err != nil {
+		log.Printf("HTTP 服务启动失败: %v", err)
+		return 1
+	}
+	ntf.StartBot(server)
+	errc := make(chan error, 1)
 	go func() {
-		if err := server.Start(); err != nil {
-			log.Fatalf("HTTP 服务启动失败: %v", err)
-		}
+		errc <- server.Start()
 	}()
 
+	// 通知 systemd 启动完成，并在采集循环正常推进时喂看门狗
+	status := "运行中，监听 " + server.ListenAddr()
+	systemd.Notify("READY=1\nSTATUS=" + status)
+	stopWatchdog := make(chan struct{})
+	defer close(stopWatchdog)
+	go systemd.Watchdog(stopWatchdog, status, col.CheckProgress)
+
 	// 优雅退出
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	code := 0
+	select {
+	case <-quit:
+	case err := <-errc:
+		log.Printf("HTTP 服务异常退出: %v", err)
+		code = 1
+	}
 
 	log.Println("正在关闭服务...")
+	systemd.Notify("STOPPING=1")
 	server.Stop()
+	return code
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+const tokenUsage = `用法: heliox-mon token <命令> [参数]
+
+  list [-user 用户名]            列出 API Token
+  add -user 用户名 -scopes 权限 [-expires 天数] [-allow-ip IP/CIDR,...] <名称>
+                                创建 Token（明文只显示一次）
+  revoke <ID>                   吊销 Token
+
+权限范围: read:traffic, read:system, read:latency, write:config, metrics（逗号分隔）
+`
+
+// tokenCommand API Token 管理子命令
+func tokenCommand(db *storage.DB, cfg *config.Config, cmd string, args []string) error {
+	switch cmd {
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ContinueOnError)
+		user := fs.String("user", "", "只列出该用户的 Token")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		tokens, err := auth.ListTokens(db, *user)
+		if err != nil {
+			return err
+		}
+		formatTime := func(t time.Time) string {
+			if t.IsZero() {
+				return "-"
+			}
+			return t.In(cfg.Timezone).Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-12s %-16s %-10s %-16s %-16s %s\n", "ID", "名称", "用户", "过期", "最近使用", "权限")
+		for _, t := range tokens {
+			fmt.Printf("%-12s %-16s %-10s %-16s %-16s %s\n", t.ID, t.Name, t.Username,
+				formatTime(t.ExpiresAt), formatTime(t.LastUsedAt), strings.Join(t.Scopes, ","))
+		}
+		return nil
+
+	case "add":
+		fs := flag.NewFlagSet("add", flag.ContinueOnError)
+		user := fs.String("user", cfg.Username, "所属用户，Token 权限不超过该用户角色")
+		scopes := fs.String("scopes", "", "权限范围，逗号分隔")
+		expires := fs.Int("expires", 0, "有效天数，0 表示永不过期")
+		allowIP := fs.String("allow-ip", "", "来源 IP 白名单（IP 或 CIDR，逗号分隔）")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("用法: heliox-mon token add -user 用户名 -scopes 权限 <名称>")
+		}
+		secret, t, err := auth.CreateToken(db, auth.TokenOptions{
+			Name:     fs.Arg(0),
+			Username: *user,
+			Scopes:   splitFlag(*scopes),
+			TTL:      time.Duration(*expires) * 24 * time.Hour,
+			AllowIPs: splitFlag(*allowIP),
+		})
+		if err != nil {
+			return err
+		}
+		cliAudit(db, auth.EventTokenCreated, t.ID, t.Username+"/"+t.Name+" "+strings.Join(t.Scopes, ","))
+		fmt.Printf("已创建 Token %s（ID %s，权限 %s）\n", t.Name, t.ID, strings.Join(t.Scopes, ","))
+		fmt.Printf("Token: %s\n", secret)
+		fmt.Println("请妥善保存，之后无法再次查看。使用方式: Authorization: Bearer <Token>")
+		return nil
+
+	case "revoke":
+		if len(args) != 1 {
+			return errors.New("用法: heliox-mon token revoke <ID>")
+		}
+		if err := auth.RevokeToken(db, args[0], ""); err != nil {
+			return err
+		}
+		cliAudit(db, auth.EventTokenRevoked, args[0], "")
+		fmt.Printf("已吊销 Token %s\n", args[0])
+		return nil
+	}
+
+	fmt.Fprint(os.Stderr, tokenUsage)
+	return fmt.Errorf("未知命令: %s", cmd)
+}
+
+// splitFlag 拆分逗号分隔的参数，忽略空项
+func splitFlag(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/hh/heliox-mon/internal/auth"
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+const userUsage = `用法: heliox-mon user <命令> [参数]
+
+  list                         列出用户
+  add [-role viewer] [-sso] <用户名>
+                               添加用户（角色 admin 或 viewer，默认 viewer）；
+                               -sso 添加只能通过单点登录登录的用户（不设密码）
+  del <用户名>                  删除用户及其会话和 API Token
+  passwd <用户名>               重置密码并注销其会话
+  role <用户名> <admin|viewer>  修改角色
+  unlock <用户名|IP>            解除登录失败锁定
+  2fa-reset <用户名>            关闭两步验证并作废恢复码（丢失验证器时使用）
+
+add（不带 -sso）与 passwd 从标准输入读取一行作为密码；输入为空时生成随机密码并打印。
+需要与服务相同的环境变量（至少 HELIOX_MON_DATA_DIR），例如：
+  set -a; . /opt/heliox-mon/.env; set +a; heliox-mon user list
+`
+
+// userCommand 用户管理子命令
+func userCommand(db *storage.DB, cfg *config.Config, cmd string, args []string) error {
+	switch cmd {
+	case "list":
+		users, err := auth.ListUsers(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-20s %-8s %-6s %-5s %s\n", "用户名", "角色", "来源", "2FA", "更新时间")
+		for _, u := range users {
+			twoFactor := "-"
+			if auth.TOTPEnabled(db, u.Username) {
+				twoFactor = "on"
+			}
+			fmt.Printf("%-20s %-8s %-6s %-5s %s\n", u.Username, u.Role, u.Source, twoFactor,
+				u.UpdatedAt.In(cfg.Timezone).Format("2006-01-02 15:04:05"))
+		}
+		return nil
+
+	case "add":
+		fs := flag.NewFlagSet("add", flag.ContinueOnError)
+		role := fs.String("role", auth.RoleViewer, "角色：admin 或 viewer")
+		sso := fs.Bool("sso", false, "只允许通过单点登录登录")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("用法: heliox-mon user add [-role viewer] [-sso] <用户名>")
+		}
+		if *sso {
+			if err := auth.CreateSSOUser(db, fs.Arg(0), *role); err != nil {
+				return err
+			}
+			cliAudit(db, auth.EventUserCreated, fs.Arg(0), *role+" sso")
+			fmt.Printf("已添加单点登录用户 %s（%s）\n", fs.Arg(0), *role)
+			return nil
+		}
+		password, generated, err := readPassword(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if err := auth.CreateUser(db, fs.Arg(0), password, *role); err != nil {
+			return err
+		}
+		cliAudit(db, auth.EventUserCreated, fs.Arg(0), *role)
+		fmt.Printf("已添加用户 %s（%s）\n", fs.Arg(0), *role)
+		if generated {
+			fmt.Printf("密码: %s\n", password)
+		}
+		return nil
+
+	case "del":
+		if len(args) != 1 {
+			return errors.New("用法: heliox-mon user del <用户名>")
+		}
+		if err := auth.DeleteUser(db, args[0]); err != nil {
+			return err
+		}
+		cliAudit(db, auth.EventUserDeleted, args[0], "")
+		fmt.Printf("已删除用户 %s\n", args[0])
+		if args[0] == cfg.Username && cfg.Password != "" {
+			fmt.Println("注意: HELIOX_MON_PASS 仍已设置，服务下次启动时会重新创建该管理员")
+		}
+		return nil
+
+	case "passwd":
+		if len(args) != 1 {
+			return errors.New("用法: heliox-mon user passwd <用户名>")
+		}
+		password, generated, err := readPassword(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if err := auth.SetPassword(db, args[0], password); err != nil {
+			return err
+		}
+		cliAudit(db, auth.EventPasswordSet, args[0], "")
+		fmt.Printf("已重置 %s 的密码，原有会话已注销\n", args[0])
+		if generated {
+			fmt.Printf("密码: %s\n", password)
+		}
+		return nil
+
+	case "role":
+		if len(args) != 2 {
+			return errors.New("用法: heliox-mon user role <用户名> <admin|viewer>")
+		}
+		u, err := auth.GetUser(db, args[0])
+		if err != nil {
+			return err
+		}
+		if err := auth.SetRole(db, args[0], args[1]); err != nil {
+			return err
+		}
+		cliAudit(db, auth.EventRoleChanged, args[0], auth.AuditDiff(map[string]string{"role": u.Role}, map[string]string{"role": args[1]}))
+		fmt.Printf("已将 %s 的角色设为 %s\n", args[0], args[1])
+		return nil
+
+	case "unlock":
+		if len(args) != 1 {
+			return errors.New("用法: heliox-mon user unlock <用户名|IP>")
+		}
+		key := "user:" + args[0]
+		if net.ParseIP(args[0]) != nil {
+			key = "ip:" + args[0]
+		}
+		ok, err := auth.Unlock(db, key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Printf("%s 没有失败记录\n", args[0])
+			return nil
+		}
+		cliAudit(db, auth.EventUserUnlocked, key, "")
+		fmt.Printf("已解除 %s 的锁定\n", args[0])
+		return nil
+
+	case "2fa-reset":
+		if len(args) != 1 {
+			return errors.New("用法: heliox-mon user 2fa-reset <用户名>")
+		}
+		if _, err := auth.GetUser(db, args[0]); err != nil {
+			return err
+		}
+		ok, err := auth.ResetTOTP(db, args[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Printf("%s 未开启两步验证\n", args[0])
+			return nil
+		}
+		cliAudit(db, auth.EventTOTPDisabled, args[0], "reset")
+		fmt.Printf("已关闭 %s 的两步验证，登录后可重新绑定\n", args[0])
+		return nil
+	}
+
+	fmt.Fprint(os.Stderr, userUsage)
+	return fmt.Errorf("未知命令: %s", cmd)
+}
+
+// readPassword 从标准输入读取一行密码，为空时生成随机密码
+func readPassword(r io.Reader) (password string, generated bool, err error) {
+	if f, ok := r.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(os.Stderr, "新密码（留空则随机生成）: ")
+		}
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", false, err
+	}
+	password = strings.TrimRight(line, "\r\n")
+	if password != "" {
+		return password, false, nil
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), true, nil
+}
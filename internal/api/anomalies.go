@@ -0,0 +1,65 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleAnomalies 流量异常记录
+// 支持 ?days=N（默认 7，最多 90）与 ?active=1（仅返回未结束的异常）
+func (s *Server) handleAnomalies(w http.ResponseWriter, r *http.Request) {
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days <= 0 || days > 90 {
+		days = 7
+	}
+	since := time.Now().AddDate(0, 0, -days).Unix()
+
+	query := `
+		SELECT id, ts, scope, direction, kind, rate, baseline, stddev, zscore, ended_at
+		FROM traffic_anomalies
+		WHERE ts >= ?`
+	if r.URL.Query().Get("active") == "1" {
+		query += " AND ended_at IS NULL"
+	}
+	query += " ORDER BY ts DESC"
+
+	rows, err := s.db.Query(query, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := []map[string]interface{}{}
+	for rows.Next() {
+		var id, ts int64
+		var scope, direction, kind string
+		var rate, baseline, stddev, zscore float64
+		var endedAt sql.NullInt64
+		if err := rows.Scan(&id, &ts, &scope, &direction, &kind, &rate, &baseline, &stddev, &zscore, &endedAt); err != nil {
+			continue
+		}
+		item := map[string]interface{}{
+			"id":        id,
+			"ts":        ts,
+			"scope":     scope,
+			"direction": direction,
+			"kind":      kind,
+			"rate":      rate,
+			"baseline":  baseline,
+			"stddev":    stddev,
+			"zscore":    zscore,
+			"ended_at":  nil,
+		}
+		if endedAt.Valid {
+			item["ended_at"] = endedAt.Int64
+		}
+		items = append(items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
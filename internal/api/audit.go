@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// audit 写入审计日志：操作者默认取当前认证主体，并附带客户端 IP 与 User-Agent
+func (s *Server) audit(r *http.Request, e auth.AuditEntry) {
+	if e.Username == "" {
+		if p := principalFromContext(r.Context()); p != nil {
+			e.Username = p.username
+		}
+	}
+	e.IP, e.UserAgent = s.clientIP(r), r.UserAgent()
+	auth.Audit(s.db, e)
+}
+
+// parseTimeParam 解析时间参数：Unix 秒、RFC 3339 或按配置时区的日期（2006-01-02）
+func (s *Server) parseTimeParam(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, true
+	}
+	if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(ts, 0), true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, s.cfg.Timezone); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// handleAudit 审计日志（仅管理员），从新到旧分页
+// 过滤参数: event、user、target、ip、since、until；分页: limit（默认 50，最多 500）与 before（上一页返回的 next_before）
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	since, ok1 := s.parseTimeParam(q.Get("since"))
+	until, ok2 := s.parseTimeParam(q.Get("until"))
+	if !ok1 || !ok2 {
+		http.Error(w, "Invalid since/until", http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	before, _ := strconv.ParseInt(q.Get("before"), 10, 64)
+
+	records, err := auth.QueryAudit(s.db, auth.AuditQuery{
+		Event:    q.Get("event"),
+		Username: q.Get("user"),
+		Target:   q.Get("target"),
+		IP:       q.Get("ip"),
+		Since:    since,
+		Until:    until,
+		BeforeID: before,
+		Limit:    limit,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(records))
+	for _, rec := range records {
+		item := map[string]interface{}{
+			"id":         rec.ID,
+			"ts":         rec.Time.Unix(),
+			"event":      rec.Event,
+			"username":   rec.Username,
+			"target":     rec.Target,
+			"ip":         rec.IP,
+			"user_agent": rec.UserAgent,
+			"detail":     rec.Detail,
+		}
+		// 配置差异等 JSON 详情原样嵌入
+		if json.Valid([]byte(rec.Detail)) && len(rec.Detail) > 0 && rec.Detail[0] == '{' {
+			item["detail"] = json.RawMessage(rec.Detail)
+		}
+		items = append(items, item)
+	}
+	var next interface{}
+	if len(records) == limit {
+		next = records[len(records)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":       items,
+		"next_before": next,
+	})
+}
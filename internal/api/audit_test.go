@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// TestAudit 测试登录与会话操作写入审计日志，以及 /api/audit 的权限和过滤
+func TestAudit(t *testing.T) {
+	s := newTestServer(t)
+
+	do(s, http.MethodPost, "/api/login", `{"username":"admin","password":"wrong"}`, "")
+	cookie := login(t, s)
+	other := login(t, s)
+	if w := do(s, http.MethodPost, "/api/logout", "", other); w.Code != http.StatusOK {
+		t.Fatalf("注销失败: %d", w.Code)
+	}
+
+	list := func(query string) []map[string]interface{} {
+		t.Helper()
+		w := do(s, http.MethodGet, "/api/audit"+query, "", cookie)
+		if w.Code != http.StatusOK {
+			t.Fatalf("查询审计日志失败: %d %s", w.Code, w.Body.String())
+		}
+		var data struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		json.NewDecoder(w.Body).Decode(&data)
+		return data.Items
+	}
+
+	counts := map[string]int{}
+	for _, item := range list("") {
+		counts[item["event"].(string)]++
+	}
+	if counts[auth.EventLoginFailed] != 1 || counts[auth.EventLogin] != 2 ||
+		counts[auth.EventSessionCreated] != 2 || counts[auth.EventSessionRevoked] != 1 {
+		t.Errorf("审计事件计数 = %v", counts)
+	}
+	if items := list("?event=login&limit=1"); len(items) != 1 || items[0]["detail"] != "password" {
+		t.Errorf("按事件过滤 = %v", items)
+	}
+
+	// 普通用户无权查看
+	if err := auth.CreateUser(s.db, "viewer", "password123", auth.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	w := do(s, http.MethodPost, "/api/login", `{"username":"viewer","password":"password123"}`, "")
+	var viewerCookie string
+	for _, c := range w.Result().Cookies() {
+		if c.Name == authCookieName {
+			viewerCookie = c.Value
+		}
+	}
+	if w := do(s, http.MethodGet, "/api/audit", "", viewerCookie); w.Code != http.StatusForbidden {
+		t.Errorf("viewer 查看审计日志应返回 403，实际 %d", w.Code)
+	}
+}
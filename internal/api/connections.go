@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleConnections 端口连接数时间序列（?hours=N，默认 24，最多 90 天）
+// 同时返回相同时间桶内的 CPU 使用率和负载，便于对照连接数突增与 CPU 负载
+func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
+	hours, _ := strconv.Atoi(r.URL.Query().Get("hours"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if hours > 90*24 {
+		hours = 90 * 24
+	}
+
+	now := time.Now().In(s.cfg.Timezone)
+	start := now.Add(-time.Duration(hours) * time.Hour)
+
+	// 超过 48 小时的数据只有小时级
+	granularity := chooseLatencyGranularity(now.Sub(start))
+	if hours > 48 && granularity < 60 {
+		granularity = 60
+	}
+	granularitySec := int64(granularity * 60)
+
+	ports := []map[string]interface{}{}
+	for _, p := range []struct {
+		port int
+		name string
+	}{{s.cfg.SnellPort, "Snell"}, {s.cfg.VlessPort, "VLESS"}} {
+		if p.port == 0 {
+			continue
+		}
+		rows, err := s.db.Query(`
+			SELECT (ts / ?) * ? AS bucket_ts, AVG(tcp), AVG(udp), AVG(clients), MAX(tcp_max), MAX(udp_max)
+			FROM port_connections
+			WHERE port = ? AND ts >= ? AND ts <= ?
+			GROUP BY bucket_ts
+			ORDER BY bucket_ts
+		`, granularitySec, granularitySec, p.port, start.Unix(), now.Unix())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		points := []map[string]interface{}{}
+		for rows.Next() {
+			var ts int64
+			var tcp, udp, clients float64
+			var tcpMax, udpMax int
+			if err := rows.Scan(&ts, &tcp, &udp, &clients, &tcpMax, &udpMax); err != nil {
+				continue
+			}
+			points = append(points, map[string]interface{}{
+				"ts":      ts,
+				"tcp":     tcp,
+				"udp":     udp,
+				"clients": clients,
+				"tcp_max": tcpMax,
+				"udp_max": udpMax,
+			})
+		}
+		rows.Close()
+
+		ports = append(ports, map[string]interface{}{
+			"port":   p.port,
+			"name":   p.name,
+			"points": points,
+		})
+	}
+
+	cpu := []map[string]interface{}{}
+	rows, err := s.db.Query(`
+		SELECT (ts / ?) * ? AS bucket_ts, AVG(cpu_percent), AVG(load_1)
+		FROM system_metrics
+		WHERE ts >= ? AND ts <= ?
+		GROUP BY bucket_ts
+		ORDER BY bucket_ts
+	`, granularitySec, granularitySec, start.Unix(), now.Unix())
+	if err == nil {
+		for rows.Next() {
+			var ts int64
+			var cpuPercent, load1 float64
+			if err := rows.Scan(&ts, &cpuPercent, &load1); err != nil {
+				continue
+			}
+			cpu = append(cpu, map[string]interface{}{
+				"ts":     ts,
+				"cpu":    cpuPercent,
+				"load_1": load1,
+			})
+		}
+		rows.Close()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"start":       start.Format("2006-01-02 15:04:05"),
+		"end":         now.Format("2006-01-02 15:04:05"),
+		"granularity": granularity,
+		"ports":       ports,
+		"system":      cpu,
+	})
+}
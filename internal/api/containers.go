@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleContainers Docker 容器资源时间序列（?hours=N，默认 24，最多 7 天）
+// 返回每个容器的最新采样、时间范围内的流量合计以及按粒度聚合的序列
+// host 网络模式的容器（host_network）没有独立的网络计数，网络字段为 null
+func (s *Server) handleContainers(w http.ResponseWriter, r *http.Request) {
+	hours, _ := strconv.Atoi(r.URL.Query().Get("hours"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if hours > 7*24 {
+		hours = 7 * 24
+	}
+
+	now := time.Now()
+	start := now.Add(-time.Duration(hours) * time.Hour).Unix()
+	granularitySec := int64(chooseLatencyGranularity(time.Duration(hours)*time.Hour) * 60)
+
+	// 时间范围内出现过的容器（按名称区分，重建后 ID 会变化）
+	rows, err := s.db.Query(`
+		SELECT name, SUM(net_rx_bytes), SUM(net_tx_bytes), MAX(cpu_percent), MAX(mem_current), MAX(ts)
+		FROM container_metrics WHERE ts >= ?
+		GROUP BY name ORDER BY name
+	`, start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	type containerRow struct {
+		name             string
+		rxBytes, txBytes *int64 // 全部为 host 网络模式的采样时为 NULL
+		maxCPU           float64
+		maxMem, lastTS   int64
+	}
+	var list []containerRow
+	for rows.Next() {
+		var c containerRow
+		if err := rows.Scan(&c.name, &c.rxBytes, &c.txBytes, &c.maxCPU, &c.maxMem, &c.lastTS); err != nil {
+			continue
+		}
+		list = append(list, c)
+	}
+	rows.Close()
+
+	containers := []map[string]interface{}{}
+	for _, c := range list {
+		var id, image string
+		var cpu, readBps, writeBps float64
+		var rxBps, txBps *float64
+		var mem, memMax int64
+		var hostNetwork bool
+		err := s.db.QueryRow(`
+			SELECT container_id, COALESCE(image, ''), cpu_percent, mem_current, mem_max, io_read_bps, io_write_bps,
+			       host_network, net_rx_bps, net_tx_bps
+			FROM container_metrics WHERE name = ? AND ts = ?
+		`, c.name, c.lastTS).Scan(&id, &image, &cpu, &mem, &memMax, &readBps, &writeBps, &hostNetwork, &rxBps, &txBps)
+		if err != nil {
+			continue
+		}
+
+		points := []map[string]interface{}{}
+		prows, err := s.db.Query(`
+			SELECT (ts / ?) * ? AS bucket_ts, AVG(cpu_percent), MAX(cpu_percent), AVG(mem_current),
+			       AVG(io_read_bps), AVG(io_write_bps), SUM(net_rx_bytes), SUM(net_tx_bytes), AVG(net_rx_bps), AVG(net_tx_bps)
+			FROM container_metrics
+			WHERE name = ? AND ts >= ?
+			GROUP BY bucket_ts
+			ORDER BY bucket_ts
+		`, granularitySec, granularitySec, c.name, start)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for prows.Next() {
+			var ts int64
+			var rxBytes, txBytes *int64
+			var pCPU, pCPUMax, pMem, pRead, pWrite float64
+			var pRx, pTx *float64
+			if err := prows.Scan(&ts, &pCPU, &pCPUMax, &pMem, &pRead, &pWrite, &rxBytes, &txBytes, &pRx, &pTx); err != nil {
+				continue
+			}
+			points = append(points, map[string]interface{}{
+				"ts":           ts,
+				"cpu_percent":  pCPU,
+				"cpu_max":      pCPUMax,
+				"mem_current":  int64(pMem),
+				"io_read_bps":  pRead,
+				"io_write_bps": pWrite,
+				"net_rx_bytes": rxBytes,
+				"net_tx_bytes": txBytes,
+				"net_rx_bps":   pRx,
+				"net_tx_bps":   pTx,
+			})
+		}
+		prows.Close()
+
+		containers = append(containers, map[string]interface{}{
+			"id":           id,
+			"name":         c.name,
+			"image":        image,
+			"running":      now.Unix()-c.lastTS < 180,
+			"host_network": hostNetwork,
+			"latest": map[string]interface{}{
+				"ts":           c.lastTS,
+				"cpu_percent":  cpu,
+				"mem_current":  mem,
+				"mem_max":      memMax,
+				"mem_percent":  percentOf(mem, memMax),
+				"io_read_bps":  readBps,
+				"io_write_bps": writeBps,
+				"net_rx_bps":   rxBps,
+				"net_tx_bps":   txBps,
+			},
+			"net_rx_bytes": c.rxBytes,
+			"net_tx_bytes": c.txBytes,
+			"cpu_max":      c.maxCPU,
+			"mem_max_used": c.maxMem,
+			"points":       points,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hours":       hours,
+		"granularity": granularitySec / 60,
+		"containers":  containers,
+	})
+}
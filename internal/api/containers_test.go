@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestContainersHostNetwork 测试 host 网络模式的容器网络字段为 null，且不计入流量合计
+func TestContainersHostNetwork(t *testing.T) {
+	s := newTestServer(t)
+	cookie := login(t, s)
+
+	now := time.Now().Unix()
+	for i, ts := range []int64{now - 120, now - 60} {
+		s.db.Exec(`INSERT INTO container_metrics (ts, container_id, name, image, cpu_percent, mem_current, mem_max,
+			io_read_bps, io_write_bps, host_network, net_rx_bytes, net_tx_bytes, net_rx_bps, net_tx_bps)
+			VALUES (?, 'a1', 'web', 'nginx', 5, 100, 0, 0, 0, 0, 600, 1200, 10, 20)`, ts)
+		s.db.Exec(`INSERT INTO container_metrics (ts, container_id, name, image, cpu_percent, mem_current, mem_max,
+			io_read_bps, io_write_bps, host_network)
+			VALUES (?, 'b2', 'proxy', 'caddy', ?, 100, 0, 0, 0, 1)`, ts, i)
+	}
+
+	w := do(s, http.MethodGet, "/api/containers", "", cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Containers []struct {
+			Name        string `json:"name"`
+			HostNetwork bool   `json:"host_network"`
+			NetRxBytes  *int64 `json:"net_rx_bytes"`
+			Latest      struct {
+				NetRxBps *float64 `json:"net_rx_bps"`
+			} `json:"latest"`
+			Points []struct {
+				NetTxBytes *int64 `json:"net_tx_bytes"`
+			} `json:"points"`
+		} `json:"containers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Containers) != 2 {
+		t.Fatalf("容器数 = %d", len(resp.Containers))
+	}
+	for _, c := range resp.Containers {
+		switch c.Name {
+		case "proxy":
+			if !c.HostNetwork || c.NetRxBytes != nil || c.Latest.NetRxBps != nil || len(c.Points) == 0 || c.Points[0].NetTxBytes != nil {
+				t.Errorf("host 网络模式的网络字段应为 null: %+v", c)
+			}
+		case "web":
+			if c.HostNetwork || c.NetRxBytes == nil || *c.NetRxBytes != 1200 || c.Latest.NetRxBps == nil || *c.Latest.NetRxBps != 10 {
+				t.Errorf("普通容器 = %+v", c)
+			}
+		}
+	}
+}
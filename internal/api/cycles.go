@@ -0,0 +1,270 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+)
+
+// billingCycle 计费周期汇总
+type billingCycle struct {
+	StartDate    string             `json:"start_date"`
+	EndDate      string             `json:"end_date"`
+	Tx           int64              `json:"tx"`
+	Rx           int64              `json:"rx"`
+	BilledBytes  int64              `json:"billed_bytes"`
+	BillingMode  string             `json:"billing_mode"`
+	LimitGB      int                `json:"limit_gb"`
+	UsagePercent float64            `json:"usage_percent"`
+	PeakDate     string             `json:"peak_date"`
+	PeakBytes    int64              `json:"peak_bytes"`
+	Finalized    bool               `json:"finalized"`
+	Ports        []billingCyclePort `json:"ports"`
+}
+
+// billingCyclePort 计费周期端口明细
+type billingCyclePort struct {
+	Port int    `json:"port"`
+	Name string `json:"name"`
+	Tx   int64  `json:"tx"`
+	Rx   int64  `json:"rx"`
+}
+
+// handleCycles 历史计费周期列表（含进行中的当前周期）
+func (s *Server) handleCycles(w http.ResponseWriter, r *http.Request) {
+	rows, err := s.db.Query(`
+		SELECT start_date, end_date, tx_bytes, rx_bytes, billed_bytes, billing_mode, limit_gb, COALESCE(peak_date, ''), peak_bytes
+		FROM billing_cycles
+		ORDER BY start_date DESC
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cycles := []*billingCycle{}
+	for rows.Next() {
+		c := &billingCycle{Finalized: true}
+		if err := rows.Scan(&c.StartDate, &c.EndDate, &c.Tx, &c.Rx, &c.BilledBytes, &c.BillingMode, &c.LimitGB, &c.PeakDate, &c.PeakBytes); err != nil {
+			continue
+		}
+		cycles = append(cycles, c)
+	}
+	rows.Close()
+
+	for _, c := range cycles {
+		c.UsagePercent = usagePercent(c.BilledBytes, c.LimitGB)
+		c.Ports = s.finalizedCyclePorts(c.StartDate)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"current": s.currentCycle(),
+		"cycles":  cycles,
+	})
+}
+
+// handleCycleReport 可打印的单周期账单
+func (s *Server) handleCycleReport(w http.ResponseWriter, r *http.Request) {
+	start := r.URL.Query().Get("start")
+	if start == "" {
+		http.Error(w, "Missing start", http.StatusBadRequest)
+		return
+	}
+
+	var cycle *billingCycle
+	current := s.currentCycle()
+	if start == current.StartDate {
+		cycle = current
+	} else {
+		c := &billingCycle{Finalized: true}
+		row := s.db.QueryRow(`
+			SELECT start_date, end_date, tx_bytes, rx_bytes, billed_bytes, billing_mode, limit_gb, COALESCE(peak_date, ''), peak_bytes
+			FROM billing_cycles
+			WHERE start_date = ?
+		`, start)
+		if err := row.Scan(&c.StartDate, &c.EndDate, &c.Tx, &c.Rx, &c.BilledBytes, &c.BillingMode, &c.LimitGB, &c.PeakDate, &c.PeakBytes); err != nil {
+			if err == sql.ErrNoRows {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.UsagePercent = usagePercent(c.BilledBytes, c.LimitGB)
+		c.Ports = s.finalizedCyclePorts(c.StartDate)
+		cycle = c
+	}
+
+	data := map[string]interface{}{
+		"ServerName":  s.cfg.ServerName,
+		"Cycle":       cycle,
+		"GeneratedAt": time.Now().In(s.cfg.Timezone).Format("2006-01-02 15:04 MST"),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := cycleReportTmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// currentCycle 根据日汇总实时计算当前周期
+func (s *Server) currentCycle() *billingCycle {
+	now := time.Now().In(s.cfg.Timezone)
+	start, end := s.getBillingCycleDates(now)
+	c := &billingCycle{
+		StartDate:   start.Format("2006-01-02"),
+		EndDate:     end.Format("2006-01-02"),
+		BillingMode: s.cfg.BillingMode,
+		LimitGB:     s.cfg.MonthlyLimitGB,
+		Ports:       []billingCyclePort{},
+	}
+
+	rows, err := s.db.Query(`
+		SELECT date, tx_bytes, rx_bytes
+		FROM traffic_daily
+		WHERE iface = 'total' AND date >= ? AND date <= ?
+	`, c.StartDate, c.EndDate)
+	if err == nil {
+		for rows.Next() {
+			var date string
+			var tx, rx int64
+			if err := rows.Scan(&date, &tx, &rx); err != nil {
+				continue
+			}
+			c.Tx += tx
+			c.Rx += rx
+			if billed := config.BilledBytes(c.BillingMode, tx, rx); billed > c.PeakBytes {
+				c.PeakBytes = billed
+				c.PeakDate = date
+			}
+		}
+		rows.Close()
+	}
+	c.BilledBytes = config.BilledBytes(c.BillingMode, c.Tx, c.Rx)
+	c.UsagePercent = usagePercent(c.BilledBytes, c.LimitGB)
+
+	rows, err = s.db.Query(`
+		SELECT port, SUM(tx_bytes), SUM(rx_bytes)
+		FROM port_traffic_daily
+		WHERE date >= ? AND date <= ?
+		GROUP BY port
+		ORDER BY port
+	`, c.StartDate, c.EndDate)
+	if err == nil {
+		c.Ports = s.scanCyclePorts(rows)
+	}
+	return c
+}
+
+// finalizedCyclePorts 读取已归档周期的端口明细
+func (s *Server) finalizedCyclePorts(startDate string) []billingCyclePort {
+	rows, err := s.db.Query(
+		"SELECT port, tx_bytes, rx_bytes FROM billing_cycle_ports WHERE start_date = ? ORDER BY port",
+		startDate,
+	)
+	if err != nil {
+		return []billingCyclePort{}
+	}
+	return s.scanCyclePorts(rows)
+}
+
+func (s *Server) scanCyclePorts(rows *sql.Rows) []billingCyclePort {
+	defer rows.Close()
+	ports := []billingCyclePort{}
+	for rows.Next() {
+		var p billingCyclePort
+		if err := rows.Scan(&p.Port, &p.Tx, &p.Rx); err != nil {
+			continue
+		}
+		p.Name = s.portName(p.Port)
+		ports = append(ports, p)
+	}
+	return ports
+}
+
+// portName 返回端口对应的协议名称
+func (s *Server) portName(port int) string {
+	switch port {
+	case s.cfg.SnellPort:
+		return "Snell"
+	case s.cfg.VlessPort:
+		return "VLESS"
+	}
+	return fmt.Sprintf("端口 %d", port)
+}
+
+func usagePercent(billed int64, limitGB int) float64 {
+	if limitGB <= 0 {
+		return 0
+	}
+	return float64(billed) / float64(int64(limitGB)*1024*1024*1024) * 100
+}
+
+// formatGB 字节转 GB 字符串
+func formatGB(bytes int64) string {
+	return fmt.Sprintf("%.2f GB", float64(bytes)/1024/1024/1024)
+}
+
+var billingModeNames = map[string]string{
+	"bidirectional": "上行+下行",
+	"tx_only":       "仅上行",
+	"rx_only":       "仅下行",
+	"max_value":     "取上行/下行较大值",
+}
+
+var cycleReportTmpl = template.Must(template.New("cycle").Funcs(template.FuncMap{
+	"gb":   formatGB,
+	"mode": func(m string) string { return billingModeNames[m] },
+	"sum":  func(a, b int64) int64 { return a + b },
+}).Parse(`<!doctype html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.ServerName}} 流量账单 {{.Cycle.StartDate}} ~ {{.Cycle.EndDate}}</title>
+<style>
+  body { font-family: -apple-system, "PingFang SC", "Helvetica Neue", sans-serif; color: #1d1d1f; max-width: 720px; margin: 40px auto; padding: 0 24px; }
+  h1 { font-size: 22px; margin-bottom: 4px; }
+  .sub { color: #6e6e73; font-size: 13px; margin-bottom: 28px; }
+  table { width: 100%; border-collapse: collapse; margin-bottom: 28px; font-size: 14px; }
+  th, td { text-align: left; padding: 8px 10px; border-bottom: 1px solid #e5e5ea; }
+  th { color: #6e6e73; font-weight: 500; }
+  td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+  .status { display: inline-block; padding: 2px 8px; border-radius: 4px; font-size: 12px; background: #f2f2f7; }
+  @media print { body { margin: 0; } .noprint { display: none; } }
+</style>
+</head>
+<body>
+<h1>{{.ServerName}} 流量账单</h1>
+<div class="sub">
+  计费周期 {{.Cycle.StartDate}} ~ {{.Cycle.EndDate}}
+  <span class="status">{{if .Cycle.Finalized}}已结算{{else}}进行中{{end}}</span>
+  · 生成于 {{.GeneratedAt}}
+</div>
+
+<table>
+  <tr><th>计费模式</th><td>{{mode .Cycle.BillingMode}} ({{.Cycle.BillingMode}})</td></tr>
+  <tr><th>上行 (TX)</th><td class="num">{{gb .Cycle.Tx}}</td></tr>
+  <tr><th>下行 (RX)</th><td class="num">{{gb .Cycle.Rx}}</td></tr>
+  <tr><th>计费流量</th><td class="num">{{gb .Cycle.BilledBytes}}</td></tr>
+  <tr><th>限额</th><td class="num">{{if gt .Cycle.LimitGB 0}}{{.Cycle.LimitGB}} GB ({{printf "%.1f" .Cycle.UsagePercent}}%){{else}}不限{{end}}</td></tr>
+  <tr><th>峰值日</th><td class="num">{{if .Cycle.PeakDate}}{{.Cycle.PeakDate}} · {{gb .Cycle.PeakBytes}}{{else}}-{{end}}</td></tr>
+</table>
+
+<table>
+  <tr><th>端口</th><th>协议</th><th class="num">上行</th><th class="num">下行</th><th class="num">合计</th></tr>
+  {{range .Cycle.Ports}}
+  <tr><td>{{.Port}}</td><td>{{.Name}}</td><td class="num">{{gb .Tx}}</td><td class="num">{{gb .Rx}}</td><td class="num">{{gb (sum .Tx .Rx)}}</td></tr>
+  {{else}}
+  <tr><td colspan="5">无端口数据</td></tr>
+  {{end}}
+</table>
+
+<button class="noprint" onclick="window.print()">打印</button>
+</body>
+</html>
+`))
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// latestDiskUsage 最近一次采样的各文件系统用量
+func (s *Server) latestDiskUsage() []map[string]interface{} {
+	disks := []map[string]interface{}{}
+	rows, err := s.db.Query(`
+		SELECT mount, device, fstype, total, used, avail, inodes_total, inodes_used
+		FROM disk_usage
+		WHERE ts = (SELECT MAX(ts) FROM disk_usage)
+		ORDER BY mount
+	`)
+	if err != nil {
+		return disks
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mount, device, fstype string
+		var total, used, avail, inodesTotal, inodesUsed int64
+		if err := rows.Scan(&mount, &device, &fstype, &total, &used, &avail, &inodesTotal, &inodesUsed); err != nil {
+			continue
+		}
+		disks = append(disks, map[string]interface{}{
+			"mount":          mount,
+			"device":         device,
+			"fstype":         fstype,
+			"total":          total,
+			"used":           used,
+			"avail":          avail,
+			"used_percent":   percentOf(used, total),
+			"inodes_total":   inodesTotal,
+			"inodes_used":    inodesUsed,
+			"inodes_percent": percentOf(inodesUsed, inodesTotal),
+		})
+	}
+	return disks
+}
+
+// latestDiskIO 最近一次采样的各块设备 I/O 速率
+func (s *Server) latestDiskIO() []map[string]interface{} {
+	devices := []map[string]interface{}{}
+	rows, err := s.db.Query(`
+		SELECT device, read_bps, write_bps, read_iops, write_iops, util_percent
+		FROM disk_io
+		WHERE ts = (SELECT MAX(ts) FROM disk_io)
+		ORDER BY device
+	`)
+	if err != nil {
+		return devices
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var device string
+		var readBps, writeBps, readIOPS, writeIOPS, util float64
+		if err := rows.Scan(&device, &readBps, &writeBps, &readIOPS, &writeIOPS, &util); err != nil {
+			continue
+		}
+		devices = append(devices, map[string]interface{}{
+			"device":       device,
+			"read_bps":     readBps,
+			"write_bps":    writeBps,
+			"read_iops":    readIOPS,
+			"write_iops":   writeIOPS,
+			"util_percent": util,
+		})
+	}
+	return devices
+}
+
+// handleDisks 文件系统用量与磁盘 I/O 历史
+// ?hours=N 用量历史（默认 24，最多 7 天）；I/O 只保留最近 1 小时
+func (s *Server) handleDisks(w http.ResponseWriter, r *http.Request) {
+	hours, _ := strconv.Atoi(r.URL.Query().Get("hours"))
+	if hours <= 0 || hours > 7*24 {
+		hours = 24
+	}
+	now := time.Now()
+	start := now.Add(-time.Duration(hours) * time.Hour)
+	granularitySec := int64(chooseLatencyGranularity(now.Sub(start)) * 60)
+
+	// 用量历史：每个时间桶取最大值
+	usage := map[string][]map[string]interface{}{}
+	rows, err := s.db.Query(`
+		SELECT mount, (ts / ?) * ? AS bucket_ts, MAX(used), MAX(total), MAX(inodes_used)
+		FROM disk_usage
+		WHERE ts >= ?
+		GROUP BY mount, bucket_ts
+		ORDER BY mount, bucket_ts
+	`, granularitySec, granularitySec, start.Unix())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var mount string
+		var ts, used, total, inodesUsed int64
+		if err := rows.Scan(&mount, &ts, &used, &total, &inodesUsed); err != nil {
+			continue
+		}
+		usage[mount] = append(usage[mount], map[string]interface{}{
+			"ts":          ts,
+			"used":        used,
+			"total":       total,
+			"inodes_used": inodesUsed,
+		})
+	}
+	rows.Close()
+
+	io := map[string][]map[string]interface{}{}
+	rows, err = s.db.Query(`
+		SELECT device, ts, read_bps, write_bps, read_iops, write_iops, util_percent
+		FROM disk_io
+		ORDER BY device, ts
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var device string
+		var ts int64
+		var readBps, writeBps, readIOPS, writeIOPS, util float64
+		if err := rows.Scan(&device, &ts, &readBps, &writeBps, &readIOPS, &writeIOPS, &util); err != nil {
+			continue
+		}
+		io[device] = append(io[device], map[string]interface{}{
+			"ts":           ts,
+			"read_bps":     readBps,
+			"write_bps":    writeBps,
+			"read_iops":    readIOPS,
+			"write_iops":   writeIOPS,
+			"util_percent": util,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"disks":   s.latestDiskUsage(),
+		"usage":   usage,
+		"disk_io": io,
+	})
+}
+
+// percentOf 计算百分比
+func percentOf(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
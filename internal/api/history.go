@@ -0,0 +1,123 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// systemHistoryTiers 系统指标汇总层级（秒），与采集器一致
+var systemHistoryTiers = []int64{60, 900, 3600}
+
+// handleSystemHistory 系统指标历史
+// ?from=&to= 支持 Unix 时间戳、YYYY-MM-DD 或 YYYY-MM-DD HH:MM，默认最近 24 小时
+// 按时间跨度自动选择汇总层级（保持约 1440 个点以内），超出该层级保留期时使用更粗的层级
+func (s *Server) handleSystemHistory(w http.ResponseWriter, r *http.Request) {
+	tz := s.cfg.Timezone
+	now := time.Now().In(tz)
+
+	to, ok := parseTimeParam(r.URL.Query().Get("to"), tz, true)
+	if !ok {
+		to = now
+	}
+	from, ok := parseTimeParam(r.URL.Query().Get("from"), tz, false)
+	if !ok {
+		from = to.Add(-24 * time.Hour)
+	}
+	if !from.Before(to) {
+		http.Error(w, "Invalid range", http.StatusBadRequest)
+		return
+	}
+
+	tier := s.chooseSystemTier(from, to, now)
+
+	rows, err := s.db.Query(
+		"SELECT * FROM system_metrics_rollup WHERE tier = ? AND ts >= ? AND ts <= ? ORDER BY ts",
+		tier, from.Unix(), to.Unix(),
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	cols, _ := rows.Columns()
+	points := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]sql.NullFloat64, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			continue
+		}
+
+		point := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			switch {
+			case col == "tier":
+				continue
+			case col == "ts" || col == "samples":
+				point[col] = int64(values[i].Float64)
+			case values[i].Valid:
+				point[col] = values[i].Float64
+			default:
+				point[col] = nil
+			}
+		}
+		points = append(points, point)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":        from.Format("2006-01-02 15:04:05"),
+		"to":          to.Format("2006-01-02 15:04:05"),
+		"granularity": tier / 60,
+		"points":      points,
+	})
+}
+
+// chooseSystemTier 选择汇总层级：不小于 chooseLatencyGranularity 给出的粒度，且起点在保留期内
+func (s *Server) chooseSystemTier(from, to, now time.Time) int64 {
+	want := int64(chooseLatencyGranularity(to.Sub(from))) * 60
+	retention := map[int64]int{
+		60:   s.cfg.SystemRetention1mDays,
+		900:  s.cfg.SystemRetention15mDays,
+		3600: s.cfg.SystemRetention1hDays,
+	}
+
+	for _, tier := range systemHistoryTiers {
+		if tier < want {
+			continue
+		}
+		days := retention[tier]
+		if days > 0 && from.Before(now.AddDate(0, 0, -days)) {
+			continue
+		}
+		return tier
+	}
+	return systemHistoryTiers[len(systemHistoryTiers)-1]
+}
+
+// parseTimeParam 解析时间参数，end 为 true 时纯日期取当天结束
+func parseTimeParam(v string, tz *time.Location, end bool) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(ts, 0).In(tz), true
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", v, tz); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, tz); err == nil {
+		if end {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/systemd"
+)
+
+// unixPrefix Unix 套接字监听地址前缀，如 unix:/run/heliox-mon.sock
+const unixPrefix = "unix:"
+
+// Listen 绑定监听地址并加载 TLS 证书，错误在启动服务前同步返回。
+// 优先使用 systemd 套接字激活传入的套接字，其次按 HELIOX_MON_LISTEN 绑定 TCP 或 Unix 套接字
+func (s *Server) Listen() error {
+	tlsCfg, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	activated, err := systemd.Listeners()
+	if err != nil {
+		return fmt.Errorf("读取 systemd 套接字失败: %w", err)
+	}
+	var ln net.Listener
+	switch {
+	case len(activated) > 0:
+		ln = activated[0]
+		for _, extra := range activated[1:] {
+			log.Printf("忽略多余的 systemd 套接字: %s", extra.Addr())
+			extra.Close()
+		}
+		s.listenAddr = "systemd:" + ln.Addr().String()
+	case strings.HasPrefix(s.cfg.ListenAddr, unixPrefix):
+		ln, err = listenUnix(strings.TrimPrefix(s.cfg.ListenAddr, unixPrefix), s.cfg.SocketMode)
+		s.listenAddr = s.cfg.ListenAddr
+	default:
+		ln, err = net.Listen("tcp", s.cfg.ListenAddr)
+		s.listenAddr = s.cfg.ListenAddr
+	}
+	if err != nil {
+		return err
+	}
+
+	s.listener = ln
+	s.server.TLSConfig = tlsCfg
+	return nil
+}
+
+// ListenAddr 实际监听的地址（Listen 之后有效）
+func (s *Server) ListenAddr() string {
+	return s.listenAddr
+}
+
+// listenUnix 绑定 Unix 套接字；残留的套接字文件（上次异常退出）会被替换，仍有进程在监听时报错
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s 已存在且不是套接字", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s 已有进程在监听", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// isUnixConn 请求是否经由 Unix 套接字到达
+func isUnixConn(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
+
+// serve 在已绑定的套接字上提供服务，Stop 后返回 nil
+func (s *Server) serve() error {
+	var err error
+	if s.server.TLSConfig != nil {
+		log.Printf("HTTPS 服务启动: %s", s.listenAddr)
+		err = s.server.ServeTLS(s.listener, "", "")
+	} else {
+		log.Printf("HTTP 服务启动: %s", s.listenAddr)
+		err = s.server.Serve(s.listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
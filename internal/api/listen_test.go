@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hh/heliox-mon/internal/auth"
+	"github.com/hh/heliox-mon/internal/config"
+)
+
+// TestUnixSocket 测试 Unix 套接字监听：替换残留文件、拒绝重复监听、文件权限，以及显式信任 unix 后采信转发头
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mon.sock")
+
+	// 上次异常退出残留的套接字文件
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.ListenAddr = "unix:" + path
+		cfg.SocketMode = 0600
+		cfg.TrustedProxies = []string{"127.0.0.1", "unix"}
+	})
+	if err := s.Listen(); err != nil {
+		t.Fatalf("替换残留套接字失败: %v", err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- s.Start() }()
+
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("套接字权限 = %v, %v", fi.Mode().Perm(), err)
+	}
+	if _, err := listenUnix(path, 0600); err == nil {
+		t.Error("已有进程监听时应报错")
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	if ip := forwardedLoginIP(t, s, client); ip != "203.0.113.9" {
+		t.Errorf("信任 unix 时客户端 IP = %q", ip)
+	}
+
+	s.Stop()
+	if err := <-errc; err != nil {
+		t.Errorf("Stop 后 Start 应返回 nil，实际 %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("关闭后应删除套接字文件")
+	}
+}
+
+// forwardedLoginIP 带伪造的 X-Forwarded-For 登录失败一次，返回审计日志记录的客户端 IP
+func forwardedLoginIP(t *testing.T, s *Server, client *http.Client) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, "http://unix/api/login", strings.NewReader(`{"username":"admin","password":"wrong"}`))
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("错误密码 = %d", resp.StatusCode)
+	}
+	records, _ := auth.QueryAudit(s.db, auth.AuditQuery{Event: auth.EventLoginFailed, Limit: 1})
+	if len(records) != 1 {
+		t.Fatalf("审计记录 = %+v", records)
+	}
+	return records[0].IP
+}
+
+// TestUnixPeerUntrusted 测试默认不信任 Unix 套接字对端的转发头
+func TestUnixPeerUntrusted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mon.sock")
+	s := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.ListenAddr = "unix:" + path
+		cfg.TrustedProxies = []string{"127.0.0.1", "::1"}
+	})
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	defer s.Stop()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	if ip := forwardedLoginIP(t, s, client); ip == "203.0.113.9" {
+		t.Error("默认不应采信 Unix 套接字对端的 X-Forwarded-For")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, &net.UnixAddr{Name: path, Net: "unix"}))
+	if s.isHTTPS(req) {
+		t.Error("默认不应采信 Unix 套接字对端的 X-Forwarded-Proto")
+	}
+}
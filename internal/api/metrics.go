@@ -0,0 +1,102 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleMetrics Prometheus 文本格式指标（供 Grafana 等抓取，Token 需要 metrics 权限）
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.writeMetrics(w)
+}
+
+// metricLabel 转义标签值
+func metricLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// writeMetrics 输出流量、系统资源与延迟的最新值
+func (s *Server) writeMetrics(w io.Writer) {
+	gauge := func(name, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	value := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+	// 流量
+	stats := s.Stats()
+	gauge("heliox_traffic_bytes", "Traffic by period and direction.")
+	for _, period := range []string{"today", "yesterday", "this_month", "last_month"} {
+		if t, ok := stats[period].(map[string]int64); ok {
+			for _, dir := range []string{"tx", "rx"} {
+				fmt.Fprintf(w, "heliox_traffic_bytes{period=%q,direction=%q} %d\n", period, dir, t[dir])
+			}
+		}
+	}
+	gauge("heliox_billing_used_bytes", "Billed traffic in the current billing cycle.")
+	fmt.Fprintf(w, "heliox_billing_used_bytes %d\n", stats["used_bytes"])
+	gauge("heliox_billing_limit_bytes", "Monthly traffic limit (0 = unlimited).")
+	fmt.Fprintf(w, "heliox_billing_limit_bytes %d\n", int64(s.cfg.MonthlyLimitGB)*1024*1024*1024)
+
+	// 系统资源
+	var cpu, load1, load5, load15 float64
+	var memUsed, memTotal, diskUsed, diskTotal int64
+	err := s.db.QueryRow(`
+		SELECT cpu_percent, mem_used, mem_total, disk_used, disk_total, load_1, load_5, load_15
+		FROM system_metrics ORDER BY ts DESC LIMIT 1
+	`).Scan(&cpu, &memUsed, &memTotal, &diskUsed, &diskTotal, &load1, &load5, &load15)
+	if err == nil {
+		gauge("heliox_cpu_percent", "CPU usage percent.")
+		fmt.Fprintf(w, "heliox_cpu_percent %s\n", value(cpu))
+		gauge("heliox_memory_bytes", "Memory usage.")
+		fmt.Fprintf(w, "heliox_memory_bytes{state=\"used\"} %d\nheliox_memory_bytes{state=\"total\"} %d\n", memUsed, memTotal)
+		gauge("heliox_disk_bytes", "Root filesystem usage.")
+		fmt.Fprintf(w, "heliox_disk_bytes{state=\"used\"} %d\nheliox_disk_bytes{state=\"total\"} %d\n", diskUsed, diskTotal)
+		gauge("heliox_load", "Load average.")
+		fmt.Fprintf(w, "heliox_load{period=\"1m\"} %s\nheliox_load{period=\"5m\"} %s\nheliox_load{period=\"15m\"} %s\n",
+			value(load1), value(load5), value(load15))
+	}
+
+	// 延迟（各目标最近 10 分钟内的最新一次探测）
+	rows, err := s.db.Query(`
+		SELECT target, COALESCE(rtt_ms, -1), sent, lost FROM latency_records
+		WHERE id IN (SELECT MAX(id) FROM latency_records WHERE ts >= ? AND is_aggregated = 0 GROUP BY target)
+		ORDER BY target
+	`, time.Now().Add(-10*time.Minute).Unix())
+	if err != nil {
+		log.Printf("查询延迟指标失败: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	var rtt, loss []string
+	for rows.Next() {
+		var target string
+		var ms float64
+		var sent, lost int
+		if err := rows.Scan(&target, &ms, &sent, &lost); err != nil {
+			continue
+		}
+		label := metricLabel(target)
+		if ms >= 0 {
+			rtt = append(rtt, fmt.Sprintf("heliox_latency_ms{target=\"%s\"} %s\n", label, value(ms)))
+		}
+		if sent > 0 {
+			loss = append(loss, fmt.Sprintf("heliox_packet_loss_percent{target=\"%s\"} %s\n", label,
+				value(float64(lost)/float64(sent)*100)))
+		}
+	}
+	if len(rtt) > 0 {
+		gauge("heliox_latency_ms", "Latest ping round-trip time.")
+		io.WriteString(w, strings.Join(rtt, ""))
+	}
+	if len(loss) > 0 {
+		gauge("heliox_packet_loss_percent", "Latest ping packet loss.")
+		io.WriteString(w, strings.Join(loss, ""))
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleNetStats 网卡错误 / 丢包与 TCP 重传、队列溢出（?hours=N，默认 24，最多 7 天）
+// 返回按粒度聚合的每分钟增量、报警阈值以及报警记录
+func (s *Server) handleNetStats(w http.ResponseWriter, r *http.Request) {
+	hours, _ := strconv.Atoi(r.URL.Query().Get("hours"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if hours > 7*24 {
+		hours = 7 * 24
+	}
+
+	now := time.Now()
+	start := now.Add(-time.Duration(hours) * time.Hour).Unix()
+	granularity := chooseLatencyGranularity(time.Duration(hours) * time.Hour)
+	granularitySec := int64(granularity * 60)
+
+	// TCP：计数按分钟求平均，重传率按报文段加权
+	tcp := []map[string]interface{}{}
+	rows, err := s.db.Query(`
+		SELECT (ts / ?) * ? AS bucket_ts, SUM(out_segs), SUM(retrans_segs), MAX(retrans_percent),
+		       AVG(in_errs), AVG(out_rsts), AVG(estab_resets), AVG(attempt_fails),
+		       AVG(listen_overflows), MAX(listen_overflows), AVG(listen_drops), AVG(syn_retrans), AVG(timeouts),
+		       AVG(udp_in_errors), AVG(udp_rcvbuf_errors)
+		FROM net_tcp_stats
+		WHERE ts >= ?
+		GROUP BY bucket_ts
+		ORDER BY bucket_ts
+	`, granularitySec, granularitySec, start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var ts, outSegs, retransSegs int64
+		var retransMax, inErrs, outRsts, estabResets, attemptFails, overflows, overflowsMax, listenDrops, synRetrans, timeouts, udpInErrors, udpRcvbuf float64
+		if err := rows.Scan(&ts, &outSegs, &retransSegs, &retransMax, &inErrs, &outRsts, &estabResets, &attemptFails,
+			&overflows, &overflowsMax, &listenDrops, &synRetrans, &timeouts, &udpInErrors, &udpRcvbuf); err != nil {
+			continue
+		}
+		tcp = append(tcp, map[string]interface{}{
+			"ts":                ts,
+			"out_segs":          outSegs,
+			"retrans_segs":      retransSegs,
+			"retrans_percent":   percentOf(retransSegs, outSegs),
+			"retrans_max":       retransMax,
+			"in_errs":           inErrs,
+			"out_rsts":          outRsts,
+			"estab_resets":      estabResets,
+			"attempt_fails":     attemptFails,
+			"listen_overflows":  overflows,
+			"listen_max":        overflowsMax,
+			"listen_drops":      listenDrops,
+			"syn_retrans":       synRetrans,
+			"timeouts":          timeouts,
+			"udp_in_errors":     udpInErrors,
+			"udp_rcvbuf_errors": udpRcvbuf,
+		})
+	}
+	rows.Close()
+
+	// 网卡：每分钟平均值
+	ifaces := map[string][]map[string]interface{}{}
+	rows, err = s.db.Query(`
+		SELECT iface, (ts / ?) * ? AS bucket_ts, AVG(rx_packets), AVG(tx_packets), AVG(rx_errs), AVG(tx_errs),
+		       AVG(rx_drop), AVG(tx_drop), AVG(rx_fifo), AVG(tx_fifo)
+		FROM net_iface_stats
+		WHERE ts >= ?
+		GROUP BY iface, bucket_ts
+		ORDER BY iface, bucket_ts
+	`, granularitySec, granularitySec, start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var iface string
+		var ts int64
+		var rxPackets, txPackets, rxErrs, txErrs, rxDrop, txDrop, rxFifo, txFifo float64
+		if err := rows.Scan(&iface, &ts, &rxPackets, &txPackets, &rxErrs, &txErrs, &rxDrop, &txDrop, &rxFifo, &txFifo); err != nil {
+			continue
+		}
+		ifaces[iface] = append(ifaces[iface], map[string]interface{}{
+			"ts":         ts,
+			"rx_packets": rxPackets,
+			"tx_packets": txPackets,
+			"rx_errs":    rxErrs,
+			"tx_errs":    txErrs,
+			"rx_drop":    rxDrop,
+			"tx_drop":    txDrop,
+			"rx_fifo":    rxFifo,
+			"tx_fifo":    txFifo,
+		})
+	}
+	rows.Close()
+
+	alerts := []map[string]interface{}{}
+	rows, err = s.db.Query(`
+		SELECT id, ts, metric, scope, value, threshold, ended_at
+		FROM net_alerts WHERE ts >= ? OR ended_at IS NULL
+		ORDER BY ts DESC LIMIT 200
+	`, start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, ts int64
+		var metric, scope string
+		var value, threshold float64
+		var endedAt sql.NullInt64
+		if err := rows.Scan(&id, &ts, &metric, &scope, &value, &threshold, &endedAt); err != nil {
+			continue
+		}
+		item := map[string]interface{}{
+			"id":        id,
+			"ts":        ts,
+			"metric":    metric,
+			"scope":     scope,
+			"value":     value,
+			"threshold": threshold,
+			"active":    !endedAt.Valid,
+			"ended_at":  nil,
+		}
+		if endedAt.Valid {
+			item["ended_at"] = endedAt.Int64
+		}
+		alerts = append(alerts, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hours":       hours,
+		"granularity": granularity,
+		"thresholds": map[string]interface{}{
+			"enabled":                  s.cfg.NetAlerts,
+			"retrans_percent":          s.cfg.NetAlertRetransPercent,
+			"listen_overflows_per_min": s.cfg.NetAlertListenOverflowsPerMin,
+			"drops_per_min":            s.cfg.NetAlertDropsPerMin,
+		},
+		"tcp":    tcp,
+		"ifaces": ifaces,
+		"alerts": alerts,
+	})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// handleNotifications 通知队列投递状态
+// GET 返回各状态计数与最近记录（支持 ?status=pending|sent|dead&limit=N）
+// POST ?id=N 将死信重新放回队列
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.retryNotification(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	counts := map[string]int{"pending": 0, "sent": 0, "dead": 0}
+	rows, err := s.db.Query("SELECT status, COUNT(*) FROM notification_outbox GROUP BY status")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err == nil {
+			counts[status] = n
+		}
+	}
+	rows.Close()
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	status := r.URL.Query().Get("status")
+
+	query := `
+		SELECT id, channel, COALESCE(dedup_key, ''), payload, status, attempts, next_attempt_at,
+		       COALESCE(last_error, ''), created_at, sent_at
+		FROM notification_outbox`
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err = s.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := []map[string]interface{}{}
+	for rows.Next() {
+		var id, nextAttempt, createdAt int64
+		var attempts int
+		var channel, dedupKey, payload, st, lastErr string
+		var sentAt sql.NullInt64
+		if err := rows.Scan(&id, &channel, &dedupKey, &payload, &st, &attempts, &nextAttempt, &lastErr, &createdAt, &sentAt); err != nil {
+			continue
+		}
+
+		// 只返回消息正文，不暴露 chat_id 等投递参数
+		var msg struct {
+			Text string `json:"text"`
+		}
+		json.Unmarshal([]byte(payload), &msg)
+
+		item := map[string]interface{}{
+			"id":              id,
+			"channel":         channel,
+			"dedup_key":       dedupKey,
+			"text":            msg.Text,
+			"status":          st,
+			"attempts":        attempts,
+			"next_attempt_at": nextAttempt,
+			"last_error":      lastErr,
+			"created_at":      createdAt,
+			"sent_at":         nil,
+		}
+		if sentAt.Valid {
+			item["sent_at"] = sentAt.Int64
+		}
+		items = append(items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"counts": counts,
+		"items":  items,
+	})
+}
+
+// retryNotification 将死信重新放回队列
+func (s *Server) retryNotification(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	res, err := s.db.Exec(
+		"UPDATE notification_outbox SET status = 'pending', attempts = 0, next_attempt_at = ? WHERE id = ? AND status = 'dead'",
+		time.Now().Unix(), id,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	s.audit(r, auth.AuditEntry{Event: auth.EventOutboxRetried, Target: strconv.FormatInt(id, 10)})
+	w.WriteHeader(http.StatusOK)
+}
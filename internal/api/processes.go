@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleProcesses 进程资源占用（?hours=N 默认 1，最多 7 天；?top=N 默认 10；?sort=cpu|mem；?name=进程名 返回该进程的时间序列）
+func (s *Server) handleProcesses(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	hours, _ := strconv.Atoi(q.Get("hours"))
+	if hours <= 0 {
+		hours = 1
+	}
+	if hours > 7*24 {
+		hours = 7 * 24
+	}
+	top, _ := strconv.Atoi(q.Get("top"))
+	if top <= 0 || top > 100 {
+		top = 10
+	}
+	orderBy := "AVG(cpu)"
+	if q.Get("sort") == "mem" {
+		orderBy = "AVG(rss)"
+	}
+
+	now := time.Now()
+	start := now.Add(-time.Duration(hours) * time.Hour).Unix()
+
+	// 最新一次采样
+	latest := []map[string]interface{}{}
+	rows, err := s.db.Query(`
+		SELECT pid, name, COALESCE(cmdline, ''), COALESCE(matched, ''), cpu_percent, rss, threads, fds,
+		       read_bps, write_bps, rchar_bps, wchar_bps
+		FROM process_metrics
+		WHERE ts = (SELECT MAX(ts) FROM process_metrics)
+		ORDER BY cpu_percent DESC
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var pid, threads, fds int
+		var name, cmdline, matched string
+		var cpu, readBps, writeBps, rcharBps, wcharBps float64
+		var rss int64
+		if err := rows.Scan(&pid, &name, &cmdline, &matched, &cpu, &rss, &threads, &fds,
+			&readBps, &writeBps, &rcharBps, &wcharBps); err != nil {
+			continue
+		}
+		latest = append(latest, map[string]interface{}{
+			"pid":         pid,
+			"name":        name,
+			"cmdline":     cmdline,
+			"matched":     matched,
+			"cpu_percent": cpu,
+			"rss":         rss,
+			"threads":     threads,
+			"fds":         fds,
+			"read_bps":    readBps,
+			"write_bps":   writeBps,
+			"rchar_bps":   rcharBps,
+			"wchar_bps":   wcharBps,
+		})
+	}
+	rows.Close()
+
+	// 时间范围内按进程名汇总的 Top-N（同名多进程按采样时刻求和）
+	rows, err = s.db.Query(`
+		SELECT name, MAX(matched), AVG(cpu), MAX(cpu), AVG(rss), MAX(rss), MAX(fds), COUNT(*)
+		FROM (
+			SELECT ts, name, MAX(COALESCE(matched, '')) AS matched, SUM(cpu_percent) AS cpu, SUM(rss) AS rss, SUM(fds) AS fds
+			FROM process_metrics WHERE ts >= ?
+			GROUP BY ts, name
+		)
+		GROUP BY name
+		ORDER BY `+orderBy+` DESC
+		LIMIT ?
+	`, start, top)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	topList := []map[string]interface{}{}
+	for rows.Next() {
+		var name, matched string
+		var avgCPU, maxCPU, avgRSS float64
+		var maxRSS int64
+		var maxFDs, samples int
+		if err := rows.Scan(&name, &matched, &avgCPU, &maxCPU, &avgRSS, &maxRSS, &maxFDs, &samples); err != nil {
+			continue
+		}
+		topList = append(topList, map[string]interface{}{
+			"name":    name,
+			"matched": matched,
+			"avg_cpu": avgCPU,
+			"max_cpu": maxCPU,
+			"avg_rss": int64(avgRSS),
+			"max_rss": maxRSS,
+			"max_fds": maxFDs,
+			"samples": samples,
+		})
+	}
+	rows.Close()
+
+	resp := map[string]interface{}{
+		"hours":  hours,
+		"latest": latest,
+		"top":    topList,
+	}
+
+	// 指定进程名时返回时间序列
+	if name := q.Get("name"); name != "" {
+		granularitySec := int64(chooseLatencyGranularity(time.Duration(hours)*time.Hour) * 60)
+		rows, err = s.db.Query(`
+			SELECT (ts / ?) * ? AS bucket_ts, AVG(cpu), MAX(cpu), AVG(rss), MAX(fds), MAX(threads),
+			       AVG(read_bps), AVG(write_bps), AVG(rchar_bps), AVG(wchar_bps)
+			FROM (
+				SELECT ts, SUM(cpu_percent) AS cpu, SUM(rss) AS rss, SUM(fds) AS fds, SUM(threads) AS threads,
+				       SUM(read_bps) AS read_bps, SUM(write_bps) AS write_bps, SUM(rchar_bps) AS rchar_bps, SUM(wchar_bps) AS wchar_bps
+				FROM process_metrics WHERE name = ? AND ts >= ?
+				GROUP BY ts
+			)
+			GROUP BY bucket_ts
+			ORDER BY bucket_ts
+		`, granularitySec, granularitySec, name, start)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		points := []map[string]interface{}{}
+		for rows.Next() {
+			var ts int64
+			var cpu, maxCPU, rss, readBps, writeBps, rcharBps, wcharBps float64
+			var fds, threads int
+			if err := rows.Scan(&ts, &cpu, &maxCPU, &rss, &fds, &threads, &readBps, &writeBps, &rcharBps, &wcharBps); err != nil {
+				continue
+			}
+			points = append(points, map[string]interface{}{
+				"ts":          ts,
+				"cpu_percent": cpu,
+				"cpu_max":     maxCPU,
+				"rss":         int64(rss),
+				"fds":         fds,
+				"threads":     threads,
+				"read_bps":    readBps,
+				"write_bps":   writeBps,
+				"rchar_bps":   rcharBps,
+				"wchar_bps":   wcharBps,
+			})
+		}
+		resp["name"] = name
+		resp["granularity"] = granularitySec / 60
+		resp["points"] = points
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// unixProxy TRUSTED_PROXIES 中表示信任 Unix 套接字对端的特殊项
+const unixProxy = "unix"
+
+// initProxies 解析可信反向代理列表，配置无效时只信任本机
+func (s *Server) initProxies() {
+	var entries []string
+	for _, e := range s.cfg.TrustedProxies {
+		if strings.EqualFold(e, unixProxy) {
+			s.trustUnixPeers = true
+			continue
+		}
+		entries = append(entries, e)
+	}
+	nets, err := auth.ParseNetworks(entries)
+	if err != nil {
+		log.Printf("TRUSTED_PROXIES 无效，只信任本机代理: %v", err)
+		nets, _ = auth.ParseNetworks([]string{"127.0.0.1", "::1"})
+	}
+	s.trustedProxies = nets
+}
+
+// remoteIP 直连对端 IP（去掉端口）
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// fromTrustedProxy 直连对端是否为可信反向代理；Unix 套接字对端只有 TRUSTED_PROXIES 含 unix 时才可信
+func (s *Server) fromTrustedProxy(r *http.Request) bool {
+	if isUnixConn(r) {
+		return s.trustUnixPeers
+	}
+	return auth.ContainsIP(s.trustedProxies, remoteIP(r))
+}
+
+// clientIP 真实客户端 IP：只有直连对端是可信代理时才采信转发头。
+// 优先取 CF-Connecting-IP；其次从右向左跳过 X-Forwarded-For 中的可信代理，取第一个不可信的地址
+func (s *Server) clientIP(r *http.Request) string {
+	peer := remoteIP(r)
+	if !s.fromTrustedProxy(r) {
+		return peer
+	}
+	if cf := strings.TrimSpace(r.Header.Get("CF-Connecting-IP")); net.ParseIP(cf) != nil {
+		return cf
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break // 格式错误，之后的地址都不可信
+		}
+		if i == 0 || !auth.ContainsIP(s.trustedProxies, hop) {
+			return hop
+		}
+	}
+	return peer
+}
+
+// isHTTPS 请求是否经由 HTTPS 到达：本机直接 TLS，或可信代理通过 X-Forwarded-Proto 声明了 https
+func (s *Server) isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if !s.fromTrustedProxy(r) {
+		return false
+	}
+	// 多级代理时取最近一级追加的值
+	protos := strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(protos[len(protos)-1]), "https")
+}
+
+// path 在站内路径前加上 BASE_PATH（用于重定向）
+func (s *Server) path(p string) string {
+	return s.cfg.BasePath + p
+}
+
+// cookiePath 会话 Cookie 的作用路径，同域名下的其他应用看不到
+func (s *Server) cookiePath() string {
+	return s.cfg.BasePath + "/"
+}
+
+// withBasePath 将服务挂载到 BASE_PATH 下：去掉前缀后交给 next，前缀外的请求返回 404
+func (s *Server) withBasePath(next http.Handler) http.Handler {
+	base := s.cfg.BasePath
+	if base == "" {
+		return next
+	}
+	stripped := http.StripPrefix(base, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == base:
+			// 前端使用相对路径，必须以 / 结尾
+			target := base + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, base+"/"):
+			stripped.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hh/heliox-mon/internal/config"
+)
+
+// TestBasePath 测试挂载在子路径下的路由、重定向与 Cookie 路径
+func TestBasePath(t *testing.T) {
+	s := newTestServerWith(t, func(cfg *config.Config) { cfg.BasePath = "/mon" })
+
+	if w := do(s, http.MethodGet, "/mon", "", ""); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/mon/" {
+		t.Errorf("/mon 应跳转到 /mon/，实际 %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := do(s, http.MethodGet, "/mon/", "", ""); w.Code != http.StatusFound || w.Header().Get("Location") != "/mon/login" {
+		t.Errorf("未登录应跳转到 /mon/login，实际 %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := do(s, http.MethodGet, "/login", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("子路径外的请求应返回 404，实际 %d", w.Code)
+	}
+
+	w := do(s, http.MethodPost, "/mon/api/login", `{"username":"admin","password":"secret"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("登录失败: %d %s", w.Code, w.Body.String())
+	}
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == authCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Path != "/mon/" {
+		t.Fatalf("会话 Cookie 路径应为 /mon/: %+v", cookie)
+	}
+	if w := do(s, http.MethodGet, "/mon/api/me", "", cookie.Value); w.Code != http.StatusOK {
+		t.Errorf("子路径下的接口访问失败: %d", w.Code)
+	}
+	if w := do(s, http.MethodGet, "/mon/login", "", cookie.Value); w.Code != http.StatusFound || w.Header().Get("Location") != "/mon/" {
+		t.Errorf("已登录访问登录页应跳转到 /mon/，实际 %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
@@ -3,31 +3,52 @@ package api
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"math"
+	"net"
 	"net/http"
 	"net/url"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/hh/heliox-mon/internal/auth"
 	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/realtime"
 	"github.com/hh/heliox-mon/internal/storage"
 	"github.com/hh/heliox-mon/web"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // Server HTTP 服务器
 type Server struct {
-	cfg    *config.Config
-	db     *storage.DB
-	server *http.Server
+	cfg      *config.Config
+	db       *storage.DB
+	server   *http.Server
+	listener net.Listener
+	realtime *realtime.Hub // 实时网速缓冲，nil 时回退到轮询数据库
+	notifier SecurityNotifier
+	mfa      *mfaChallenges // 等待输入验证码的登录
+
+	listenAddr     string       // 实际监听地址（日志与 systemd 状态）
+	trustedProxies []*net.IPNet // 可信反向代理，只采信它们设置的转发头
+	trustUnixPeers bool         // 采信经 Unix 套接字连接的对端设置的转发头
+
+	// ACME 自动证书，未启用时为 nil
+	acme       *autocert.Manager
+	acmeServer *http.Server // HTTP-01 验证服务
+
+	// 外部身份认证，未配置时为 nil
+	cfAccess           *auth.CFAccess
+	oidc               *auth.OIDC
+	oidcStates         *oidcStates
+	trustedAuthProxies []*net.IPNet
 }
 
 // NewServer 创建服务器
@@ -35,7 +56,10 @@ func NewServer(cfg *config.Config, db *storage.DB) *Server {
 	s := &Server{
 		cfg: cfg,
 		db:  db,
+		mfa: newMFAChallenges(),
 	}
+	s.initProxies()
+	s.initSSO()
 
 	mux := http.NewServeMux()
 
@@ -43,45 +67,92 @@ func NewServer(cfg *config.Config, db *storage.DB) *Server {
 	// Public
 	mux.HandleFunc("/login", s.handleLoginView)
 	mux.HandleFunc("/api/login", s.handleLoginAPI)
+	mux.HandleFunc("/api/auth/providers", s.handleAuthProviders)
+	mux.HandleFunc("/api/oidc/login", s.handleOIDCLogin)
+	mux.HandleFunc("/api/oidc/callback", s.handleOIDCCallback)
+	mux.HandleFunc("/api/logout", s.auth(s.handleLogout))
+	mux.HandleFunc("/api/sessions", s.auth(s.handleSessions))
+	mux.HandleFunc("/api/me", s.auth(s.handleMe))
+	mux.HandleFunc("/api/tokens", s.auth(s.handleTokens))
+	mux.HandleFunc("/api/2fa", s.auth(s.handle2FA))
+	mux.HandleFunc("/api/2fa/setup", s.auth(s.handle2FASetup))
+	mux.HandleFunc("/api/2fa/enable", s.auth(s.handle2FAEnable))
+	mux.HandleFunc("/api/2fa/disable", s.auth(s.handle2FADisable))
+	mux.HandleFunc("/api/2fa/recovery-codes", s.auth(s.handle2FARecoveryCodes))
 
 	// API 路由 (Auth)
 	mux.HandleFunc("/api/stats", s.auth(s.handleStats))
 	mux.HandleFunc("/api/system", s.auth(s.handleSystem))
+	mux.HandleFunc("/api/system/disks", s.auth(s.handleDisks))
+	mux.HandleFunc("/api/system/history", s.auth(s.handleSystemHistory))
 	mux.HandleFunc("/api/traffic/daily", s.auth(s.handleTrafficDaily))
 	mux.HandleFunc("/api/traffic/monthly", s.auth(s.handleTrafficMonthly))
 	mux.HandleFunc("/api/traffic/realtime", s.auth(s.handleTrafficRealtime))
 	mux.HandleFunc("/api/traffic/ports", s.auth(s.handlePortTraffic))
+	mux.HandleFunc("/api/traffic/connections", s.auth(s.handleConnections))
+	mux.HandleFunc("/api/cycles", s.auth(s.handleCycles))
+	mux.HandleFunc("/api/cycles/report", s.auth(s.handleCycleReport))
+	mux.HandleFunc("/api/traffic/anomalies", s.auth(s.handleAnomalies))
+	mux.HandleFunc("/api/network/stats", s.auth(s.handleNetStats))
 	mux.HandleFunc("/api/latency", s.auth(s.handleLatency))
-	mux.HandleFunc("/api/config", s.auth(s.handleConfig))
+	mux.HandleFunc("/api/services", s.auth(s.handleServices))
+	mux.HandleFunc("/api/processes", s.auth(s.handleProcesses))
+	mux.HandleFunc("/api/containers", s.auth(s.handleContainers))
+	mux.HandleFunc("/api/config", s.auth(s.adminOnWrite(s.handleConfig)))
+	mux.HandleFunc("/api/notifications", s.auth(s.adminOnWrite(s.handleNotifications)))
+	mux.HandleFunc("/api/audit", s.auth(s.adminOnly(s.handleAudit)))
+	mux.HandleFunc("/metrics", s.auth(s.handleMetrics))
 
 	// 静态文件 (Auth with exceptions)
 	mux.HandleFunc("/", s.auth(s.handleStatic))
 
 	s.server = &http.Server{
 		Addr:    cfg.ListenAddr,
-		Handler: mux,
+		Handler: s.withBasePath(s.withTLS(mux)),
 	}
 
 	return s
 }
 
-// Start 启动服务器
+// SetRealtime 接入采集器的实时网速缓冲
+func (s *Server) SetRealtime(h *realtime.Hub) {
+	s.realtime = h
+}
+
+// Start 启动服务器（未调用 Listen 时先绑定），阻塞到 Stop
 func (s *Server) Start() error {
-	log.Printf("HTTP 服务启动: %s", s.cfg.ListenAddr)
-	return s.server.ListenAndServe()
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			return err
+		}
+	}
+
+	if s.acme != nil && s.cfg.ACMEHTTPListen != "" {
+		s.acmeServer = &http.Server{
+			Addr:    s.cfg.ACMEHTTPListen,
+			Handler: s.acme.HTTPHandler(nil),
+		}
+		go func() {
+			log.Printf("ACME HTTP 验证服务启动: %s", s.cfg.ACMEHTTPListen)
+			if err := s.acmeServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("ACME HTTP 验证服务错误: %v", err)
+			}
+		}()
+	}
+	return s.serve()
 }
 
 // Stop 停止服务器
 func (s *Server) Stop() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if s.acmeServer != nil {
+		s.acmeServer.Shutdown(ctx)
+	}
 	s.server.Shutdown(ctx)
 }
 
-const (
-	authCookieName = "heliox_auth"
-	authSalt       = "heliox_static_salt_v1"
-)
+const authCookieName = "heliox_auth"
 
 // auth 认证中间件 (Cookie + Basic Fallback)
 func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
@@ -95,29 +166,70 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// 2. Cookie 验证
-		cookie, err := r.Cookie(authCookieName)
-		if err == nil && s.validateToken(cookie.Value) {
-			next(w, r)
+		// 2. API Token（Authorization: Bearer），按接口检查权限范围
+		if secret, ok := bearerToken(r); ok {
+			t, role, err := auth.LookupToken(s.db, secret, s.clientIP(r))
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if scope := tokenScope(r.Method, r.URL.Path); scope == "" || !t.HasScope(scope) {
+				http.Error(w, "Forbidden: token lacks scope "+scope, http.StatusForbidden)
+				return
+			}
+			next(w, withPrincipal(r, &principal{username: t.Username, role: role, token: t}))
 			return
 		}
 
-		// 3. Basic Auth 验证 (API兼容性/旧脚本)
-		user, pass, ok := r.BasicAuth()
-		if ok && user == s.cfg.Username && pass == s.cfg.Password {
-			next(w, r)
+		// 3. 外部身份（Cloudflare Access JWT / 可信代理请求头），不经过本地两步验证
+		if name, present, err := s.externalIdentity(r); present {
+			if err != nil {
+				log.Printf("外部身份校验失败: %v", err)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			u, err := auth.EnsureSSOUser(s.db, name, s.cfg.SSODefaultRole)
+			if err != nil {
+				log.Printf("外部身份 %s 无法登录: %v", name, err)
+				http.Error(w, "Forbidden: unknown user", http.StatusForbidden)
+				return
+			}
+			next(w, withPrincipal(r, &principal{username: u.Username, role: u.Role}))
 			return
 		}
 
-		// 4. 未授权
-		if strings.HasPrefix(r.URL.Path, "/api") {
+		// 4. 会话 Cookie 验证
+		if cookie, err := r.Cookie(authCookieName); err == nil {
+			if sess := s.lookupSession(cookie.Value); sess != nil {
+				next(w, withPrincipal(r, &principal{username: sess.username, role: sess.role, session: sess}))
+				return
+			}
+		}
+
+		// 5. Basic Auth 验证 (API兼容性/旧脚本)
+		if user, pass, ok := r.BasicAuth(); ok {
+			if s.loginThrottled(w, r, user) {
+				return
+			}
+			// 开启两步验证的用户只凭密码不能通过，按普通失败处理，不透露密码是否正确
+			u, err := auth.Authenticate(s.db, user, pass)
+			if err == nil && !auth.TOTPEnabled(s.db, u.Username) {
+				auth.ClearFailures(s.db, s.clientIP(r), user)
+				next(w, withPrincipal(r, &principal{username: u.Username, role: u.Role}))
+				return
+			}
+			s.loginFailed(r, user, "basic")
+		}
+
+		// 6. 未授权
+		if strings.HasPrefix(r.URL.Path, "/api") || r.URL.Path == "/metrics" {
 			// API 请求返回 401
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
 		// 浏览器请求重定向到 /login
-		http.Redirect(w, r, "/login", http.StatusFound)
+		http.Redirect(w, r, s.path("/login"), http.StatusFound)
 	}
 }
 
@@ -129,8 +241,8 @@ func (s *Server) handleLoginView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 如果已登录，跳转首页
-	if cookie, err := r.Cookie(authCookieName); err == nil && s.validateToken(cookie.Value) {
-		http.Redirect(w, r, "/", http.StatusFound)
+	if cookie, err := r.Cookie(authCookieName); err == nil && s.lookupSession(cookie.Value) != nil {
+		http.Redirect(w, r, s.path("/"), http.StatusFound)
 		return
 	}
 
@@ -154,64 +266,103 @@ func (s *Server) handleLoginAPI(w http.ResponseWriter, r *http.Request) {
 		Username       string `json:"username"`
 		Password       string `json:"password"`
 		TurnstileToken string `json:"turnstile_token"`
+		MFAToken       string `json:"mfa_token"` // 第二步：密码校验通过后返回的挑战
+		Code           string `json:"code"`      // 验证码或恢复码
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
+	// 两步验证的第二步
+	if req.MFAToken != "" {
+		s.loginSecondStep(w, r, req.MFAToken, req.Code)
+		return
+	}
+
 	// Turnstile 验证
 	if s.cfg.TurnstileSecretKey != "" {
 		if req.TurnstileToken == "" {
 			http.Error(w, "Missing captcha token", http.StatusForbidden)
 			return
 		}
-		if !s.verifyTurnstile(req.TurnstileToken, r.RemoteAddr) {
+		if !s.verifyTurnstile(req.TurnstileToken, s.clientIP(r)) {
 			http.Error(w, "Captcha validation failed", http.StatusForbidden)
 			return
 		}
 	}
 
-	if req.Username != s.cfg.Username || req.Password != s.cfg.Password {
+	if s.loginThrottled(w, r, req.Username) {
+		return
+	}
+	user, err := auth.Authenticate(s.db, req.Username, req.Password)
+	if err != nil {
+		if err != auth.ErrInvalidPassword {
+			log.Printf("校验用户失败: %v", err)
+		}
+		s.loginFailed(r, req.Username, "password")
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	token := s.generateToken()
-	http.SetCookie(w, &http.Cookie{
-		Name:     authCookieName,
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   86400 * 30, // 30天
-		SameSite: http.SameSiteStrictMode,
-	})
-
-	w.WriteHeader(http.StatusOK)
-}
+	// 已开启两步验证：未同时提交验证码时返回挑战，由前端提示输入
+	if auth.TOTPEnabled(s.db, user.Username) {
+		if req.Code == "" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"mfa_required": true,
+				"mfa_token":    s.mfa.create(user.Username, time.Now()),
+			})
+			return
+		}
+		if err := auth.VerifySecondFactor(s.db, user.Username, req.Code, time.Now()); err != nil {
+			s.loginFailed(r, user.Username, "totp")
+			http.Error(w, "Invalid code", http.StatusUnauthorized)
+			return
+		}
+	}
 
-func (s *Server) generateToken() string {
-	hash := sha256.Sum256([]byte(s.cfg.Username + ":" + s.cfg.Password + ":" + authSalt))
-	return hex.EncodeToString(hash[:])
+	s.completeLogin(w, r, user.Username, "password")
 }
 
-func (s *Server) validateToken(token string) bool {
-	return token == s.generateToken()
+// loginSecondStep 校验两步验证码，通过后创建会话
+func (s *Server) loginSecondStep(w http.ResponseWriter, r *http.Request, mfaToken, code string) {
+	ch := s.mfa.get(mfaToken, time.Now())
+	if ch == nil {
+		http.Error(w, "Login expired, please sign in again", http.StatusUnauthorized)
+		return
+	}
+	if s.loginThrottled(w, r, ch.username) {
+		return
+	}
+	if err := auth.VerifySecondFactor(s.db, ch.username, code, time.Now()); err != nil {
+		s.mfa.fail(mfaToken)
+		s.loginFailed(r, ch.username, "totp")
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+	s.mfa.remove(mfaToken)
+	s.completeLogin(w, r, ch.username, "totp")
 }
 
-// verifyTurnstile 验证 Turnstile Token
-func (s *Server) verifyTurnstile(token string, remoteIP string) bool {
-	// 去除端口号 (IPv4/IPv6 compatibility)
-	if idx := strings.LastIndex(remoteIP, ":"); idx != -1 {
-		// 简单的判断，如果有点，可能是ipv4:port，或者ipv6 [::]:port
-		// 这里简化处理，直接透传或不传ip给cloudflare也行
-		// Cloudflare 文档说 remoteip 是可选的
+// completeLogin 全部校验通过：清除失败计数、记录审计并创建会话
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, username, method string) {
+	auth.ClearFailures(s.db, s.clientIP(r), username)
+	s.audit(r, auth.AuditEntry{Event: auth.EventLogin, Username: username, Detail: method})
+	if err := s.createSession(w, r, username); err != nil {
+		log.Printf("创建会话失败: %v", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
 	}
+	w.WriteHeader(http.StatusOK)
+}
 
+// verifyTurnstile 验证 Turnstile Token，ip 为真实客户端地址（不含端口）
+func (s *Server) verifyTurnstile(token string, ip string) bool {
 	formData := url.Values{}
 	formData.Set("secret", s.cfg.TurnstileSecretKey)
 	formData.Set("response", token)
-	formData.Set("remoteip", remoteIP)
+	formData.Set("remoteip", ip)
 
 	resp, err := http.PostForm("https://challenges.cloudflare.com/turnstile/v0/siteverify", formData)
 	if err != nil {
@@ -239,12 +390,18 @@ func (s *Server) verifyTurnstile(token string, remoteIP string) bool {
 
 // handleStats 仪表盘汇总数据
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Stats())
+}
+
+// Stats 计算仪表盘汇总数据（供 HTTP 接口与 Telegram 机器人共用）
+func (s *Server) Stats() map[string]interface{} {
 	tz := s.cfg.Timezone
 	now := time.Now().In(tz)
 	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
 
 	// 计算计费周期（支持 ResetDay）
-	billingStart, _ := s.getBillingCycleDates(now)
+	billingStart, billingEnd := s.getBillingCycleDates(now)
 	// 计算自然月（用于上月流量）
 	lastMonthStart := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, tz)
 	lastMonthEnd := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, tz).Add(-time.Second)
@@ -305,30 +462,17 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	stats["last_month"] = map[string]int64{"tx": lastMonthTx, "rx": lastMonthRx}
 
 	// 根据 billing_mode 计算已用流量
-	var usedBytes int64
-	switch s.cfg.BillingMode {
-	case "tx_only":
-		usedBytes = monthTx
-	case "rx_only":
-		usedBytes = monthRx
-	case "max_value":
-		if monthTx > monthRx {
-			usedBytes = monthTx
-		} else {
-			usedBytes = monthRx
-		}
-	default: // bidirectional
-		usedBytes = monthTx + monthRx
-	}
+	usedBytes := config.BilledBytes(s.cfg.BillingMode, monthTx, monthRx)
 	stats["used_bytes"] = usedBytes
 
 	stats["monthly_limit_gb"] = s.cfg.MonthlyLimitGB
 	stats["billing_mode"] = s.cfg.BillingMode
 	stats["reset_day"] = s.cfg.ResetDay
+	stats["billing_start"] = billingStart.Format("2006-01-02")
+	stats["billing_end"] = billingEnd.Format("2006-01-02")
 	stats["alert_thresholds"] = s.cfg.AlertThresholds
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
+	return stats
 }
 
 // getBillingCycleDates 根据 ResetDay 计算计费周期起止日期
@@ -349,33 +493,119 @@ func (s *Server) getBillingCycleDates(now time.Time) (start, end time.Time) {
 
 // handleSystem 系统资源
 func (s *Server) handleSystem(w http.ResponseWriter, r *http.Request) {
-	row := s.db.QueryRow(
-		"SELECT ts, cpu_percent, mem_used, mem_total, disk_used, disk_total, load_1, load_5, load_15 FROM system_metrics ORDER BY ts DESC LIMIT 1",
-	)
+	data, err := s.System()
+	if err != nil {
+		http.Error(w, "No data", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// System 读取最新系统资源快照
+func (s *Server) System() (map[string]interface{}, error) {
+	row := s.db.QueryRow(`
+		SELECT ts, cpu_percent, mem_used, mem_total, disk_used, disk_total, load_1, load_5, load_15,
+		       COALESCE(cpu_user, 0), COALESCE(cpu_system, 0), COALESCE(cpu_iowait, 0),
+		       COALESCE(cpu_irq, 0), COALESCE(cpu_softirq, 0), COALESCE(cpu_steal, 0), COALESCE(cpu_cores, ''),
+		       COALESCE(mem_buffers, 0), COALESCE(mem_cached, 0), COALESCE(mem_dirty, 0), COALESCE(mem_writeback, 0),
+		       COALESCE(swap_used, 0), COALESCE(swap_total, 0), COALESCE(oom_kills, 0),
+		       psi_cpu_some_avg10, psi_cpu_some_avg60, psi_cpu_some_total,
+		       psi_mem_some_avg10, psi_mem_some_avg60, psi_mem_some_total, psi_mem_full_avg10, psi_mem_full_avg60, psi_mem_full_total,
+		       psi_io_some_avg10, psi_io_some_avg60, psi_io_some_total, psi_io_full_avg10, psi_io_full_avg60, psi_io_full_total
+		FROM system_metrics ORDER BY ts DESC LIMIT 1
+	`)
 
 	var ts int64
 	var cpu, load1, load5, load15 float64
 	var memUsed, memTotal, diskUsed, diskTotal int64
+	var cpuUser, cpuSystem, cpuIOWait, cpuIRQ, cpuSoftIRQ, cpuSteal float64
+	var coresJSON string
+	var buffers, cached, dirty, writeback, swapUsed, swapTotal, oomKills int64
+	var psi [15]sql.NullFloat64
+
+	dest := []interface{}{&ts, &cpu, &memUsed, &memTotal, &diskUsed, &diskTotal, &load1, &load5, &load15,
+		&cpuUser, &cpuSystem, &cpuIOWait, &cpuIRQ, &cpuSoftIRQ, &cpuSteal, &coresJSON,
+		&buffers, &cached, &dirty, &writeback, &swapUsed, &swapTotal, &oomKills}
+	for i := range psi {
+		dest = append(dest, &psi[i])
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
 
-	if err := row.Scan(&ts, &cpu, &memUsed, &memTotal, &diskUsed, &diskTotal, &load1, &load5, &load15); err != nil {
-		http.Error(w, "No data", http.StatusNotFound)
-		return
+	cores := []float64{}
+	if coresJSON != "" {
+		json.Unmarshal([]byte(coresJSON), &cores)
+	}
+
+	// 最近 1 小时新增的 OOM kill 次数（重启后计数器归零时按当前值计）
+	var oomMin sql.NullInt64
+	s.db.QueryRow("SELECT MIN(oom_kills) FROM system_metrics WHERE ts >= ?", ts-3600).Scan(&oomMin)
+	oomRecent := oomKills
+	if oomMin.Valid && oomMin.Int64 <= oomKills {
+		oomRecent = oomKills - oomMin.Int64
 	}
 
 	data := map[string]interface{}{
 		"ts":          ts,
 		"cpu_percent": cpu,
-		"mem_used":    memUsed,
-		"mem_total":   memTotal,
-		"disk_used":   diskUsed,
-		"disk_total":  diskTotal,
-		"load_1":      load1,
-		"load_5":      load5,
-		"load_15":     load15,
+		"cpu": map[string]interface{}{
+			"user":    cpuUser,
+			"system":  cpuSystem,
+			"iowait":  cpuIOWait,
+			"irq":     cpuIRQ,
+			"softirq": cpuSoftIRQ,
+			"steal":   cpuSteal,
+		},
+		"cpu_cores": cores,
+		"mem_used":  memUsed,
+		"mem_total": memTotal,
+		"memory": map[string]interface{}{
+			"buffers":      buffers,
+			"cached":       cached,
+			"dirty":        dirty,
+			"writeback":    writeback,
+			"swap_used":    swapUsed,
+			"swap_total":   swapTotal,
+			"oom_kills":    oomKills,
+			"oom_kills_1h": oomRecent,
+		},
+		"pressure": map[string]interface{}{
+			"cpu":    psiData(psi[0:3], nil),
+			"memory": psiData(psi[3:6], psi[6:9]),
+			"io":     psiData(psi[9:12], psi[12:15]),
+		},
+		"disk_used":  diskUsed,
+		"disk_total": diskTotal,
+		"disks":      s.latestDiskUsage(),
+		"disk_io":    s.latestDiskIO(),
+		"load_1":     load1,
+		"load_5":     load5,
+		"load_15":    load15,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(data)
+	return data, nil
+}
+
+// psiData 组装 PSI 数据（avg10, avg60, total），内核不支持时返回 nil
+func psiData(some, full []sql.NullFloat64) interface{} {
+	if !some[0].Valid {
+		return nil
+	}
+	stats := func(v []sql.NullFloat64) map[string]interface{} {
+		return map[string]interface{}{
+			"avg10": v[0].Float64,
+			"avg60": v[1].Float64,
+			"total": int64(v[2].Float64),
+		}
+	}
+	result := map[string]interface{}{"some": stats(some)}
+	if full != nil && full[0].Valid {
+		result["full"] = stats(full)
+	}
+	return result
 }
 
 // handleTrafficDaily 每日流量
@@ -515,6 +745,7 @@ func (s *Server) handleTrafficMonthly(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleTrafficRealtime SSE 实时推送
+// 优先从采集器的内存环形缓冲订阅，连接建立时先回放最近 ?replay=N 秒（默认 REALTIME_REPLAY_SECONDS）
 func (s *Server) handleTrafficRealtime(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -526,6 +757,73 @@ func (s *Server) handleTrafficRealtime(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.realtime == nil {
+		s.streamSnapshotRates(w, r, flusher)
+		return
+	}
+
+	replay := s.cfg.RealtimeReplaySeconds
+	if v := r.URL.Query().Get("replay"); v != "" {
+		replay, _ = strconv.Atoi(v)
+	}
+	if replay < 0 {
+		replay = 0
+	}
+	if replay > s.cfg.RealtimeBufferSeconds {
+		replay = s.cfg.RealtimeBufferSeconds
+	}
+
+	// 先订阅再回放，按时间戳去重，避免回放与订阅之间漏掉样本
+	ch, cancel := s.realtime.Subscribe()
+	defer cancel()
+
+	var last time.Time
+	send := func(sample realtime.Sample) {
+		if !sample.TS.After(last) {
+			return
+		}
+		last = sample.TS
+		jsonData, _ := json.Marshal(map[string]interface{}{
+			"tx_speed": sample.Total.Tx,
+			"rx_speed": sample.Total.Rx,
+			"ts":       sample.TS.Unix(),
+			"ts_ms":    sample.TS.UnixMilli(),
+			"ifaces":   sample.Ifaces,
+			"ports":    sample.Ports,
+		})
+		w.Write([]byte("data: " + string(jsonData) + "\n\n"))
+	}
+
+	if replay > 0 {
+		for _, sample := range s.realtime.Since(time.Now().Add(-time.Duration(replay) * time.Second)) {
+			send(sample)
+		}
+	}
+	flusher.Flush()
+
+	// 定期发送注释行，避免反向代理因空闲断开
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case sample, ok := <-ch:
+			if !ok {
+				return
+			}
+			send(sample)
+			flusher.Flush()
+		case <-keepalive.C:
+			w.Write([]byte(": keepalive\n\n"))
+			flusher.Flush()
+		}
+	}
+}
+
+// streamSnapshotRates 未接入实时缓冲时，每秒从流量快照表计算网速
+func (s *Server) streamSnapshotRates(w http.ResponseWriter, r *http.Request, flusher http.Flusher) {
 	ticker := time.NewTicker(1 * time.Second) // 1秒推送
 	defer ticker.Stop()
 
@@ -614,6 +912,19 @@ func (s *Server) handleLatency(w http.ResponseWriter, r *http.Request) {
 	if startStr == "" || endStr == "" {
 		granularityMinutes = 1
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.latencyData(startTime, endTime, granularityMinutes))
+}
+
+// Latency 最近 24 小时延迟数据（与 /api/latency 默认参数一致）
+func (s *Server) Latency() map[string]interface{} {
+	now := time.Now().In(s.cfg.Timezone)
+	return s.latencyData(now.Add(-24*time.Hour), now, 1)
+}
+
+// latencyData 按粒度聚合各目标的延迟数据
+func (s *Server) latencyData(startTime, endTime time.Time, granularityMinutes int) map[string]interface{} {
 	granularitySec := int64(granularityMinutes * 60)
 
 	startTs := startTime.Unix()
@@ -722,8 +1033,7 @@ func (s *Server) handleLatency(w http.ResponseWriter, r *http.Request) {
 		result["targets"] = append(result["targets"].([]map[string]interface{}), targetData)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	return result
 }
 
 func chooseLatencyGranularity(duration time.Duration) int {
@@ -749,6 +1059,12 @@ func chooseLatencyGranularity(duration time.Duration) int {
 
 // handlePortTraffic 端口流量统计
 func (s *Server) handlePortTraffic(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.PortTraffic())
+}
+
+// PortTraffic 计算各端口今日/昨日/本月/上月流量
+func (s *Server) PortTraffic() map[string]interface{} {
 	tz := s.cfg.Timezone
 	now := time.Now().In(tz)
 	today := now.Format("2006-01-02")
@@ -844,8 +1160,7 @@ func (s *Server) handlePortTraffic(w http.ResponseWriter, r *http.Request) {
 		result["ports"] = append(result["ports"].([]map[string]interface{}), portData)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	return result
 }
 
 // handleConfig 配置管理
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleServices 代理服务健康状态与最近事件（?days=N，默认 7）
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().Unix()
+
+	rows, err := s.db.Query(`
+		SELECT port, name, state, since, last_check, COALESCE(pid, 0), COALESCE(process, ''),
+		       COALESCE(unit, ''), COALESCE(started_at, 0), restarts, tcp_listen, udp_bound, COALESCE(last_error, '')
+		FROM service_status ORDER BY port
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	services := []map[string]interface{}{}
+	for rows.Next() {
+		var port, pid, restarts int
+		var name, state, process, unit, lastErr string
+		var since, lastCheck, startedAt int64
+		var tcpListen, udpBound bool
+		if err := rows.Scan(&port, &name, &state, &since, &lastCheck, &pid, &process, &unit, &startedAt,
+			&restarts, &tcpListen, &udpBound, &lastErr); err != nil {
+			continue
+		}
+
+		svc := map[string]interface{}{
+			"port":           port,
+			"name":           name,
+			"state":          state,
+			"since":          since,
+			"last_check":     lastCheck,
+			"uptime_seconds": 0,
+			"pid":            pid,
+			"process":        process,
+			"unit":           unit,
+			"started_at":     startedAt,
+			"restarts":       restarts,
+			"tcp_listen":     tcpListen,
+			"udp_bound":      udpBound,
+			"last_error":     lastErr,
+		}
+		if state == "up" {
+			svc["uptime_seconds"] = now - since
+		}
+		services = append(services, svc)
+	}
+	rows.Close()
+
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days <= 0 || days > 90 {
+		days = 7
+	}
+	rows, err = s.db.Query(`
+		SELECT id, ts, port, name, event, COALESCE(detail, '')
+		FROM service_events WHERE ts >= ? ORDER BY ts DESC LIMIT 200
+	`, time.Now().AddDate(0, 0, -days).Unix())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	events := []map[string]interface{}{}
+	for rows.Next() {
+		var id, ts int64
+		var port int
+		var name, event, detail string
+		if err := rows.Scan(&id, &ts, &port, &name, &event, &detail); err != nil {
+			continue
+		}
+		events = append(events, map[string]interface{}{
+			"id":     id,
+			"ts":     ts,
+			"port":   port,
+			"name":   name,
+			"event":  event,
+			"detail": detail,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"services": services,
+		"events":   events,
+	})
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// sessionTouchInterval last_seen 的最小更新间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+// session 登录会话
+type session struct {
+	id        string // 对外展示与吊销用的 ID（不是 Cookie 值）
+	username  string
+	role      string // 来自 users 表，角色变更立即生效
+	createdAt time.Time
+	lastSeen  time.Time
+	expiresAt time.Time // 绝对过期时间
+	ip        string
+	userAgent string
+}
+
+type ctxKey int
+
+const ctxPrincipal ctxKey = iota
+
+// sessionFromContext 返回当前请求的会话（Basic Auth 请求为 nil）
+func sessionFromContext(ctx context.Context) *session {
+	if p := principalFromContext(ctx); p != nil {
+		return p.session
+	}
+	return nil
+}
+
+// createSession 创建会话并写入 Cookie
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request, username string) error {
+	now := time.Now()
+	token := auth.RandomToken(32)
+	sess := &session{
+		id:        auth.RandomToken(12),
+		username:  username,
+		createdAt: now,
+		lastSeen:  now,
+		expiresAt: now.Add(s.cfg.SessionMaxAge),
+		ip:        s.clientIP(r),
+		userAgent: r.UserAgent(),
+	}
+	if len(sess.userAgent) > 300 {
+		sess.userAgent = sess.userAgent[:300]
+	}
+
+	// 顺便清理过期会话
+	s.db.Exec("DELETE FROM sessions WHERE expires_at < ? OR last_seen < ?",
+		now.Unix(), now.Add(-s.cfg.SessionIdleTimeout).Unix())
+
+	_, err := s.db.Exec(`
+		INSERT INTO sessions (id, token_hash, username, created_at, last_seen, expires_at, ip, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, sess.id, auth.HashToken(token), sess.username, now.Unix(), now.Unix(), sess.expiresAt.Unix(), sess.ip, sess.userAgent)
+	if err != nil {
+		return err
+	}
+	s.audit(r, auth.AuditEntry{Event: auth.EventSessionCreated, Username: username, Target: sess.id})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookieName,
+		Value:    token,
+		Path:     s.cookiePath(),
+		HttpOnly: true,
+		Secure:   s.isHTTPS(r),
+		MaxAge:   int(s.cfg.SessionMaxAge.Seconds()),
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+// lookupSession 校验 Cookie 对应的会话（空闲超时与绝对过期），有效时刷新 last_seen
+// 用户被删除后其会话随之失效
+func (s *Server) lookupSession(token string) *session {
+	if token == "" {
+		return nil
+	}
+	var sess session
+	var createdAt, lastSeen, expiresAt int64
+	err := s.db.QueryRow(`
+		SELECT s.id, s.username, u.role, s.created_at, s.last_seen, s.expires_at, COALESCE(s.ip, ''), COALESCE(s.user_agent, '')
+		FROM sessions s JOIN users u ON u.username = s.username
+		WHERE s.token_hash = ?
+	`, auth.HashToken(token)).Scan(&sess.id, &sess.username, &sess.role, &createdAt, &lastSeen, &expiresAt, &sess.ip, &sess.userAgent)
+	if err != nil {
+		return nil
+	}
+	sess.createdAt, sess.lastSeen, sess.expiresAt = time.Unix(createdAt, 0), time.Unix(lastSeen, 0), time.Unix(expiresAt, 0)
+
+	now := time.Now()
+	if now.After(sess.expiresAt) || now.Sub(sess.lastSeen) > s.cfg.SessionIdleTimeout {
+		s.db.Exec("DELETE FROM sessions WHERE id = ?", sess.id)
+		return nil
+	}
+	if now.Sub(sess.lastSeen) >= sessionTouchInterval {
+		s.db.Exec("UPDATE sessions SET last_seen = ? WHERE id = ?", now.Unix(), sess.id)
+		sess.lastSeen = now
+	}
+	return &sess
+}
+
+// clearSessionCookie 删除浏览器中的会话 Cookie
+func (s *Server) clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookieName,
+		Value:    "",
+		Path:     s.cookiePath(),
+		HttpOnly: true,
+		Secure:   s.isHTTPS(r),
+		MaxAge:   -1,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// handleLogout 注销当前会话
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if sess := sessionFromContext(r.Context()); sess != nil {
+		s.db.Exec("DELETE FROM sessions WHERE id = ?", sess.id)
+		s.audit(r, auth.AuditEntry{Event: auth.EventSessionRevoked, Target: sess.id, Detail: "logout"})
+	}
+	s.clearSessionCookie(w, r)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleSessions 会话列表与吊销
+// GET 列出当前用户的会话；DELETE ?id=xxx 吊销指定会话，DELETE ?all=1 注销全部会话（含当前）
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	current := sessionFromContext(r.Context())
+	username := principalFromContext(r.Context()).username
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := s.db.Query(`
+			SELECT id, created_at, last_seen, expires_at, COALESCE(ip, ''), COALESCE(user_agent, '')
+			FROM sessions WHERE username = ? AND expires_at >= ? AND last_seen >= ?
+			ORDER BY last_seen DESC
+		`, username, time.Now().Unix(), time.Now().Add(-s.cfg.SessionIdleTimeout).Unix())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		sessions := []map[string]interface{}{}
+		for rows.Next() {
+			var id, ip, ua string
+			var createdAt, lastSeen, expiresAt int64
+			if err := rows.Scan(&id, &createdAt, &lastSeen, &expiresAt, &ip, &ua); err != nil {
+				continue
+			}
+			sessions = append(sessions, map[string]interface{}{
+				"id":         id,
+				"created_at": createdAt,
+				"last_seen":  lastSeen,
+				"expires_at": expiresAt,
+				"ip":         ip,
+				"user_agent": ua,
+				"current":    current != nil && current.id == id,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"sessions": sessions})
+
+	case http.MethodDelete:
+		var res sql.Result
+		var err error
+		if r.URL.Query().Get("all") == "1" {
+			res, err = s.db.Exec("DELETE FROM sessions WHERE username = ?", username)
+			s.clearSessionCookie(w, r)
+		} else {
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				http.Error(w, "Missing id", http.StatusBadRequest)
+				return
+			}
+			res, err = s.db.Exec("DELETE FROM sessions WHERE id = ? AND username = ?", id, username)
+			if current != nil && current.id == id {
+				s.clearSessionCookie(w, r)
+			}
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		if n > 0 {
+			target := r.URL.Query().Get("id")
+			if target == "" {
+				target = "all"
+			}
+			s.audit(r, auth.AuditEntry{Event: auth.EventSessionRevoked, Target: target, Detail: strconv.FormatInt(n, 10)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"revoked": n})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	return newTestServerWith(t, nil)
+}
+
+// newTestServerWith 创建测试服务器，modify 非空时在创建前调整配置
+func newTestServerWith(t *testing.T, modify func(cfg *config.Config)) *Server {
+	t.Helper()
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := auth.EnsureBootstrapAdmin(db, "admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Timezone:           time.UTC,
+		Username:           "admin",
+		Password:           "secret",
+		SessionIdleTimeout: time.Hour,
+		SessionMaxAge:      24 * time.Hour,
+	}
+	if modify != nil {
+		modify(cfg)
+	}
+	return NewServer(cfg, db)
+}
+
+// do 发送请求，cookie 非空时附带会话 Cookie
+func do(s *Server, method, target, body, cookie string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: authCookieName, Value: cookie})
+	}
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	return w
+}
+
+func login(t *testing.T, s *Server) string {
+	t.Helper()
+	w := do(s, http.MethodPost, "/api/login", `{"username":"admin","password":"secret"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("登录失败: %d %s", w.Code, w.Body.String())
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == authCookieName {
+			return c.Value
+		}
+	}
+	t.Fatal("登录未设置 Cookie")
+	return ""
+}
+
+// TestSessions 测试会话创建、过期、注销与全部注销
+func TestSessions(t *testing.T) {
+	s := newTestServer(t)
+
+	if w := do(s, http.MethodPost, "/api/login", `{"username":"admin","password":"wrong"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("错误密码应返回 401，实际 %d", w.Code)
+	}
+
+	a, b := login(t, s), login(t, s)
+	if a == b {
+		t.Fatal("每次登录应生成不同的会话")
+	}
+	if w := do(s, http.MethodGet, "/api/config", "", a); w.Code != http.StatusOK {
+		t.Errorf("有效会话访问失败: %d", w.Code)
+	}
+	if w := do(s, http.MethodGet, "/api/config", "", "forged"); w.Code != http.StatusUnauthorized {
+		t.Errorf("伪造 Cookie 应返回 401，实际 %d", w.Code)
+	}
+
+	w := do(s, http.MethodGet, "/api/sessions", "", a)
+	if w.Code != http.StatusOK || strings.Count(w.Body.String(), `"id"`) != 2 || !strings.Contains(w.Body.String(), `"current":true`) {
+		t.Errorf("会话列表 = %s", w.Body.String())
+	}
+
+	// 注销当前会话不影响其他会话
+	if w := do(s, http.MethodPost, "/api/logout", "", a); w.Code != http.StatusOK {
+		t.Fatalf("注销失败: %d", w.Code)
+	}
+	if w := do(s, http.MethodGet, "/api/config", "", a); w.Code != http.StatusUnauthorized {
+		t.Errorf("注销后会话仍有效: %d", w.Code)
+	}
+	if w := do(s, http.MethodGet, "/api/config", "", b); w.Code != http.StatusOK {
+		t.Errorf("其他会话被误注销: %d", w.Code)
+	}
+
+	// 空闲超时
+	s.db.Exec("UPDATE sessions SET last_seen = ?", time.Now().Add(-2*time.Hour).Unix())
+	if w := do(s, http.MethodGet, "/api/config", "", b); w.Code != http.StatusUnauthorized {
+		t.Errorf("空闲超时后会话仍有效: %d", w.Code)
+	}
+
+	// 全部注销
+	c, d := login(t, s), login(t, s)
+	if w := do(s, http.MethodDelete, "/api/sessions?all=1", "", c); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":2`) {
+		t.Errorf("全部注销 = %d %s", w.Code, w.Body.String())
+	}
+	if w := do(s, http.MethodGet, "/api/config", "", d); w.Code != http.StatusUnauthorized {
+		t.Errorf("全部注销后会话仍有效: %d", w.Code)
+	}
+}
+
+// TestViewerRole 测试只读用户可以查看但不能修改
+func TestViewerRole(t *testing.T) {
+	s := newTestServer(t)
+	if err := auth.CreateUser(s.db, "viewer", "viewer-pass", auth.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	w := do(s, http.MethodPost, "/api/login", `{"username":"viewer","password":"viewer-pass"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("登录失败: %d", w.Code)
+	}
+	viewer := w.Result().Cookies()[0].Value
+	admin := login(t, s)
+
+	if w := do(s, http.MethodGet, "/api/me", "", viewer); !strings.Contains(w.Body.String(), `"role":"viewer"`) {
+		t.Errorf("/api/me = %s", w.Body.String())
+	}
+	if w := do(s, http.MethodGet, "/api/config", "", viewer); w.Code != http.StatusOK {
+		t.Errorf("只读用户查看配置 = %d", w.Code)
+	}
+	for _, target := range []string{"/api/config", "/api/notifications?id=1"} {
+		if w := do(s, http.MethodPost, target, "", viewer); w.Code != http.StatusForbidden {
+			t.Errorf("只读用户 POST %s = %d, 期望 403", target, w.Code)
+		}
+		if w := do(s, http.MethodPost, target, "", admin); w.Code == http.StatusForbidden {
+			t.Errorf("管理员 POST %s 被拒绝", target)
+		}
+	}
+
+	// 删除用户后会话立即失效
+	if err := auth.DeleteUser(s.db, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	if w := do(s, http.MethodGet, "/api/me", "", viewer); w.Code != http.StatusUnauthorized {
+		t.Errorf("删除用户后 = %d, 期望 401", w.Code)
+	}
+}
+
+// TestTwoFactorLogin 测试开启两步验证后的两步登录与 Basic Auth 拒绝
+func TestTwoFactorLogin(t *testing.T) {
+	s := newTestServer(t)
+	cookie := login(t, s)
+
+	w := do(s, http.MethodPost, "/api/2fa/setup", "", cookie)
+	var setup struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &setup)
+	if !strings.HasPrefix(setup.URI, "otpauth://totp/") || setup.Secret == "" {
+		t.Fatalf("setup = %d %s", w.Code, w.Body.String())
+	}
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(setup.Secret)
+	code := hotp(key, time.Now().Unix()/30)
+	if w := do(s, http.MethodPost, "/api/2fa/enable", `{"code":"`+code+`"}`, cookie); w.Code != http.StatusOK {
+		t.Fatalf("enable = %d %s", w.Code, w.Body.String())
+	}
+
+	// 第一步只返回挑战，不设置 Cookie
+	w = do(s, http.MethodPost, "/api/login", `{"username":"admin","password":"secret"}`, "")
+	var step1 struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &step1)
+	if !step1.MFARequired || step1.MFAToken == "" || len(w.Result().Cookies()) != 0 {
+		t.Fatalf("第一步 = %d %s", w.Code, w.Body.String())
+	}
+	if w := do(s, http.MethodPost, "/api/login", `{"mfa_token":"`+step1.MFAToken+`","code":"000000x"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("错误验证码 = %d", w.Code)
+	}
+	// 同一窗口的验证码已在开启时使用过，取下一窗口
+	code = hotp(key, time.Now().Unix()/30+1)
+	w = do(s, http.MethodPost, "/api/login", `{"mfa_token":"`+step1.MFAToken+`","code":"`+code+`"}`, "")
+	if w.Code != http.StatusOK || len(w.Result().Cookies()) == 0 {
+		t.Fatalf("第二步 = %d %s", w.Code, w.Body.String())
+	}
+	// 挑战只能使用一次
+	if w := do(s, http.MethodPost, "/api/login", `{"mfa_token":"`+step1.MFAToken+`","code":"`+code+`"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("重复使用挑战 = %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.SetBasicAuth("admin", "secret")
+	bw := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(bw, req)
+	if bw.Code != http.StatusUnauthorized || strings.TrimSpace(bw.Body.String()) != "Unauthorized" {
+		t.Errorf("开启两步验证后 Basic Auth = %d %q, 期望与密码错误相同的 401", bw.Code, bw.Body.String())
+	}
+	var failures int
+	s.db.QueryRow("SELECT COUNT(*) FROM audit_log WHERE event = ? AND detail = 'basic'", auth.EventLoginFailed).Scan(&failures)
+	if failures != 1 {
+		t.Errorf("开启两步验证后 Basic Auth 应计为登录失败，实际 %d 条", failures)
+	}
+}
+
+// hotp 计算验证码（与验证器应用一致）
+func hotp(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1000000)
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+const (
+	// cfAccessHeader Cloudflare Access 附加在回源请求上的 JWT
+	cfAccessHeader = "Cf-Access-Jwt-Assertion"
+	// oidcStateTTL 跳转到身份提供方后完成登录的时限
+	oidcStateTTL = 10 * time.Minute
+	// oidcMaxPending 同时进行中的 OIDC 登录上限（登录入口无需认证，防止占满内存）
+	oidcMaxPending = 1000
+	// oidcStateCookie 把 state 绑定到发起登录的浏览器，防止登录 CSRF
+	oidcStateCookie = "heliox_oidc_state"
+)
+
+// ssoHTTPClient 访问 JWKS 与 OIDC 端点
+var ssoHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// oidcLogin 进行中的 OIDC 登录
+type oidcLogin struct {
+	nonce    string
+	verifier string // PKCE code_verifier
+	expires  time.Time
+}
+
+// oidcStates 以 state 为键的进行中登录（只在内存中）
+type oidcStates struct {
+	mu sync.Mutex
+	m  map[string]*oidcLogin
+}
+
+// create 生成 state 与对应的 nonce / PKCE verifier；进行中的登录已达上限时返回 nil
+func (c *oidcStates) create(now time.Time) (string, *oidcLogin) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 达到上限时才清理过期项，平时不必每次遍历
+	if len(c.m) >= oidcMaxPending {
+		for k, l := range c.m {
+			if now.After(l.expires) {
+				delete(c.m, k)
+			}
+		}
+		if len(c.m) >= oidcMaxPending {
+			return "", nil
+		}
+	}
+	state := auth.RandomToken(24)
+	l := &oidcLogin{nonce: auth.RandomToken(24), verifier: auth.RandomToken(32), expires: now.Add(oidcStateTTL)}
+	c.m[state] = l
+	return state, l
+}
+
+// take 取出并删除（state 只能使用一次）
+func (c *oidcStates) take(state string, now time.Time) *oidcLogin {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.m[state]
+	delete(c.m, state)
+	if l == nil || now.After(l.expires) {
+		return nil
+	}
+	return l
+}
+
+// initSSO 按配置启用外部身份认证
+func (s *Server) initSSO() {
+	if s.cfg.CFAccessTeamDomain != "" {
+		if s.cfg.CFAccessAUD == "" {
+			log.Printf("未设置 CF_ACCESS_AUD，Cloudflare Access 认证未启用")
+		} else {
+			s.cfAccess = auth.NewCFAccess(s.cfg.CFAccessTeamDomain, s.cfg.CFAccessAUD, ssoHTTPClient)
+		}
+	}
+
+	if s.cfg.OIDCIssuer != "" {
+		s.oidc = auth.NewOIDC(auth.OIDCConfig{
+			Issuer:        s.cfg.OIDCIssuer,
+			ClientID:      s.cfg.OIDCClientID,
+			ClientSecret:  s.cfg.OIDCClientSecret,
+			RedirectURL:   s.cfg.OIDCRedirectURL,
+			Scopes:        s.cfg.OIDCScopes,
+			UsernameClaim: s.cfg.OIDCUsernameClaim,
+		}, ssoHTTPClient)
+		s.oidcStates = &oidcStates{m: make(map[string]*oidcLogin)}
+	}
+
+	if s.cfg.TrustedAuthHeader != "" {
+		nets, err := auth.ParseNetworks(s.cfg.TrustedAuthProxies)
+		switch {
+		case err != nil:
+			log.Printf("TRUSTED_AUTH_PROXIES 无效，可信请求头认证未启用: %v", err)
+		case len(nets) == 0:
+			log.Printf("未设置 TRUSTED_AUTH_PROXIES，可信请求头认证未启用")
+		default:
+			s.trustedAuthProxies = nets
+		}
+	}
+}
+
+// externalIdentity 从 Cloudflare Access JWT 或可信代理的请求头识别用户
+// present 为 false 表示请求没有携带外部身份；携带了但校验失败时返回 err
+func (s *Server) externalIdentity(r *http.Request) (username string, present bool, err error) {
+	if s.cfAccess != nil {
+		if token := r.Header.Get(cfAccessHeader); token != "" {
+			username, err = s.cfAccess.Identity(token, time.Now())
+			return username, true, err
+		}
+	}
+	// 只信任直连对端在白名单内的请求，其他来源的同名请求头一律忽略
+	if s.trustedAuthProxies != nil && auth.ContainsIP(s.trustedAuthProxies, remoteIP(r)) {
+		if name := strings.TrimSpace(r.Header.Get(s.cfg.TrustedAuthHeader)); name != "" {
+			return name, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// handleAuthProviders 登录页可用的外部登录方式（公开）
+func (s *Server) handleAuthProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"oidc": s.oidc != nil,
+	})
+}
+
+// handleOIDCLogin 跳转到身份提供方
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+	state, l := s.oidcStates.create(time.Now())
+	if l == nil {
+		log.Printf("进行中的 OIDC 登录过多，拒绝新的登录请求")
+		http.Error(w, "Too many pending logins, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	target, err := s.oidc.AuthURL(r.Context(), state, l.nonce, l.verifier)
+	if err != nil {
+		log.Printf("OIDC 登录失败: %v", err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+	// 回调是身份提供方发起的跨站顶层跳转，Lax 才会带上
+	s.setOIDCStateCookie(w, r, state, int(oidcStateTTL.Seconds()))
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// handleOIDCCallback 身份提供方回调：换取并校验 ID Token，映射到本地用户后创建会话
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	bound := false
+	if c, err := r.Cookie(oidcStateCookie); err == nil && c.Value != "" {
+		bound = subtle.ConstantTimeCompare([]byte(c.Value), []byte(q.Get("state"))) == 1
+	}
+	s.setOIDCStateCookie(w, r, "", -1)
+	if e := q.Get("error"); e != "" {
+		log.Printf("OIDC 登录被拒绝: %s %s", e, q.Get("error_description"))
+		http.Redirect(w, r, s.path("/login?error=oidc"), http.StatusFound)
+		return
+	}
+	if !bound {
+		// 不是本浏览器发起的登录（或 Cookie 已过期），不能消耗 state
+		http.Error(w, "Login expired, please sign in again", http.StatusBadRequest)
+		return
+	}
+	l := s.oidcStates.take(q.Get("state"), time.Now())
+	if l == nil {
+		http.Error(w, "Login expired, please sign in again", http.StatusBadRequest)
+		return
+	}
+
+	name, err := s.oidc.Exchange(r.Context(), q.Get("code"), l.verifier, l.nonce)
+	if err != nil {
+		log.Printf("OIDC 登录失败: %v", err)
+		http.Redirect(w, r, s.path("/login?error=oidc"), http.StatusFound)
+		return
+	}
+	user, err := auth.EnsureSSOUser(s.db, name, s.cfg.SSODefaultRole)
+	if err != nil {
+		log.Printf("OIDC 用户 %s 无法登录: %v", name, err)
+		http.Redirect(w, r, s.path("/login?error=user"), http.StatusFound)
+		return
+	}
+	s.audit(r, auth.AuditEntry{Event: auth.EventLogin, Username: user.Username, Detail: "oidc"})
+	if err := s.createSession(w, r, user.Username); err != nil {
+		log.Printf("创建会话失败: %v", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	s.writeLoginRedirect(w, s.path("/"))
+}
+
+// setOIDCStateCookie 写入或删除（maxAge < 0）登录 state Cookie
+func (s *Server) setOIDCStateCookie(w http.ResponseWriter, r *http.Request, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     s.cookiePath(),
+		HttpOnly: true,
+		Secure:   s.isHTTPS(r),
+		MaxAge:   maxAge,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// writeLoginRedirect 用同源页面跳转到 target
+// 回调由身份提供方跨站跳转而来，若直接 302，整条跳转链仍算跨站，浏览器不会带上 SameSite=Strict 的会话 Cookie
+func (s *Server) writeLoginRedirect(w http.ResponseWriter, target string) {
+	t := html.EscapeString(target)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintf(w, `<!DOCTYPE html><html><head><meta charset="utf-8"><meta http-equiv="refresh" content="0;url=%s"></head><body><a href="%s">Continue</a></body></html>`, t, t)
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// fakeIdP 本地身份提供方：同时提供 Cloudflare Access 证书地址与 OIDC 发现/Token 端点
+type fakeIdP struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	nonce string // 授权请求中的 nonce，Token 端点签发时带回
+	sub   string // 签发的邮箱
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeIdP{key: key, sub: "alice@example.com"}
+	mux := http.NewServeMux()
+	jwks := func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1", "kty": "RSA",
+			"n": b64.EncodeToString(key.N.Bytes()),
+			"e": b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}
+	mux.HandleFunc("/cdn-cgi/access/certs", jwks)
+	mux.HandleFunc("/jwks", jwks)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("client_secret") != "s3cret" || r.Form.Get("code_verifier") == "" {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": p.sign(t, map[string]interface{}{
+				"iss": p.URL, "aud": "heliox", "email": p.sub, "email_verified": true,
+				"nonce": p.nonce, "exp": time.Now().Add(time.Hour).Unix(),
+			}),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// sign 签发 RS256 JWT
+func (p *fakeIdP) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	b64 := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + b64.EncodeToString(sig)
+}
+
+// TestCFAccess 测试 Cloudflare Access JWT 的校验与用户映射
+func TestCFAccess(t *testing.T) {
+	idp := newFakeIdP(t)
+	s := newTestServer(t)
+	s.cfg.CFAccessTeamDomain, s.cfg.CFAccessAUD = idp.URL, "app-aud"
+	s.initSSO()
+
+	request := func(claims map[string]interface{}) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set(cfAccessHeader, idp.sign(t, claims))
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	// 未开启自动创建时，只允许预先添加的 SSO 用户
+	if code := request(map[string]interface{}{"iss": idp.URL, "aud": []string{"app-aud"}, "email": "alice@example.com", "exp": exp}); code != http.StatusForbidden {
+		t.Errorf("未知用户应返回 403，实际 %d", code)
+	}
+	if err := auth.CreateSSOUser(s.db, "alice@example.com", auth.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	if code := request(map[string]interface{}{"iss": idp.URL, "aud": []string{"app-aud"}, "email": "alice@example.com", "exp": exp}); code != http.StatusOK {
+		t.Errorf("有效 JWT 应通过，实际 %d", code)
+	}
+	if code := request(map[string]interface{}{"iss": idp.URL, "aud": []string{"other-app"}, "email": "alice@example.com", "exp": exp}); code != http.StatusUnauthorized {
+		t.Errorf("aud 不匹配应返回 401，实际 %d", code)
+	}
+	if code := request(map[string]interface{}{"iss": idp.URL, "aud": "app-aud", "email": "alice@example.com", "exp": time.Now().Add(-time.Hour).Unix()}); code != http.StatusUnauthorized {
+		t.Errorf("过期 JWT 应返回 401，实际 %d", code)
+	}
+
+	// 伪造签名
+	token := idp.sign(t, map[string]interface{}{"iss": idp.URL, "aud": "app-aud", "email": "alice@example.com", "exp": exp})
+	parts := strings.Split(token, ".")
+	forged, _ := json.Marshal(map[string]interface{}{"iss": idp.URL, "aud": "app-aud", "email": "admin", "exp": exp})
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set(cfAccessHeader, parts[0]+"."+base64.RawURLEncoding.EncodeToString(forged)+"."+parts[2])
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("签名无效应返回 401，实际 %d", w.Code)
+	}
+}
+
+// TestTrustedHeader 测试只接受白名单代理设置的身份请求头
+func TestTrustedHeader(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.TrustedAuthHeader = "X-Remote-User"
+	s.cfg.TrustedAuthProxies = []string{"10.0.0.0/8"}
+	s.cfg.SSODefaultRole = auth.RoleViewer
+	s.initSSO()
+
+	request := func(remote string, user ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Remote-User", "bob")
+		if len(user) > 0 {
+			req.Header.Set("X-Remote-User", user[0])
+		}
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("203.0.113.5:4000"); w.Code != http.StatusUnauthorized {
+		t.Errorf("非白名单来源的请求头应被忽略，实际 %d", w.Code)
+	}
+	w := request("10.1.2.3:4000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("白名单代理应通过，实际 %d", w.Code)
+	}
+	var me map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&me)
+	if me["username"] != "bob" || me["role"] != auth.RoleViewer {
+		t.Errorf("身份映射错误: %v", me)
+	}
+	if u, err := auth.GetUser(s.db, "bob"); err != nil || u.Source != auth.SourceSSO {
+		t.Errorf("应自动创建 SSO 用户: %v %v", u, err)
+	}
+
+	// 外部身份不能映射到同名的本地账户（引导管理员、命令行创建的用户）
+	if err := auth.CreateUser(s.db, "carol", "password123", auth.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"admin", "carol"} {
+		if w := request("10.1.2.3:4000", name); w.Code != http.StatusForbidden {
+			t.Errorf("外部身份 %s 映射到本地账户应返回 403，实际 %d", name, w.Code)
+		}
+	}
+}
+
+// TestOIDCLogin 测试授权码登录：跳转、回调换取 ID Token、创建会话
+func TestOIDCLogin(t *testing.T) {
+	idp := newFakeIdP(t)
+	s := newTestServer(t)
+	s.cfg.OIDCIssuer, s.cfg.OIDCClientID, s.cfg.OIDCClientSecret = idp.URL, "heliox", "s3cret"
+	s.cfg.OIDCRedirectURL = "https://mon.example.com/api/oidc/callback"
+	s.cfg.SSODefaultRole = auth.RoleViewer
+	s.initSSO()
+
+	w := do(s, http.MethodGet, "/api/auth/providers", "", "")
+	if !strings.Contains(w.Body.String(), `"oidc":true`) {
+		t.Errorf("应返回已启用 OIDC: %s", w.Body.String())
+	}
+
+	w = do(s, http.MethodGet, "/api/oidc/login", "", "")
+	if w.Code != http.StatusFound {
+		t.Fatalf("应跳转到身份提供方，实际 %d %s", w.Code, w.Body.String())
+	}
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	q := loc.Query()
+	if !strings.HasPrefix(loc.String(), idp.URL+"/authorize") || q.Get("code_challenge_method") != "S256" || q.Get("state") == "" {
+		t.Fatalf("授权地址错误: %s", loc)
+	}
+	idp.nonce = q.Get("nonce")
+	state := q.Get("state")
+	var stateCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oidcStateCookie {
+			stateCookie = c
+		}
+	}
+	if stateCookie == nil || stateCookie.Value != state || !stateCookie.HttpOnly || stateCookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("应设置绑定 state 的 Lax Cookie: %+v", stateCookie)
+	}
+	callback := func(state, cookieState string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/oidc/callback?state="+url.QueryEscape(state)+"&code=good-code", nil)
+		if cookieState != "" {
+			req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: cookieState})
+		}
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未知 state 不能完成登录
+	if w := callback("bogus", "bogus"); w.Code != http.StatusBadRequest {
+		t.Errorf("未知 state 应返回 400，实际 %d", w.Code)
+	}
+	// 登录 CSRF：其他浏览器拿到的回调地址（无 Cookie 或 Cookie 不符）不能完成登录，也不消耗 state
+	if w := callback(state, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("缺少 state Cookie 应返回 400，实际 %d", w.Code)
+	}
+	if w := callback(state, "other"); w.Code != http.StatusBadRequest {
+		t.Errorf("state Cookie 不符应返回 400，实际 %d", w.Code)
+	}
+
+	w = callback(state, state)
+	// 不能直接 302：跨站跳转链中浏览器不会发送 Strict 会话 Cookie，需由同源页面再跳转首页
+	if w.Code != http.StatusOK || w.Header().Get("Location") != "" || !strings.Contains(w.Body.String(), `content="0;url=/"`) {
+		t.Fatalf("回调应创建会话并返回同源跳转页，实际 %d %s %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	var cookie string
+	for _, c := range w.Result().Cookies() {
+		if c.Name == authCookieName {
+			cookie = c.Value
+		}
+	}
+	w = do(s, http.MethodGet, "/api/me", "", cookie)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "alice@example.com") {
+		t.Errorf("OIDC 会话无效: %d %s", w.Code, w.Body.String())
+	}
+
+	// state 只能使用一次
+	if w := callback(state, state); w.Code != http.StatusBadRequest {
+		t.Errorf("重复使用 state 应返回 400，实际 %d", w.Code)
+	}
+
+	// 进行中的登录达到上限后拒绝新登录，过期项清理后恢复
+	for range oidcMaxPending {
+		s.oidcStates.create(time.Now())
+	}
+	if w := do(s, http.MethodGet, "/api/oidc/login", "", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("进行中的登录已满应返回 503，实际 %d", w.Code)
+	}
+	if _, l := s.oidcStates.create(time.Now().Add(oidcStateTTL + time.Second)); l == nil {
+		t.Error("过期项清理后应允许新登录")
+	}
+}
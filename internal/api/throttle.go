@@ -0,0 +1,62 @@
+package api
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+	"github.com/hh/heliox-mon/internal/notifier"
+)
+
+// SecurityNotifier 登录安全报警
+type SecurityNotifier interface {
+	SendSecurityAlert(a *notifier.SecurityAlert) error
+}
+
+// SetNotifier 接入通知器（登录锁定报警）
+func (s *Server) SetNotifier(n SecurityNotifier) {
+	s.notifier = n
+}
+
+// throttle 登录失败限制参数
+func (s *Server) throttle() auth.Throttle {
+	return auth.Throttle{
+		BackoffAfter: s.cfg.LoginBackoffAfter,
+		LockoutAfter: s.cfg.LoginLockoutAfter,
+		Lockout:      s.cfg.LoginLockout,
+	}
+}
+
+// loginThrottled IP 或用户名处于退避/锁定期时返回 429 并设置 Retry-After
+func (s *Server) loginThrottled(w http.ResponseWriter, r *http.Request, username string) bool {
+	wait := s.throttle().Blocked(s.db, s.clientIP(r), username, time.Now())
+	if wait <= 0 {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many failed attempts, retry later", http.StatusTooManyRequests)
+	return true
+}
+
+// loginFailed 记录失败（审计 + 计数），新触发锁定时推送报警
+func (s *Server) loginFailed(r *http.Request, username, method string) {
+	ip, now := s.clientIP(r), time.Now()
+	s.audit(r, auth.AuditEntry{Event: auth.EventLoginFailed, Username: username, Detail: method})
+
+	for _, l := range s.throttle().RecordFailure(s.db, ip, username, now) {
+		log.Printf("登录连续失败 %d 次，锁定 %s 至 %s", l.Failures, l.Key, l.Until.Format("15:04:05"))
+		s.audit(r, auth.AuditEntry{Event: auth.EventLoginLocked, Username: username, Target: l.Key})
+		if s.notifier == nil {
+			continue
+		}
+		err := s.notifier.SendSecurityAlert(&notifier.SecurityAlert{
+			Key: l.Key, Failures: l.Failures, Until: l.Until, IP: ip, Username: username, Time: now,
+		})
+		if err != nil {
+			log.Printf("发送登录锁定报警失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/notifier"
+)
+
+type securityRecorder struct {
+	alerts []*notifier.SecurityAlert
+}
+
+func (r *securityRecorder) SendSecurityAlert(a *notifier.SecurityAlert) error {
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+// loginFrom 从指定地址登录
+func loginFrom(s *Server, remote, password string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/login",
+		strings.NewReader(`{"username":"admin","password":"`+password+`"}`))
+	req.RemoteAddr = remote
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	return w
+}
+
+// TestLoginThrottle 测试失败退避、锁定、审计与报警
+func TestLoginThrottle(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.LoginBackoffAfter = 2
+	s.cfg.LoginLockoutAfter = 4
+	s.cfg.LoginLockout = 10 * time.Minute
+	rec := &securityRecorder{}
+	s.SetNotifier(rec)
+
+	const attacker = "203.0.113.5:4000"
+	for i := 0; i < 2; i++ {
+		if w := loginFrom(s, attacker, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("第 %d 次失败 = %d", i+1, w.Code)
+		}
+	}
+	// 第 2 次失败后尚未退避，第 3 次失败后需等待 1 秒
+	if w := loginFrom(s, attacker, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("第 3 次失败 = %d", w.Code)
+	}
+	w := loginFrom(s, attacker, "secret")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("退避期内 = %d Retry-After=%q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// 跳过退避期，第 4 次失败触发锁定（IP 与用户名各一次）
+	s.db.Exec("UPDATE login_failures SET blocked_until = 0")
+	loginFrom(s, attacker, "wrong")
+	if len(rec.alerts) != 2 {
+		t.Fatalf("锁定报警 %d 条, 期望 2", len(rec.alerts))
+	}
+	// 用户名被锁定后，其他 IP 使用正确密码也要等待
+	if w := loginFrom(s, "198.51.100.7:4000", "secret"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("用户锁定期间 = %d, 期望 429", w.Code)
+	}
+
+	// Basic Auth 同样受限
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.RemoteAddr = attacker
+	req.SetBasicAuth("admin", "secret")
+	bw := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(bw, req)
+	if bw.Code != http.StatusTooManyRequests {
+		t.Errorf("Basic Auth 锁定期间 = %d, 期望 429", bw.Code)
+	}
+
+	var failed, locked int
+	s.db.QueryRow("SELECT COUNT(*) FROM audit_log WHERE event = 'login_failed'").Scan(&failed)
+	s.db.QueryRow("SELECT COUNT(*) FROM audit_log WHERE event = 'login_locked'").Scan(&locked)
+	if failed != 4 || locked != 2 {
+		t.Errorf("审计记录 failed=%d locked=%d, 期望 4 和 2", failed, locked)
+	}
+
+	// 解锁后登录成功并清除计数
+	s.db.Exec("DELETE FROM login_failures")
+	if w := loginFrom(s, attacker, "secret"); w.Code != http.StatusOK {
+		t.Errorf("解锁后登录 = %d", w.Code)
+	}
+}
+
+// TestClientIP 测试本机反向代理后的真实客户端 IP
+func TestClientIP(t *testing.T) {
+	s := &Server{cfg: &config.Config{TrustedProxies: []string{"127.0.0.1", "::1", "10.0.0.0/8"}}}
+	s.initProxies()
+
+	cases := []struct {
+		remote string
+		header map[string]string
+		want   string
+	}{
+		{"203.0.113.5:1234", map[string]string{"CF-Connecting-IP": "1.1.1.1"}, "203.0.113.5"}, // 非可信代理，忽略头
+		{"127.0.0.1:1234", map[string]string{"CF-Connecting-IP": "1.1.1.1"}, "1.1.1.1"},
+		{"127.0.0.1:1234", map[string]string{"X-Forwarded-For": "9.9.9.9, 2.2.2.2"}, "2.2.2.2"},
+		{"10.0.0.2:1234", map[string]string{"X-Forwarded-For": "9.9.9.9, 2.2.2.2, 10.0.0.7"}, "2.2.2.2"}, // 跳过可信的中间代理
+		{"[::1]:1234", map[string]string{"X-Forwarded-For": "garbage"}, "::1"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = c.remote
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		if got := s.clientIP(req); got != c.want {
+			t.Errorf("clientIP(%s, %v) = %s, 期望 %s", c.remote, c.header, got, c.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.5:1234"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if s.isHTTPS(req) {
+		t.Error("非可信代理的 X-Forwarded-Proto 不应被采信")
+	}
+	req.RemoteAddr = "10.1.1.1:1234"
+	if !s.isHTTPS(req) {
+		t.Error("可信代理声明 https 时应视为 HTTPS")
+	}
+}
+
+// TestTwoFactorDisableThrottle 测试关闭两步验证时的密码校验与登录共用失败计数
+func TestTwoFactorDisableThrottle(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.LoginBackoffAfter = 2
+	s.cfg.LoginLockoutAfter = 10
+	cookie := login(t, s)
+
+	for i := 0; i < 3; i++ {
+		if w := do(s, http.MethodPost, "/api/2fa/disable", `{"password":"wrong","code":"000000"}`, cookie); w.Code != http.StatusForbidden {
+			t.Fatalf("第 %d 次密码错误 = %d", i+1, w.Code)
+		}
+	}
+	w := do(s, http.MethodPost, "/api/2fa/disable", `{"password":"secret","code":"000000"}`, cookie)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("退避期内 = %d Retry-After=%q, 期望 429", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	var failed int
+	s.db.QueryRow("SELECT COUNT(*) FROM audit_log WHERE event = 'login_failed' AND detail = '2fa_disable'").Scan(&failed)
+	if failed != 3 {
+		t.Errorf("login_failed 审计 %d 条, 期望 3", failed)
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certCheckInterval 检查证书文件是否更新的最小间隔
+const certCheckInterval = 10 * time.Second
+
+// certReloader 从文件加载证书，文件修改后在下一次握手时自动重新加载（无需重启）
+type certReloader struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time // 证书与私钥中较新的修改时间
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// modified 证书与私钥中较新的修改时间
+func (c *certReloader) modified() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (c *certReloader) load() error {
+	modTime, err := c.modified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert, c.modTime = &cert, modTime
+	return nil
+}
+
+// GetCertificate tls.Config 回调；重新加载失败时继续使用旧证书
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.checkedAt) >= certCheckInterval {
+		c.checkedAt = now
+		if modTime, err := c.modified(); err == nil && !modTime.Equal(c.modTime) {
+			if err := c.load(); err != nil {
+				log.Printf("重新加载 TLS 证书失败，继续使用旧证书: %v", err)
+			} else {
+				log.Printf("已重新加载 TLS 证书: %s", c.certFile)
+			}
+		}
+	}
+	return c.cert, nil
+}
+
+// tlsEnabled 是否启用 HTTPS
+func (s *Server) tlsEnabled() bool {
+	return s.cfg.TLSCertFile != "" || len(s.cfg.ACMEDomains) > 0
+}
+
+// tlsConfig 按配置构造 TLS 参数，未启用 HTTPS 时返回 nil
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	if !s.tlsEnabled() {
+		return nil, nil
+	}
+
+	var cfg *tls.Config
+	switch {
+	case s.cfg.TLSCertFile != "":
+		if s.cfg.TLSKeyFile == "" {
+			return nil, errors.New("设置了 TLS_CERT_FILE 但缺少 TLS_KEY_FILE")
+		}
+		reloader, err := newCertReloader(s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载 TLS 证书失败: %w", err)
+		}
+		cfg = &tls.Config{GetCertificate: reloader.GetCertificate}
+
+	default:
+		m, err := s.acmeManager()
+		if err != nil {
+			return nil, err
+		}
+		s.acme = m
+		cfg = m.TLSConfig()
+	}
+	cfg.MinVersion = tls.VersionTLS12
+
+	if s.cfg.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(s.cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端 CA 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("客户端 CA 文件中没有有效证书: %s", s.cfg.TLSClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		switch s.cfg.TLSClientAuth {
+		case "require":
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		case "api":
+			// 握手时可不带证书（浏览器访问页面），由 withTLS 对接口强制检查
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("TLS_CLIENT_AUTH 只能是 require 或 api: %s", s.cfg.TLSClientAuth)
+		}
+
+		// ACME TLS-ALPN-01 验证请求不会携带客户端证书
+		base := cfg
+		cfg = base.Clone()
+		cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			for _, proto := range hello.SupportedProtos {
+				if proto == acme.ALPNProto {
+					relaxed := base.Clone()
+					relaxed.ClientAuth = tls.NoClientCert
+					return relaxed, nil
+				}
+			}
+			return nil, nil
+		}
+	}
+	return cfg, nil
+}
+
+// acmeManager 自动申请与续期证书，缓存在数据目录的 acme 子目录
+func (s *Server) acmeManager() (*autocert.Manager, error) {
+	client := &acme.Client{DirectoryURL: s.cfg.ACMEDirectory}
+	if s.cfg.ACMECAFile != "" {
+		pem, err := os.ReadFile(s.cfg.ACMECAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 ACME CA 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ACME CA 文件中没有有效证书: %s", s.cfg.ACMECAFile)
+		}
+		client.HTTPClient = &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		}
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(s.cfg.DataPath("acme")),
+		HostPolicy: autocert.HostWhitelist(s.cfg.ACMEDomains...),
+		Email:      s.cfg.ACMEEmail,
+		Client:     client,
+	}, nil
+}
+
+// withTLS HTTPS 时添加 HSTS，并在 api 模式下要求接口请求携带已验证的客户端证书
+func (s *Server) withTLS(next http.Handler) http.Handler {
+	if !s.tlsEnabled() {
+		return next
+	}
+	hsts := ""
+	if s.cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(s.cfg.HSTSMaxAge)
+	}
+	apiOnly := s.cfg.TLSClientCAFile != "" && s.cfg.TLSClientAuth == "api"
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && hsts != "" {
+			w.Header().Set("Strict-Transport-Security", hsts)
+		}
+		if apiOnly && (strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/metrics") {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				http.Error(w, "Client certificate required", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+)
+
+// issueCert 签发测试证书，parent 为 nil 时生成自签名 CA
+func issueCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// TestCertReload 测试证书文件更新后自动重新加载，无效文件时保留旧证书
+func TestCertReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	write := func(certPEM, keyPEM []byte, mtime time.Time) {
+		os.WriteFile(certFile, certPEM, 0600)
+		os.WriteFile(keyFile, keyPEM, 0600)
+		os.Chtimes(certFile, mtime, mtime)
+		os.Chtimes(keyFile, mtime, mtime)
+	}
+
+	_, _, certPEM, keyPEM := issueCert(t, "first", nil, nil)
+	write(certPEM, keyPEM, time.Now().Add(-time.Minute))
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, _, certPEM, keyPEM := issueCert(t, "second", nil, nil)
+	write(certPEM, keyPEM, time.Now())
+	r.checkedAt = time.Time{}
+	if c, _ := r.GetCertificate(nil); c.Leaf == nil || !c.Leaf.Equal(second) {
+		t.Error("证书更新后未重新加载")
+	}
+
+	write([]byte("broken"), keyPEM, time.Now().Add(time.Minute))
+	r.checkedAt = time.Time{}
+	if c, _ := r.GetCertificate(nil); c.Leaf == nil || !c.Leaf.Equal(second) {
+		t.Error("证书无效时应保留旧证书")
+	}
+}
+
+// TestClientCertAPI 测试 api 模式：页面无需客户端证书，接口必须携带 CA 签发的证书；HTTPS 响应带 HSTS
+func TestClientCertAPI(t *testing.T) {
+	dir := t.TempDir()
+	_, _, certPEM, keyPEM := issueCert(t, "server", nil, nil)
+	ca, caKey, caPEM, _ := issueCert(t, "client-ca", nil, nil)
+	_, _, clientPEM, clientKeyPEM := issueCert(t, "client", ca, caKey)
+	for name, data := range map[string][]byte{"cert.pem": certPEM, "key.pem": keyPEM, "ca.pem": caPEM} {
+		os.WriteFile(filepath.Join(dir, name), data, 0600)
+	}
+
+	s := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.TLSCertFile = filepath.Join(dir, "cert.pem")
+		cfg.TLSKeyFile = filepath.Join(dir, "key.pem")
+		cfg.TLSClientCAFile = filepath.Join(dir, "ca.pem")
+		cfg.TLSClientAuth = "api"
+		cfg.HSTSMaxAge = 3600
+	})
+	tlsCfg, err := s.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(s.server.Handler)
+	ts.TLS = tlsCfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	get := func(path string, certs ...tls.Certificate) *http.Response {
+		t.Helper()
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs},
+		}}
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("/login")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("无证书访问登录页 = %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Strict-Transport-Security") != "max-age=3600" {
+		t.Errorf("HSTS = %q", resp.Header.Get("Strict-Transport-Security"))
+	}
+	if resp := get("/api/me"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("无证书访问接口应返回 403，实际 %d", resp.StatusCode)
+	}
+
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := get("/api/me", clientCert); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("携带证书后应进入登录认证（401），实际 %d", resp.StatusCode)
+	}
+}
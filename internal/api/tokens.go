@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// tokenScope 接口所需的 Token 权限范围，空字符串表示 Token 不可访问（登录、会话、Token 管理等）
+func tokenScope(method, path string) string {
+	write := method != http.MethodGet && method != http.MethodHead
+	switch {
+	case path == "/metrics":
+		return auth.ScopeMetrics
+	case path == "/api/config":
+		if write {
+			return auth.ScopeWriteConfig
+		}
+		return auth.ScopeReadSystem
+	case write:
+		return ""
+	case path == "/api/stats", strings.HasPrefix(path, "/api/traffic/"), strings.HasPrefix(path, "/api/cycles"):
+		return auth.ScopeReadTraffic
+	case path == "/api/latency":
+		return auth.ScopeReadLatency
+	case strings.HasPrefix(path, "/api/system"), strings.HasPrefix(path, "/api/network/"),
+		path == "/api/services", path == "/api/processes", path == "/api/containers", path == "/api/notifications":
+		return auth.ScopeReadSystem
+	}
+	return ""
+}
+
+// bearerToken 读取 Authorization: Bearer 凭据
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(h[7:]), true
+}
+
+// tokenData Token 的 JSON 表示（不含明文）
+func tokenData(t *auth.Token) map[string]interface{} {
+	unix := func(ts time.Time) interface{} {
+		if ts.IsZero() {
+			return nil
+		}
+		return ts.Unix()
+	}
+	allowIPs := t.AllowIPs
+	if allowIPs == nil {
+		allowIPs = []string{}
+	}
+	return map[string]interface{}{
+		"id":           t.ID,
+		"name":         t.Name,
+		"username":     t.Username,
+		"scopes":       t.Scopes,
+		"allow_ips":    allowIPs,
+		"created_at":   t.CreatedAt.Unix(),
+		"expires_at":   unix(t.ExpiresAt),
+		"last_used_at": unix(t.LastUsedAt),
+		"last_used_ip": t.LastUsedIP,
+	}
+}
+
+// handleTokens API Token 管理（仅限登录会话或 Basic Auth，Token 本身不能管理 Token）
+// GET 列出当前用户的 Token（管理员 ?all=1 列出全部）
+// POST {"name", "scopes", "expires_days", "allow_ips"} 创建，明文只在响应中出现一次
+// DELETE ?id=xxx 吊销（管理员可吊销任意用户的 Token）
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
+	p := principalFromContext(r.Context())
+	owner := p.username
+	if p.role == auth.RoleAdmin {
+		owner = ""
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		filter := p.username
+		if owner == "" && r.URL.Query().Get("all") == "1" {
+			filter = ""
+		}
+		tokens, err := auth.ListTokens(s.db, filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		list := []map[string]interface{}{}
+		for i := range tokens {
+			list = append(list, tokenData(&tokens[i]))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"tokens": list, "scopes": auth.Scopes})
+
+	case http.MethodPost:
+		var req struct {
+			Name        string   `json:"name"`
+			Scopes      []string `json:"scopes"`
+			ExpiresDays int      `json:"expires_days"`
+			AllowIPs    []string `json:"allow_ips"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		secret, t, err := auth.CreateToken(s.db, auth.TokenOptions{
+			Name:     req.Name,
+			Username: p.username,
+			Scopes:   req.Scopes,
+			TTL:      time.Duration(req.ExpiresDays) * 24 * time.Hour,
+			AllowIPs: req.AllowIPs,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.audit(r, auth.AuditEntry{
+			Event: auth.EventTokenCreated, Target: t.ID,
+			Detail: t.Username + "/" + t.Name + " " + strings.Join(t.Scopes, ","),
+		})
+		data := tokenData(t)
+		data["token"] = secret
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "Missing id", http.StatusBadRequest)
+			return
+		}
+		if err := auth.RevokeToken(s.db, id, owner); err != nil {
+			if err == auth.ErrTokenNotFound {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.audit(r, auth.AuditEntry{Event: auth.EventTokenRevoked, Target: id})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"revoked": 1})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// doToken 携带 Bearer Token 发送请求
+func doToken(s *Server, method, target, token, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if remoteAddr != "" {
+		req.RemoteAddr = remoteAddr
+	}
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	return w
+}
+
+// TestAPITokens 测试 Token 创建、权限范围、IP 白名单、过期与吊销
+func TestAPITokens(t *testing.T) {
+	s := newTestServer(t)
+	cookie := login(t, s)
+
+	w := do(s, http.MethodPost, "/api/tokens",
+		`{"name":"grafana","scopes":["read:traffic","metrics"],"allow_ips":["192.0.2.0/24"]}`, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("创建 Token 失败: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if !strings.HasPrefix(created.Token, auth.TokenPrefix) {
+		t.Fatalf("Token = %q", created.Token)
+	}
+
+	const allowed, denied = "192.0.2.10:5000", "198.51.100.1:5000"
+	cases := []struct {
+		method, target, remote string
+		want                   int
+	}{
+		{http.MethodGet, "/api/stats", allowed, http.StatusOK},
+		{http.MethodGet, "/metrics", allowed, http.StatusOK},
+		{http.MethodGet, "/api/latency", allowed, http.StatusForbidden}, // 缺少 read:latency
+		{http.MethodPost, "/api/config", allowed, http.StatusForbidden}, // 缺少 write:config
+		{http.MethodGet, "/api/tokens", allowed, http.StatusForbidden},  // Token 不能管理 Token
+		{http.MethodGet, "/api/stats", denied, http.StatusUnauthorized}, // 不在白名单
+	}
+	for _, c := range cases {
+		if w := doToken(s, c.method, c.target, created.Token, c.remote); w.Code != c.want {
+			t.Errorf("%s %s 来自 %s = %d, 期望 %d", c.method, c.target, c.remote, w.Code, c.want)
+		}
+	}
+	if w := doToken(s, http.MethodGet, "/metrics", created.Token, allowed); !strings.Contains(w.Body.String(), "heliox_traffic_bytes{") {
+		t.Errorf("/metrics 输出缺少流量指标:\n%s", w.Body.String())
+	}
+
+	// 最近使用时间与 IP
+	tokens, _ := auth.ListTokens(s.db, "admin")
+	if len(tokens) != 1 || tokens[0].LastUsedAt.IsZero() || tokens[0].LastUsedIP != "192.0.2.10" {
+		t.Errorf("最近使用记录 = %+v", tokens)
+	}
+
+	// 只读用户不能授予 write:config
+	auth.CreateUser(s.db, "viewer", "viewer-pass", auth.RoleViewer)
+	if _, _, err := auth.CreateToken(s.db, auth.TokenOptions{Name: "x", Username: "viewer", Scopes: []string{auth.ScopeWriteConfig}}); err == nil {
+		t.Error("只读用户创建了 write:config Token")
+	}
+
+	// 过期
+	expired, _, err := auth.CreateToken(s.db, auth.TokenOptions{Name: "cron", Username: "admin", Scopes: []string{auth.ScopeReadSystem}, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.db.Exec("UPDATE api_tokens SET expires_at = ? WHERE name = 'cron'", time.Now().Add(-time.Minute).Unix())
+	if w := doToken(s, http.MethodGet, "/api/system", expired, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("过期 Token = %d, 期望 401", w.Code)
+	}
+
+	// 吊销
+	if w := do(s, http.MethodDelete, "/api/tokens?id="+created.ID, "", cookie); w.Code != http.StatusOK {
+		t.Fatalf("吊销失败: %d", w.Code)
+	}
+	if w := doToken(s, http.MethodGet, "/api/stats", created.Token, allowed); w.Code != http.StatusUnauthorized {
+		t.Errorf("吊销后 = %d, 期望 401", w.Code)
+	}
+}
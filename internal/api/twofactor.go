@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+const (
+	// mfaChallengeTTL 密码校验通过后输入验证码的时限
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaMaxAttempts 同一次登录允许输错验证码的次数
+	mfaMaxAttempts = 5
+	// totpIssuer 验证器应用中显示的发行方
+	totpIssuer = "Heliox Monitor"
+)
+
+// mfaChallenge 已通过密码校验、等待第二步验证的登录
+type mfaChallenge struct {
+	username string
+	expires  time.Time
+	attempts int
+}
+
+// mfaChallenges 待完成的两步验证登录（只在内存中，重启后需重新输入密码）
+type mfaChallenges struct {
+	mu sync.Mutex
+	m  map[string]*mfaChallenge // key 为 Token 的哈希
+}
+
+func newMFAChallenges() *mfaChallenges {
+	return &mfaChallenges{m: make(map[string]*mfaChallenge)}
+}
+
+// create 创建挑战，返回交给浏览器的一次性 Token
+func (c *mfaChallenges) create(username string, now time.Time) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, ch := range c.m {
+		if now.After(ch.expires) {
+			delete(c.m, k)
+		}
+	}
+	token := auth.RandomToken(24)
+	c.m[auth.HashToken(token)] = &mfaChallenge{username: username, expires: now.Add(mfaChallengeTTL)}
+	return token
+}
+
+// get 查找未过期的挑战
+func (c *mfaChallenges) get(token string, now time.Time) *mfaChallenge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := c.m[auth.HashToken(token)]
+	if ch == nil || now.After(ch.expires) {
+		return nil
+	}
+	return ch
+}
+
+// fail 记录一次输错，超过次数后作废
+func (c *mfaChallenges) fail(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := auth.HashToken(token)
+	if ch := c.m[key]; ch != nil {
+		ch.attempts++
+		if ch.attempts >= mfaMaxAttempts {
+			delete(c.m, key)
+		}
+	}
+}
+
+func (c *mfaChallenges) remove(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, auth.HashToken(token))
+}
+
+// readJSON 解析请求体，失败时返回 400
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// handle2FA 两步验证状态
+func (s *Server) handle2FA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := principalFromContext(r.Context()).username
+	enabled := auth.TOTPEnabled(s.db, username)
+	data := map[string]interface{}{"enabled": enabled}
+	if enabled {
+		data["recovery_codes_left"] = auth.RecoveryCodesLeft(s.db, username)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// handle2FASetup 生成待确认的密钥，返回 otpauth URI（可生成二维码）与手动输入用的密钥
+func (s *Server) handle2FASetup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := principalFromContext(r.Context()).username
+	secret, err := auth.BeginTOTP(s.db, username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"secret": secret,
+		"uri":    auth.TOTPURI(totpIssuer, username+"@"+s.cfg.ServerName, secret),
+	})
+}
+
+// handle2FAEnable 用验证器应用显示的验证码确认开启，返回恢复码（只显示一次）
+func (s *Server) handle2FAEnable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	codes, err := auth.ConfirmTOTP(s.db, principalFromContext(r.Context()).username, req.Code, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.audit(r, auth.AuditEntry{Event: auth.EventTOTPEnabled})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"enabled": true, "recovery_codes": codes})
+}
+
+// handle2FADisable 关闭两步验证，需要密码与验证码（或恢复码）
+// 与登录共用失败计数与锁定，防止用窃取的会话暴力破解密码
+func (s *Server) handle2FADisable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	username := principalFromContext(r.Context()).username
+	if s.loginThrottled(w, r, username) {
+		return
+	}
+	if _, err := auth.Authenticate(s.db, username, req.Password); err != nil {
+		s.loginFailed(r, username, "2fa_disable")
+		http.Error(w, "Invalid credentials", http.StatusForbidden)
+		return
+	}
+	if err := auth.VerifySecondFactor(s.db, username, req.Code, time.Now()); err != nil {
+		s.loginFailed(r, username, "2fa_disable")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	auth.ClearFailures(s.db, s.clientIP(r), username)
+	if _, err := auth.ResetTOTP(s.db, username); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, auth.AuditEntry{Event: auth.EventTOTPDisabled})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false})
+}
+
+// handle2FARecoveryCodes 重新生成恢复码（旧恢复码作废），需要当前验证码（同样计入登录失败）
+func (s *Server) handle2FARecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	username := principalFromContext(r.Context()).username
+	if s.loginThrottled(w, r, username) {
+		return
+	}
+	if err := auth.VerifySecondFactor(s.db, username, req.Code, time.Now()); err != nil {
+		s.loginFailed(r, username, "2fa_recovery_codes")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	auth.ClearFailures(s.db, s.clientIP(r), username)
+	codes, err := auth.NewRecoveryCodes(s.db, username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"recovery_codes": codes})
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// principal 当前请求的认证主体
+type principal struct {
+	username string
+	role     string
+	session  *session    // Basic Auth 与 Token 请求为 nil
+	token    *auth.Token // API Token 请求时非 nil
+}
+
+// principalFromContext 返回当前请求的认证主体（未经 auth 中间件时为 nil）
+func principalFromContext(ctx context.Context) *principal {
+	p, _ := ctx.Value(ctxPrincipal).(*principal)
+	return p
+}
+
+// withPrincipal 将认证主体放入请求上下文
+func withPrincipal(r *http.Request, p *principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), ctxPrincipal, p))
+}
+
+// adminOnWrite 只读请求（GET/HEAD）对所有角色开放，其余方法仅限管理员
+func (s *Server) adminOnWrite(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if p := principalFromContext(r.Context()); p == nil || p.role != auth.RoleAdmin {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// adminOnly 仅限管理员
+func (s *Server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p := principalFromContext(r.Context()); p == nil || p.role != auth.RoleAdmin {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleMe 当前登录用户与角色（前端据此隐藏管理操作）
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	p := principalFromContext(r.Context())
+	if p == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username": p.username,
+		"role":     p.role,
+	})
+}
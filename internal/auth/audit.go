@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// 审计事件
+const (
+	EventLogin          = "login"           // 登录成功，detail 为登录方式
+	EventLoginFailed    = "login_failed"    // 登录或 Basic Auth 失败
+	EventLoginLocked    = "login_locked"    // 连续失败触发临时锁定
+	EventSessionCreated = "session_created" // target 为会话 ID
+	EventSessionRevoked = "session_revoked" // 注销或吊销会话，target 为会话 ID（全部注销时为 all）
+	EventConfigChanged  = "config_changed"  // detail 为修改前后的差异
+	EventAlertAcked     = "alert_acked"     // target 为报警 ID
+	EventUserCreated    = "user_created"
+	EventUserDeleted    = "user_deleted" // 同时删除该用户的会话、Token 与两步验证
+	EventPasswordSet    = "password_changed"
+	EventRoleChanged    = "role_changed"
+	EventUserUnlocked   = "user_unlocked"
+	EventTOTPEnabled    = "2fa_enabled"
+	EventTOTPDisabled   = "2fa_disabled"
+	EventTokenCreated   = "token_created"
+	EventTokenRevoked   = "token_revoked"
+	EventOutboxRetried  = "notification_retried"
+)
+
+// AuditEntry 审计记录
+type AuditEntry struct {
+	Event     string
+	Username  string // 执行操作的用户（命令行为 cli:<系统用户>，机器人为 telegram:<用户>）
+	Target    string // 操作对象
+	IP        string
+	UserAgent string
+	Detail    string
+}
+
+// Audit 写入审计日志（失败只记录日志，不影响请求）
+func Audit(db *storage.DB, e AuditEntry) {
+	if len(e.UserAgent) > 300 {
+		e.UserAgent = e.UserAgent[:300]
+	}
+	_, err := db.Exec(
+		"INSERT INTO audit_log (ts, event, username, target, ip, user_agent, detail) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		time.Now().Unix(), e.Event, e.Username, e.Target, e.IP, e.UserAgent, e.Detail,
+	)
+	if err != nil {
+		log.Printf("写入审计日志失败: %v", err)
+	}
+}
+
+// AuditDiff 比较修改前后的键值，返回变化部分的 JSON：{"键":{"before":..,"after":..}}
+// 没有变化时返回空字符串
+func AuditDiff(before, after map[string]string) string {
+	diff := make(map[string]map[string]string)
+	for k, v := range before {
+		if after[k] != v {
+			diff[k] = map[string]string{"before": v, "after": after[k]}
+		}
+	}
+	for k, v := range after {
+		if _, ok := before[k]; !ok && v != "" {
+			diff[k] = map[string]string{"before": "", "after": v}
+		}
+	}
+	if len(diff) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(diff) // map 的键按字母序输出
+	return string(data)
+}
+
+// configSnapshotKey config 表中保存上次启动配置的键
+const configSnapshotKey = "audit_config_snapshot"
+
+// AuditConfigChanges 与上次启动时的配置比较，有变化时记录差异（首次启动只保存快照）
+func AuditConfigChanges(db *storage.DB, snapshot map[string]string) {
+	var raw string
+	db.QueryRow("SELECT value FROM config WHERE key = ?", configSnapshotKey).Scan(&raw)
+	current, _ := json.Marshal(snapshot)
+	if raw == string(current) {
+		return
+	}
+	if raw != "" {
+		var previous map[string]string
+		json.Unmarshal([]byte(raw), &previous)
+		if diff := AuditDiff(previous, snapshot); diff != "" {
+			Audit(db, AuditEntry{Event: EventConfigChanged, Username: "system", Target: "env", Detail: diff})
+		}
+	}
+	_, err := db.Exec(`
+		INSERT INTO config (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, configSnapshotKey, string(current))
+	if err != nil {
+		log.Printf("保存配置快照失败: %v", err)
+	}
+}
+
+// AuditRecord 查询结果
+type AuditRecord struct {
+	ID        int64
+	Time      time.Time
+	Event     string
+	Username  string
+	Target    string
+	IP        string
+	UserAgent string
+	Detail    string
+}
+
+// AuditQuery 查询条件，零值字段表示不过滤
+type AuditQuery struct {
+	Event    string
+	Username string
+	Target   string
+	IP       string
+	Since    time.Time
+	Until    time.Time
+	BeforeID int64 // 分页游标：只返回 ID 小于该值的记录
+	Limit    int
+}
+
+// where 拼接过滤条件
+func (q AuditQuery) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if q.Event != "" {
+		add("event = ?", q.Event)
+	}
+	if q.Username != "" {
+		add("username = ?", q.Username)
+	}
+	if q.Target != "" {
+		add("target = ?", q.Target)
+	}
+	if q.IP != "" {
+		add("ip = ?", q.IP)
+	}
+	if !q.Since.IsZero() {
+		add("ts >= ?", q.Since.Unix())
+	}
+	if !q.Until.IsZero() {
+		add("ts < ?", q.Until.Unix())
+	}
+	if q.BeforeID > 0 {
+		add("id < ?", q.BeforeID)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+const auditColumns = `id, ts, event, COALESCE(username, ''), COALESCE(target, ''), COALESCE(ip, ''),
+	COALESCE(user_agent, ''), COALESCE(detail, '')`
+
+// scanAudit 逐行读取查询结果
+func scanAudit(db *storage.DB, query string, args []interface{}, fn func(AuditRecord) error) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec AuditRecord
+		var ts int64
+		if err := rows.Scan(&rec.ID, &ts, &rec.Event, &rec.Username, &rec.Target, &rec.IP, &rec.UserAgent, &rec.Detail); err != nil {
+			return err
+		}
+		rec.Time = time.Unix(ts, 0)
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// QueryAudit 按条件查询审计日志，从新到旧
+func QueryAudit(db *storage.DB, q AuditQuery) ([]AuditRecord, error) {
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	where, args := q.where()
+	records := []AuditRecord{}
+	err := scanAudit(db, "SELECT "+auditColumns+" FROM audit_log"+where+" ORDER BY id DESC LIMIT ?", append(args, q.Limit),
+		func(rec AuditRecord) error {
+			records = append(records, rec)
+			return nil
+		})
+	return records, err
+}
+
+// ExportAudit 按条件从旧到新遍历全部审计日志（不分页，忽略 Limit）
+func ExportAudit(db *storage.DB, q AuditQuery, fn func(AuditRecord) error) error {
+	where, args := q.where()
+	return scanAudit(db, "SELECT "+auditColumns+" FROM audit_log"+where+" ORDER BY id", args, fn)
+}
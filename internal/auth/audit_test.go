@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TestAuditLog 测试审计日志只能追加、配置差异与分页查询
+func TestAuditLog(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 5; i++ {
+		Audit(db, AuditEntry{Event: EventLogin, Username: "admin", IP: "203.0.113.5", Detail: "password"})
+	}
+	Audit(db, AuditEntry{Event: EventLoginFailed, Username: "bob"})
+
+	if _, err := db.Exec("UPDATE audit_log SET username = 'x'"); err == nil {
+		t.Error("审计日志不应允许修改")
+	}
+	if _, err := db.Exec("DELETE FROM audit_log"); err == nil {
+		t.Error("审计日志不应允许删除")
+	}
+
+	page, err := QueryAudit(db, AuditQuery{Event: EventLogin, Limit: 3})
+	if err != nil || len(page) != 3 || page[0].ID != 5 {
+		t.Fatalf("第一页 = %+v, %v", page, err)
+	}
+	page, _ = QueryAudit(db, AuditQuery{Event: EventLogin, Limit: 3, BeforeID: page[2].ID})
+	if len(page) != 2 || page[1].ID != 1 {
+		t.Errorf("第二页 = %+v", page)
+	}
+
+	// 首次启动只保存快照，之后记录变化的键
+	AuditConfigChanges(db, map[string]string{"RESET_DAY": "1", "BILLING_MODE": "tx_only"})
+	AuditConfigChanges(db, map[string]string{"RESET_DAY": "1", "BILLING_MODE": "tx_only"})
+	AuditConfigChanges(db, map[string]string{"RESET_DAY": "15", "BILLING_MODE": "tx_only"})
+	changes, _ := QueryAudit(db, AuditQuery{Event: EventConfigChanged})
+	if len(changes) != 1 || changes[0].Detail != `{"RESET_DAY":{"after":"15","before":"1"}}` {
+		t.Errorf("配置变化 = %+v", changes)
+	}
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksMaxAge 公钥缓存时长
+	jwksMaxAge = time.Hour
+	// jwksMinRefresh 两次拉取的最小间隔（含失败），防止伪造的 kid 或不可用的 IdP 放大请求
+	jwksMinRefresh = time.Minute
+	// jwtLeeway 校验 exp/nbf 时允许的时钟偏差
+	jwtLeeway = time.Minute
+)
+
+var ErrJWTInvalid = errors.New("JWT 无效")
+
+// JWKS 远程公钥集（按 kid 缓存）
+type JWKS struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetched   time.Time // 最近一次成功拉取
+	attempted time.Time // 最近一次拉取（成功或失败）
+	fetchErr  error     // 最近一次拉取的错误
+}
+
+// NewJWKS 创建公钥集
+func NewJWKS(url string, client *http.Client) *JWKS {
+	return &JWKS{url: url, client: client}
+}
+
+// key 按 kid 查找公钥，缓存过期或 kid 未知时重新拉取
+func (j *JWKS) key(kid string, now time.Time) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if k, ok := j.keys[kid]; ok && now.Sub(j.fetched) < jwksMaxAge {
+		return k, nil
+	}
+	if j.attempted.IsZero() || now.Sub(j.attempted) >= jwksMinRefresh {
+		j.attempted = now
+		keys, err := fetchJWKS(j.client, j.url)
+		j.fetchErr = err
+		if err != nil {
+			return nil, err
+		}
+		j.keys, j.fetched = keys, now
+	}
+	if k, ok := j.keys[kid]; ok {
+		return k, nil
+	}
+	if j.fetchErr != nil {
+		return nil, fmt.Errorf("JWKS 暂不可用: %w", j.fetchErr)
+	}
+	return nil, fmt.Errorf("%w: 未知的 kid %q", ErrJWTInvalid, kid)
+}
+
+// fetchJWKS 拉取并解析公钥（支持 RSA 与 P-256）
+func fetchJWKS(client *http.Client, url string) (map[string]crypto.PublicKey, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取 JWKS 失败: HTTP %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("解析 JWKS 失败: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	b64 := base64.RawURLEncoding
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, err1 := b64.DecodeString(k.N)
+			e, err2 := b64.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			x, err1 := b64.DecodeString(k.X)
+			y, err2 := b64.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+// Claims JWT 声明
+type Claims map[string]interface{}
+
+// String 读取字符串声明
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// HasAudience aud 可以是字符串或数组
+func (c Claims) HasAudience(aud string) bool {
+	switch v := c["aud"].(type) {
+	case string:
+		return v == aud
+	case []interface{}:
+		for _, a := range v {
+			if a == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// time 读取数值时间声明，不存在时返回零值
+func (c Claims) time(name string) time.Time {
+	if v, ok := c[name].(float64); ok {
+		return time.Unix(int64(v), 0)
+	}
+	return time.Time{}
+}
+
+// Verify 校验签名（RS256 / ES256）与有效期，返回声明；iss/aud 等由调用方检查
+func (j *JWKS) Verify(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTInvalid
+	}
+	b64 := base64.RawURLEncoding
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	raw, err := b64.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(raw, &header) != nil {
+		return nil, ErrJWTInvalid
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTInvalid
+	}
+
+	key, err := j.key(header.Kid, now)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+			return nil, ErrJWTInvalid
+		}
+	case *ecdsa.PublicKey:
+		// JWS 的 ES256 签名为 r||s 各 32 字节
+		if header.Alg != "ES256" || len(sig) != 64 {
+			return nil, ErrJWTInvalid
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return nil, ErrJWTInvalid
+		}
+	default:
+		return nil, ErrJWTInvalid
+	}
+
+	var claims Claims
+	raw, err = b64.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(raw, &claims) != nil {
+		return nil, ErrJWTInvalid
+	}
+	exp := claims.time("exp")
+	if exp.IsZero() || now.After(exp.Add(jwtLeeway)) {
+		return nil, fmt.Errorf("%w: 已过期", ErrJWTInvalid)
+	}
+	if nbf := claims.time("nbf"); !nbf.IsZero() && now.Add(jwtLeeway).Before(nbf) {
+		return nil, fmt.Errorf("%w: 尚未生效", ErrJWTInvalid)
+	}
+	return claims, nil
+}
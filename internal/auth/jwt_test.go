@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestJWKSFetchBackoff 测试 JWKS 拉取失败后同样遵守最小间隔，不会每个请求都访问 IdP
+func TestJWKSFetchBackoff(t *testing.T) {
+	var hits atomic.Int32
+	var up atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !up.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"keys":[]}`))
+	}))
+	defer srv.Close()
+
+	j := NewJWKS(srv.URL, srv.Client())
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := j.key("kid1", now.Add(time.Duration(i)*time.Second)); err == nil {
+			t.Fatal("JWKS 不可用时应返回错误")
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("最小间隔内拉取 %d 次，want 1", n)
+	}
+
+	up.Store(true)
+	_, err := j.key("kid1", now.Add(jwksMinRefresh))
+	if n := hits.Load(); n != 2 || !errors.Is(err, ErrJWTInvalid) {
+		t.Errorf("间隔过后应重新拉取: hits=%d err=%v", n, err)
+	}
+	j.key("forged", now.Add(jwksMinRefresh+time.Second))
+	if n := hits.Load(); n != 2 {
+		t.Errorf("未知 kid 在最小间隔内不应重新拉取，hits=%d", n)
+	}
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// SourceSSO 由外部身份提供方首次登录时自动创建的用户（没有可用的本地密码）
+const SourceSSO = "sso"
+
+// ErrNotSSOUser 同名用户是本地账户（env/local），不允许通过外部身份登录
+var ErrNotSSOUser = errors.New("同名用户是本地账户，不能通过外部身份登录")
+
+// EnsureSSOUser 将外部身份映射到本地用户：只匹配来源为 sso 的用户（自动创建或用 CreateSSOUser 预先添加）；
+// 不存在时按 defaultRole 自动创建，defaultRole 为空表示只允许预先添加的用户。
+// 外部用户名可由 IdP 声明或请求头决定，绝不映射到同名的本地账户（如引导管理员）
+func EnsureSSOUser(db *storage.DB, username, defaultRole string) (*User, error) {
+	if username == "" {
+		return nil, errors.New("身份信息中没有用户名")
+	}
+	u, err := GetUser(db, username)
+	if err == nil {
+		if u.Source != SourceSSO {
+			return nil, ErrNotSSOUser
+		}
+		return u, nil
+	}
+	if err != ErrUserNotFound {
+		return nil, err
+	}
+	if defaultRole == "" {
+		return nil, ErrUserNotFound
+	}
+	if err := CreateSSOUser(db, username, defaultRole); err != nil {
+		return nil, err
+	}
+	return GetUser(db, username)
+}
+
+// CreateSSOUser 预先添加只能通过外部身份登录的用户
+func CreateSSOUser(db *storage.DB, username, role string) error {
+	// 随机密码不会告知任何人，该用户只能通过外部身份登录
+	return createUser(db, username, RandomToken(32), role, SourceSSO)
+}
+
+// CFAccess 校验 Cloudflare Access 签发的 Cf-Access-Jwt-Assertion
+type CFAccess struct {
+	issuer string
+	aud    string
+	jwks   *JWKS
+}
+
+// NewCFAccess teamDomain 为 <team>.cloudflareaccess.com，也可以是完整 URL（测试或自建代理）
+func NewCFAccess(teamDomain, aud string, client *http.Client) *CFAccess {
+	issuer := strings.TrimRight(teamDomain, "/")
+	if !strings.HasPrefix(issuer, "http://") && !strings.HasPrefix(issuer, "https://") {
+		issuer = "https://" + issuer
+	}
+	return &CFAccess{
+		issuer: issuer,
+		aud:    aud,
+		jwks:   NewJWKS(issuer+"/cdn-cgi/access/certs", client),
+	}
+}
+
+// Identity 校验 JWT 并返回邮箱（服务令牌没有邮箱，使用 common_name）
+func (p *CFAccess) Identity(token string, now time.Time) (string, error) {
+	claims, err := p.jwks.Verify(token, now)
+	if err != nil {
+		return "", err
+	}
+	if claims.String("iss") != p.issuer || !claims.HasAudience(p.aud) {
+		return "", fmt.Errorf("%w: iss/aud 不匹配", ErrJWTInvalid)
+	}
+	if email := claims.String("email"); email != "" {
+		return email, nil
+	}
+	return claims.String("common_name"), nil
+}
+
+// OIDCConfig 通用 OIDC 授权码登录配置
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string // 默认 email
+}
+
+// OIDC 授权码模式（带 PKCE 与 nonce）
+type OIDC struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	jwks      *JWKS
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewOIDC 创建 OIDC 客户端（发现文档在首次使用时获取，身份提供方暂时不可用不影响启动）
+func NewOIDC(cfg OIDCConfig, client *http.Client) *OIDC {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "email"
+	}
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+	return &OIDC{cfg: cfg, client: client}
+}
+
+// discover 获取并缓存发现文档
+func (o *OIDC) discover(ctx context.Context) (*oidcDiscovery, *JWKS, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.discovery != nil {
+		return o.discovery, o.jwks, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("获取 OIDC 发现文档失败: HTTP %d", resp.StatusCode)
+	}
+	var d oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, nil, err
+	}
+	if strings.TrimRight(d.Issuer, "/") != o.cfg.Issuer {
+		return nil, nil, fmt.Errorf("OIDC issuer 不匹配: %s", d.Issuer)
+	}
+	o.discovery, o.jwks = &d, NewJWKS(d.JWKSURI, o.client)
+	return o.discovery, o.jwks, nil
+}
+
+// pkceChallenge S256 code_challenge
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthURL 跳转到身份提供方的授权地址
+func (o *OIDC) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, _, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", o.cfg.ClientID)
+	q.Set("redirect_uri", o.cfg.RedirectURL)
+	q.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", pkceChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange 用授权码换取 ID Token，校验后返回用户名声明
+func (o *OIDC) Exchange(ctx context.Context, code, verifier, nonce string) (string, error) {
+	d, jwks, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.cfg.RedirectURL)
+	form.Set("client_id", o.cfg.ClientID)
+	form.Set("client_secret", o.cfg.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("换取 Token 失败: HTTP %d", resp.StatusCode)
+	}
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || tok.IDToken == "" {
+		return "", errors.New("响应中没有 id_token")
+	}
+
+	claims, err := jwks.Verify(tok.IDToken, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimRight(claims.String("iss"), "/") != o.cfg.Issuer || !claims.HasAudience(o.cfg.ClientID) {
+		return "", fmt.Errorf("%w: iss/aud 不匹配", ErrJWTInvalid)
+	}
+	if claims.String("nonce") != nonce {
+		return "", fmt.Errorf("%w: nonce 不匹配", ErrJWTInvalid)
+	}
+	// 以邮箱作为用户名时，要求身份提供方已验证该邮箱
+	if o.cfg.UsernameClaim == "email" {
+		if verified, ok := claims["email_verified"].(bool); ok && !verified {
+			return "", errors.New("邮箱未验证")
+		}
+	}
+	return claims.String(o.cfg.UsernameClaim), nil
+}
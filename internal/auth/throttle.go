@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// maxBackoff 单次退避的上限（未配置锁定时使用）
+const maxBackoff = 15 * time.Minute
+
+// Throttle 登录失败限制：按 IP 与用户名分别计数，超过阈值后指数退避，再超过则临时锁定
+type Throttle struct {
+	BackoffAfter int           // 连续失败多少次后开始退避（0 关闭）
+	LockoutAfter int           // 连续失败多少次后锁定（0 关闭）
+	Lockout      time.Duration // 锁定时长，也是失败计数的过期时间
+}
+
+// Lockout 一次失败触发的新锁定
+type Lockout struct {
+	Key      string // ip:<地址> 或 user:<用户名>
+	Failures int
+	Until    time.Time
+}
+
+// throttleKeys 计数使用的键（用户名为空时只按 IP）
+func throttleKeys(ip, username string) []string {
+	keys := []string{"ip:" + ip}
+	if username != "" {
+		keys = append(keys, "user:"+username)
+	}
+	return keys
+}
+
+func (t Throttle) enabled() bool {
+	return t.BackoffAfter > 0 || t.LockoutAfter > 0
+}
+
+// window 失败计数的有效期
+func (t Throttle) window() time.Duration {
+	if t.Lockout > 0 {
+		return t.Lockout
+	}
+	return maxBackoff
+}
+
+// delay 第 n 次连续失败后需要等待的时间
+func (t Throttle) delay(n int) time.Duration {
+	if t.LockoutAfter > 0 && n >= t.LockoutAfter {
+		return t.window()
+	}
+	if t.BackoffAfter <= 0 || n <= t.BackoffAfter {
+		return 0
+	}
+	// 超过阈值后 1s、2s、4s……
+	d := time.Second << min(n-t.BackoffAfter-1, 20)
+	return min(d, t.window())
+}
+
+// Blocked 返回 IP 或用户名仍需等待的时间，0 表示允许尝试
+func (t Throttle) Blocked(db *storage.DB, ip, username string, now time.Time) time.Duration {
+	if !t.enabled() {
+		return 0
+	}
+	var wait time.Duration
+	for _, key := range throttleKeys(ip, username) {
+		var until int64
+		err := db.QueryRow("SELECT blocked_until FROM login_failures WHERE key = ?", key).Scan(&until)
+		if err != nil {
+			continue
+		}
+		if d := time.Unix(until, 0).Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// RecordFailure 记录一次失败，返回本次新触发的锁定
+func (t Throttle) RecordFailure(db *storage.DB, ip, username string, now time.Time) []Lockout {
+	if !t.enabled() {
+		return nil
+	}
+	// 顺带清理过期计数（随机用户名的尝试会留下大量记录）
+	db.Exec("DELETE FROM login_failures WHERE last_failure < ? AND blocked_until < ?",
+		now.Add(-t.window()).Unix(), now.Unix())
+
+	var lockouts []Lockout
+	for _, key := range throttleKeys(ip, username) {
+		var failures int
+		var last int64
+		err := db.QueryRow("SELECT failures, last_failure FROM login_failures WHERE key = ?", key).Scan(&failures, &last)
+		if err == sql.ErrNoRows || now.Sub(time.Unix(last, 0)) > t.window() {
+			failures = 0
+		}
+		failures++
+
+		var until time.Time
+		if d := t.delay(failures); d > 0 {
+			until = now.Add(d)
+		}
+		db.Exec(`
+			INSERT INTO login_failures (key, failures, last_failure, blocked_until) VALUES (?, ?, ?, ?)
+			ON CONFLICT(key) DO UPDATE SET failures = excluded.failures,
+			    last_failure = excluded.last_failure, blocked_until = excluded.blocked_until
+		`, key, failures, now.Unix(), until.Unix())
+
+		if t.LockoutAfter > 0 && failures == t.LockoutAfter {
+			lockouts = append(lockouts, Lockout{Key: key, Failures: failures, Until: until})
+		}
+	}
+	return lockouts
+}
+
+// ClearFailures 登录成功后清除 IP 与用户名的失败计数
+func ClearFailures(db *storage.DB, ip, username string) {
+	for _, key := range throttleKeys(ip, username) {
+		db.Exec("DELETE FROM login_failures WHERE key = ?", key)
+	}
+}
+
+// Unlock 手动解除用户名或 IP 的锁定（key 为 ip:<地址> 或 user:<用户名>）
+func Unlock(db *storage.DB, key string) (bool, error) {
+	res, err := db.Exec("DELETE FROM login_failures WHERE key = ?", key)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
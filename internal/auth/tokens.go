@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TokenPrefix API Token 前缀，便于在日志和密钥扫描中识别
+const TokenPrefix = "hxm_"
+
+// tokenTouchInterval last_used 的最小更新间隔
+const tokenTouchInterval = time.Minute
+
+// API Token 权限范围
+const (
+	ScopeReadTraffic = "read:traffic" // 流量、端口、计费周期、异常
+	ScopeReadSystem  = "read:system"  // 系统资源、服务、进程、容器、网络质量、通知状态
+	ScopeReadLatency = "read:latency" // 延迟
+	ScopeWriteConfig = "write:config" // 修改配置（仅管理员可授予）
+	ScopeMetrics     = "metrics"      // Prometheus /metrics
+)
+
+// Scopes 全部可用的权限范围
+var Scopes = []string{ScopeReadTraffic, ScopeReadSystem, ScopeReadLatency, ScopeWriteConfig, ScopeMetrics}
+
+var (
+	ErrTokenNotFound = errors.New("Token 不存在")
+	ErrTokenInvalid  = errors.New("Token 无效或已过期")
+	ErrTokenIP       = errors.New("来源 IP 不在 Token 白名单内")
+)
+
+// randomBytes 生成 n 字节随机数
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand 不可用时无法安全运行
+	}
+	return b
+}
+
+// RandomToken 生成 n 字节随机数的 base64url 编码
+func RandomToken(n int) string {
+	return base64.RawURLEncoding.EncodeToString(randomBytes(n))
+}
+
+// HashToken 数据库中只保存随机凭据的 SHA-256，泄露数据库不会泄露可用的凭据
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Token API Token（不含明文）
+type Token struct {
+	ID         string
+	Name       string
+	Username   string // 所属用户，Token 权限不超过该用户角色
+	Scopes     []string
+	AllowIPs   []string // IP 或 CIDR，为空表示不限制
+	CreatedAt  time.Time
+	ExpiresAt  time.Time // 零值表示永不过期
+	LastUsedAt time.Time // 零值表示从未使用
+	LastUsedIP string
+}
+
+// HasScope Token 是否包含指定权限
+func (t *Token) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsIP 来源 IP 是否在白名单内
+func (t *Token) allowsIP(ip string) bool {
+	if len(t.AllowIPs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range t.AllowIPs {
+		if _, ipnet, err := parseIPOrCIDR(entry); err == nil && ipnet.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIPOrCIDR 单个 IP 视为 /32 或 /128
+func parseIPOrCIDR(s string) (net.IP, *net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		return net.ParseCIDR(s)
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, nil, fmt.Errorf("无效的 IP: %s", s)
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return ip, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// ParseNetworks 解析 IP / CIDR 列表
+func ParseNetworks(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		_, ipnet, err := parseIPOrCIDR(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, fmt.Errorf("无效的地址 %q", entry)
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
+
+// ContainsIP IP 是否属于任一网段
+func ContainsIP(nets []*net.IPNet, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenOptions 创建 Token 的参数
+type TokenOptions struct {
+	Name     string
+	Username string
+	Scopes   []string
+	TTL      time.Duration // 0 表示永不过期
+	AllowIPs []string
+}
+
+// CreateToken 创建 Token，返回只展示一次的明文
+func CreateToken(db *storage.DB, opts TokenOptions) (string, *Token, error) {
+	if opts.Name == "" {
+		return "", nil, errors.New("Token 名称不能为空")
+	}
+	user, err := GetUser(db, opts.Username)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(opts.Scopes) == 0 {
+		return "", nil, errors.New("至少需要一个权限范围")
+	}
+	for _, scope := range opts.Scopes {
+		valid := false
+		for _, s := range Scopes {
+			valid = valid || s == scope
+		}
+		if !valid {
+			return "", nil, fmt.Errorf("未知的权限范围: %s（可选 %s）", scope, strings.Join(Scopes, ", "))
+		}
+		if scope == ScopeWriteConfig && user.Role != RoleAdmin {
+			return "", nil, errors.New("只有管理员可以授予 write:config")
+		}
+	}
+	for _, entry := range opts.AllowIPs {
+		if _, _, err := parseIPOrCIDR(entry); err != nil {
+			return "", nil, fmt.Errorf("无效的 IP 白名单 %q", entry)
+		}
+	}
+
+	now := time.Now()
+	t := &Token{
+		ID:        RandomToken(9),
+		Name:      opts.Name,
+		Username:  opts.Username,
+		Scopes:    opts.Scopes,
+		AllowIPs:  opts.AllowIPs,
+		CreatedAt: now,
+	}
+	var expiresAt int64
+	if opts.TTL > 0 {
+		t.ExpiresAt = now.Add(opts.TTL)
+		expiresAt = t.ExpiresAt.Unix()
+	}
+	secret := TokenPrefix + RandomToken(32)
+
+	_, err = db.Exec(`
+		INSERT INTO api_tokens (id, name, token_hash, username, scopes, allow_ips, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, t.Name, HashToken(secret), t.Username, strings.Join(t.Scopes, ","), strings.Join(t.AllowIPs, ","),
+		now.Unix(), expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return secret, t, nil
+}
+
+const tokenColumns = `id, name, username, scopes, COALESCE(allow_ips, ''), created_at, expires_at,
+	COALESCE(last_used_at, 0), COALESCE(last_used_ip, '')`
+
+func scanToken(row interface{ Scan(...interface{}) error }) (*Token, error) {
+	var t Token
+	var scopes, allowIPs string
+	var createdAt, expiresAt, lastUsedAt int64
+	if err := row.Scan(&t.ID, &t.Name, &t.Username, &scopes, &allowIPs, &createdAt, &expiresAt,
+		&lastUsedAt, &t.LastUsedIP); err != nil {
+		return nil, err
+	}
+	t.Scopes = splitList(scopes)
+	t.AllowIPs = splitList(allowIPs)
+	t.CreatedAt = time.Unix(createdAt, 0)
+	if expiresAt > 0 {
+		t.ExpiresAt = time.Unix(expiresAt, 0)
+	}
+	if lastUsedAt > 0 {
+		t.LastUsedAt = time.Unix(lastUsedAt, 0)
+	}
+	return &t, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// ListTokens 列出 Token（username 为空时列出全部）
+func ListTokens(db *storage.DB, username string) ([]Token, error) {
+	query := "SELECT " + tokenColumns + " FROM api_tokens"
+	var args []interface{}
+	if username != "" {
+		query += " WHERE username = ?"
+		args = append(args, username)
+	}
+	rows, err := db.Query(query+" ORDER BY created_at", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []Token
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken 吊销 Token（username 非空时只能吊销该用户自己的 Token）
+func RevokeToken(db *storage.DB, id, username string) error {
+	query, args := "DELETE FROM api_tokens WHERE id = ?", []interface{}{id}
+	if username != "" {
+		query += " AND username = ?"
+		args = append(args, username)
+	}
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// LookupToken 校验明文 Token（过期、IP 白名单、所属用户仍存在），返回 Token 与所属用户的角色
+// 校验通过时记录最近使用时间与来源 IP
+func LookupToken(db *storage.DB, secret, ip string) (*Token, string, error) {
+	if !strings.HasPrefix(secret, TokenPrefix) {
+		return nil, "", ErrTokenInvalid
+	}
+	row := db.QueryRow(`
+		SELECT t.id, t.name, t.username, t.scopes, COALESCE(t.allow_ips, ''), t.created_at, t.expires_at,
+		       COALESCE(t.last_used_at, 0), COALESCE(t.last_used_ip, ''), u.role
+		FROM api_tokens t JOIN users u ON u.username = t.username
+		WHERE t.token_hash = ?
+	`, HashToken(secret))
+
+	var role string
+	t, err := scanToken(scanFunc(func(dest ...interface{}) error {
+		return row.Scan(append(dest, &role)...)
+	}))
+	if err == sql.ErrNoRows {
+		return nil, "", ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if !t.ExpiresAt.IsZero() && now.After(t.ExpiresAt) {
+		return nil, "", ErrTokenInvalid
+	}
+	if !t.allowsIP(ip) {
+		return nil, "", ErrTokenIP
+	}
+	if now.Sub(t.LastUsedAt) >= tokenTouchInterval || t.LastUsedIP != ip {
+		db.Exec("UPDATE api_tokens SET last_used_at = ?, last_used_ip = ? WHERE id = ?", now.Unix(), ip, t.ID)
+		t.LastUsedAt, t.LastUsedIP = now, ip
+	}
+	return t, role, nil
+}
+
+// scanFunc 适配 scanToken 的 Scan 接口，便于在查询中附加额外列
+type scanFunc func(dest ...interface{}) error
+
+func (f scanFunc) Scan(dest ...interface{}) error { return f(dest...) }
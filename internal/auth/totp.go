@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TOTP 参数（RFC 6238 默认值，兼容主流验证器应用）
+const (
+	totpPeriod  = 30 // 秒
+	totpDigits  = 6
+	totpSkew    = 1  // 允许前后各 1 个时间窗口的时钟偏差
+	secretBytes = 20 // 160 位，RFC 4226 推荐长度
+
+	// RecoveryCodeCount 每次生成的恢复码数量
+	RecoveryCodeCount = 10
+)
+
+var (
+	ErrTOTPNotEnrolled = errors.New("未开启两步验证")
+	ErrTOTPInvalid     = errors.New("验证码错误")
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpCode 计算指定时间窗口的验证码（RFC 4226 动态截断）
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%1000000)
+}
+
+// matchTOTP 在允许的偏差内查找匹配的时间窗口，要求大于 lastStep 以防重放
+func matchTOTP(secret []byte, code string, now time.Time, lastStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	cur := now.Unix() / totpPeriod
+	for step := cur - totpSkew; step <= cur+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI 生成验证器应用使用的 otpauth URI（可转成二维码或手动输入密钥）
+func TOTPURI(issuer, username, secret string) string {
+	label := url.PathEscape(issuer + ":" + username)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// TOTPEnabled 用户是否已开启两步验证
+func TOTPEnabled(db *storage.DB, username string) bool {
+	var enabled bool
+	db.QueryRow("SELECT enabled FROM user_totp WHERE username = ?", username).Scan(&enabled)
+	return enabled
+}
+
+// BeginTOTP 生成新的待确认密钥（覆盖之前未确认的密钥；已开启时需先关闭）
+func BeginTOTP(db *storage.DB, username string) (string, error) {
+	if _, err := GetUser(db, username); err != nil {
+		return "", err
+	}
+	if TOTPEnabled(db, username) {
+		return "", errors.New("已开启两步验证，请先关闭")
+	}
+	secret := b32.EncodeToString(randomBytes(secretBytes))
+	_, err := db.Exec(`
+		INSERT INTO user_totp (username, secret, enabled, created_at, last_step) VALUES (?, ?, 0, ?, 0)
+		ON CONFLICT(username) DO UPDATE SET secret = excluded.secret, enabled = 0,
+		    created_at = excluded.created_at, last_step = 0
+	`, username, secret, time.Now().Unix())
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ConfirmTOTP 用验证码确认待确认的密钥并开启两步验证，返回只展示一次的恢复码
+func ConfirmTOTP(db *storage.DB, username, code string, now time.Time) ([]string, error) {
+	var secret string
+	var enabled bool
+	err := db.QueryRow("SELECT secret, enabled FROM user_totp WHERE username = ?", username).Scan(&secret, &enabled)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("请先生成密钥")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errors.New("已开启两步验证")
+	}
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(key, code, now, 0)
+	if !ok {
+		return nil, ErrTOTPInvalid
+	}
+	if _, err := db.Exec("UPDATE user_totp SET enabled = 1, last_step = ? WHERE username = ?", step, username); err != nil {
+		return nil, err
+	}
+	return NewRecoveryCodes(db, username)
+}
+
+// VerifySecondFactor 校验验证码或恢复码（恢复码一次性），已开启两步验证时使用
+func VerifySecondFactor(db *storage.DB, username, code string, now time.Time) error {
+	var secret string
+	var lastStep int64
+	err := db.QueryRow("SELECT secret, last_step FROM user_totp WHERE username = ? AND enabled = 1", username).
+		Scan(&secret, &lastStep)
+	if err == sql.ErrNoRows {
+		return ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+
+	// 恢复码含连字符，长度与验证码不同
+	if strings.Contains(code, "-") {
+		res, err := db.Exec(
+			"UPDATE totp_recovery_codes SET used_at = ? WHERE username = ? AND code_hash = ? AND used_at IS NULL",
+			now.Unix(), username, HashToken(normalizeRecoveryCode(code)),
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrTOTPInvalid
+		}
+		return nil
+	}
+
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		return err
+	}
+	step, ok := matchTOTP(key, code, now, lastStep)
+	if !ok {
+		return ErrTOTPInvalid
+	}
+	_, err = db.Exec("UPDATE user_totp SET last_step = ? WHERE username = ?", step, username)
+	return err
+}
+
+// NewRecoveryCodes 重新生成恢复码（旧恢复码全部作废）
+func NewRecoveryCodes(db *storage.DB, username string) ([]string, error) {
+	if _, err := db.Exec("DELETE FROM totp_recovery_codes WHERE username = ?", username); err != nil {
+		return nil, err
+	}
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		s := strings.ToLower(b32.EncodeToString(randomBytes(7)))[:10]
+		codes[i] = s[:5] + "-" + s[5:]
+		_, err := db.Exec("INSERT INTO totp_recovery_codes (username, code_hash) VALUES (?, ?)",
+			username, HashToken(codes[i]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// RecoveryCodesLeft 未使用的恢复码数量
+func RecoveryCodesLeft(db *storage.DB, username string) int {
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM totp_recovery_codes WHERE username = ? AND used_at IS NULL", username).Scan(&n)
+	return n
+}
+
+// ResetTOTP 关闭两步验证并删除恢复码（用户自助关闭或管理员重置）
+func ResetTOTP(db *storage.DB, username string) (bool, error) {
+	res, err := db.Exec("DELETE FROM user_totp WHERE username = ?", username)
+	if err != nil {
+		return false, err
+	}
+	if _, err := db.Exec("DELETE FROM totp_recovery_codes WHERE username = ?", username); err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TestTOTPCode RFC 6238 附录 B 的 SHA1 测试向量（取后 6 位）
+func TestTOTPCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		if got := totpCode(secret, c.unix/totpPeriod); got != c.want {
+			t.Errorf("T=%d: %s, 期望 %s", c.unix, got, c.want)
+		}
+	}
+}
+
+// TestTOTPEnrollment 测试绑定、重放保护与一次性恢复码
+func TestTOTPEnrollment(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := CreateUser(db, "alice", "alice-password", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := BeginTOTP(db, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := b32.DecodeString(secret)
+	now := time.Unix(1_700_000_000, 0)
+	code := func(at time.Time) string { return totpCode(key, at.Unix()/totpPeriod) }
+
+	if code(now) != "000000" {
+		if _, err := ConfirmTOTP(db, "alice", "000000", now); err != ErrTOTPInvalid {
+			t.Errorf("错误验证码确认: %v", err)
+		}
+	}
+	codes, err := ConfirmTOTP(db, "alice", code(now), now)
+	if err != nil || len(codes) != RecoveryCodeCount {
+		t.Fatalf("确认失败: %v %v", codes, err)
+	}
+
+	// 同一时间窗口的验证码不能重复使用，下一个窗口可以
+	if err := VerifySecondFactor(db, "alice", code(now), now); err != ErrTOTPInvalid {
+		t.Errorf("重放验证码: %v", err)
+	}
+	next := now.Add(totpPeriod * time.Second)
+	if err := VerifySecondFactor(db, "alice", code(next), next); err != nil {
+		t.Errorf("下一窗口验证码: %v", err)
+	}
+
+	// 恢复码只能用一次
+	if err := VerifySecondFactor(db, "alice", " "+codes[0]+" ", now); err != nil {
+		t.Errorf("恢复码: %v", err)
+	}
+	if err := VerifySecondFactor(db, "alice", codes[0], now); err != ErrTOTPInvalid {
+		t.Errorf("重复使用恢复码: %v", err)
+	}
+	if n := RecoveryCodesLeft(db, "alice"); n != RecoveryCodeCount-1 {
+		t.Errorf("剩余恢复码 %d", n)
+	}
+
+	if ok, _ := ResetTOTP(db, "alice"); !ok || TOTPEnabled(db, "alice") {
+		t.Error("重置后仍开启两步验证")
+	}
+}
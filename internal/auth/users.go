@@ -0,0 +1,241 @@
+// Package auth 用户、密码哈希与角色
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// 角色
+const (
+	RoleAdmin  = "admin"  // 可修改配置、处理通知
+	RoleViewer = "viewer" // 只读
+)
+
+// 用户来源
+const (
+	SourceEnv   = "env"   // 由 HELIOX_MON_USER/HELIOX_MON_PASS 引导创建，密码随环境变量同步
+	SourceLocal = "local" // 通过命令行创建
+)
+
+// MinPasswordLength 密码最小长度
+const MinPasswordLength = 8
+
+var (
+	ErrUserNotFound     = errors.New("用户不存在")
+	ErrUserExists       = errors.New("用户已存在")
+	ErrInvalidRole      = errors.New("角色无效（admin 或 viewer）")
+	ErrPasswordTooShort = fmt.Errorf("密码至少 %d 个字符", MinPasswordLength)
+	ErrInvalidPassword  = errors.New("用户名或密码错误")
+)
+
+// dummyHash 用户不存在时也做一次 bcrypt 比较，避免通过响应时间枚举用户名
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("heliox-mon-dummy"), bcrypt.DefaultCost)
+
+// User 用户
+type User struct {
+	Username  string
+	Role      string
+	Source    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ValidRole 角色是否有效
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleViewer
+}
+
+// HashPassword 生成 bcrypt 哈希（超过 72 字节时返回错误而不是静默截断）
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CreateUser 创建用户
+func CreateUser(db *storage.DB, username, password, role string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	return createUser(db, username, password, role, SourceLocal)
+}
+
+// createUser 写入用户（引导管理员沿用旧密码，不检查长度）
+func createUser(db *storage.DB, username, password, role, source string) error {
+	if username == "" {
+		return errors.New("用户名不能为空")
+	}
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	if _, err := GetUser(db, username); err == nil {
+		return ErrUserExists
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	_, err = db.Exec(`
+		INSERT INTO users (username, password_hash, role, source, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, username, hash, role, source, now, now)
+	return err
+}
+
+// GetUser 查询用户
+func GetUser(db *storage.DB, username string) (*User, error) {
+	var u User
+	var createdAt, updatedAt int64
+	err := db.QueryRow(
+		"SELECT username, role, source, created_at, updated_at FROM users WHERE username = ?", username,
+	).Scan(&u.Username, &u.Role, &u.Source, &createdAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.CreatedAt, u.UpdatedAt = time.Unix(createdAt, 0), time.Unix(updatedAt, 0)
+	return &u, nil
+}
+
+// ListUsers 列出全部用户
+func ListUsers(db *storage.DB) ([]User, error) {
+	rows, err := db.Query("SELECT username, role, source, created_at, updated_at FROM users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&u.Username, &u.Role, &u.Source, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		u.CreatedAt, u.UpdatedAt = time.Unix(createdAt, 0), time.Unix(updatedAt, 0)
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// CountUsers 用户数量
+func CountUsers(db *storage.DB) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n)
+	return n, err
+}
+
+// DeleteUser 删除用户及其全部会话、API Token 与两步验证
+func DeleteUser(db *storage.DB, username string) error {
+	res, err := db.Exec("DELETE FROM users WHERE username = ?", username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	if _, err := db.Exec("DELETE FROM sessions WHERE username = ?", username); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM api_tokens WHERE username = ?", username); err != nil {
+		return err
+	}
+	_, err = ResetTOTP(db, username)
+	return err
+}
+
+// SetPassword 重置密码并注销该用户的全部会话
+func SetPassword(db *storage.DB, username, password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(
+		"UPDATE users SET password_hash = ?, source = ?, updated_at = ? WHERE username = ?",
+		hash, SourceLocal, time.Now().Unix(), username,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	_, err = db.Exec("DELETE FROM sessions WHERE username = ?", username)
+	return err
+}
+
+// SetRole 修改角色
+func SetRole(db *storage.DB, username, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	res, err := db.Exec("UPDATE users SET role = ?, updated_at = ? WHERE username = ?", role, time.Now().Unix(), username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验用户名和密码
+func Authenticate(db *storage.DB, username, password string) (*User, error) {
+	var hash string
+	err := db.QueryRow("SELECT password_hash FROM users WHERE username = ?", username).Scan(&hash)
+	if err == sql.ErrNoRows {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return nil, ErrInvalidPassword
+	}
+	return GetUser(db, username)
+}
+
+// EnsureBootstrapAdmin 兼容旧版单用户配置：HELIOX_MON_PASS 非空时确保存在对应的管理员
+// 用户不存在则创建；仍由环境变量管理（source=env）时同步密码。
+// 通过命令行重置过密码的用户（source=local）不再受环境变量影响。
+func EnsureBootstrapAdmin(db *storage.DB, username, password string) error {
+	if password == "" {
+		return nil
+	}
+	var hash, source string
+	err := db.QueryRow("SELECT password_hash, source FROM users WHERE username = ?", username).Scan(&hash, &source)
+	if err == sql.ErrNoRows {
+		return createUser(db, username, password, RoleAdmin, SourceEnv)
+	}
+	if err != nil || source != SourceEnv {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+		return nil
+	}
+	newHash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(
+		"UPDATE users SET password_hash = ?, role = ?, updated_at = ? WHERE username = ?",
+		newHash, RoleAdmin, time.Now().Unix(), username,
+	)
+	return err
+}
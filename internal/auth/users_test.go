@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TestEnsureBootstrapAdmin 测试引导管理员的创建与密码同步
+func TestEnsureBootstrapAdmin(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 旧版短密码也要能继续使用
+	if err := EnsureBootstrapAdmin(db, "admin", "old"); err != nil {
+		t.Fatal(err)
+	}
+	u, err := Authenticate(db, "admin", "old")
+	if err != nil || u.Role != RoleAdmin || u.Source != SourceEnv {
+		t.Fatalf("引导管理员 = %+v, %v", u, err)
+	}
+
+	// 环境变量改密码后同步
+	if err := EnsureBootstrapAdmin(db, "admin", "new-password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Authenticate(db, "admin", "old"); err != ErrInvalidPassword {
+		t.Errorf("旧密码仍可登录: %v", err)
+	}
+
+	// 命令行重置后不再被环境变量覆盖
+	if err := SetPassword(db, "admin", "cli-password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureBootstrapAdmin(db, "admin", "new-password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Authenticate(db, "admin", "cli-password"); err != nil {
+		t.Errorf("命令行设置的密码失效: %v", err)
+	}
+
+	if err := CreateUser(db, "bob", "short", RoleViewer); err != ErrPasswordTooShort {
+		t.Errorf("短密码: %v", err)
+	}
+	if err := CreateUser(db, "bob", "bob-password", "root"); err != ErrInvalidRole {
+		t.Errorf("无效角色: %v", err)
+	}
+	if err := CreateUser(db, "bob", "bob-password", RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Authenticate(db, "nobody", "bob-password"); err != ErrInvalidPassword {
+		t.Errorf("不存在的用户: %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package collector
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/notifier"
+)
+
+// ifaceCounters 网卡累计计数器（/proc/net/dev 一行）
+type ifaceCounters struct {
+	tx uint64
+	rx uint64
+
+	rxPackets, txPackets uint64
+	rxErrs, txErrs       uint64
+	rxDrop, txDrop       uint64
+	rxFifo, txFifo       uint64
+}
+
+// 异常类型
+const (
+	anomalySpike = "spike" // 突增（凭据泄露、DDoS 反射）
+	anomalyDrop  = "drop"  // 骤降（代理进程挂掉）
+)
+
+const (
+	// anomalyAlpha EWMA 平滑系数（每个周内小时每周约 60 个样本）
+	anomalyAlpha = 0.05
+	// anomalyRelearnAfter 持续异常超过该时长后恢复基线学习，适应新的流量水平
+	anomalyRelearnAfter = time.Hour
+)
+
+// baseline 某个周内小时的 EWMA 基线
+type baseline struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+// update 用新样本更新基线
+func (b *baseline) update(x float64) {
+	if b.samples == 0 {
+		b.mean, b.variance = x, 0
+	} else {
+		diff := x - b.mean
+		incr := anomalyAlpha * diff
+		b.mean += incr
+		b.variance = (1 - anomalyAlpha) * (b.variance + diff*incr)
+	}
+	b.samples++
+}
+
+// stddev 标准差，设置下限避免基线平稳时 z-score 失真
+func (b baseline) stddev() float64 {
+	return math.Max(math.Sqrt(b.variance), math.Max(b.mean*0.05, 1))
+}
+
+// anomalyParams 判定参数
+type anomalyParams struct {
+	zScore     float64
+	minRate    float64 // bytes/s
+	dropRatio  float64
+	minSamples int
+}
+
+// classify 判断样本是否异常，返回异常类型（空表示正常）和 z-score
+func (p anomalyParams) classify(b baseline, x float64) (string, float64) {
+	if b.samples < p.minSamples {
+		return "", 0
+	}
+	z := (x - b.mean) / b.stddev()
+	if z >= p.zScore && x-b.mean >= p.minRate {
+		return anomalySpike, z
+	}
+	if b.mean >= p.minRate && x <= b.mean*p.dropRatio {
+		return anomalyDrop, z
+	}
+	return "", z
+}
+
+// anomalyTrack 进行中的异常
+type anomalyTrack struct {
+	id    int64
+	kind  string
+	since time.Time
+}
+
+// rateSample 某个范围的当前速率
+type rateSample struct {
+	scope string
+	tx    float64
+	rx    float64
+}
+
+// runAnomalyDetection 流量异常检测（每 1 分钟）
+func (c *Collector) runAnomalyDetection() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.detectAnomalies(time.Now().In(c.cfg.Timezone))
+		}
+	}
+}
+
+// restoreAnomalies 恢复上次运行时未结束的异常：继续检测时载入内存，等待恢复后正常结束并通知；
+// 关闭检测时直接标记结束，避免接口一直显示为进行中
+func (c *Collector) restoreAnomalies(now time.Time) {
+	if !c.cfg.AnomalyDetection {
+		if res, err := c.db.Exec("UPDATE traffic_anomalies SET ended_at = ? WHERE ended_at IS NULL", now.Unix()); err == nil {
+			if n, _ := res.RowsAffected(); n > 0 {
+				log.Printf("异常检测已关闭，结束 %d 条未结束的流量异常", n)
+			}
+		}
+		return
+	}
+
+	rows, err := c.db.Query("SELECT id, ts, scope, direction, kind FROM traffic_anomalies WHERE ended_at IS NULL ORDER BY id")
+	if err != nil {
+		log.Printf("读取未结束的流量异常失败: %v", err)
+		return
+	}
+	var stale []int64
+	for rows.Next() {
+		var id, ts int64
+		var scope, direction, kind string
+		if err := rows.Scan(&id, &ts, &scope, &direction, &kind); err != nil {
+			continue
+		}
+		key := scope + "/" + direction
+		// 同一范围只保留最新一条
+		if prev := c.anomalies[key]; prev != nil {
+			stale = append(stale, prev.id)
+		}
+		c.anomalies[key] = &anomalyTrack{id: id, kind: kind, since: time.Unix(ts, 0)}
+	}
+	rows.Close()
+	for _, id := range stale {
+		_, _ = c.db.Exec("UPDATE traffic_anomalies SET ended_at = ? WHERE id = ?", now.Unix(), id)
+	}
+}
+
+func (c *Collector) anomalyParams() anomalyParams {
+	return anomalyParams{
+		zScore:     c.cfg.AnomalyZScore,
+		minRate:    float64(c.cfg.AnomalyMinRateKBs) * 1024,
+		dropRatio:  c.cfg.AnomalyDropRatio,
+		minSamples: c.cfg.AnomalyMinSamples,
+	}
+}
+
+// detectAnomalies 对各范围的速率做异常判定并更新基线
+func (c *Collector) detectAnomalies(now time.Time) {
+	how := int(now.Weekday())*24 + now.Hour()
+	params := c.anomalyParams()
+
+	for _, s := range c.collectRateSamples(now) {
+		for _, d := range []struct {
+			direction string
+			rate      float64
+		}{{"tx", s.tx}, {"rx", s.rx}} {
+			key := s.scope + "/" + d.direction
+			b := c.loadBaseline(s.scope, d.direction, how)
+			kind, z := params.classify(b, d.rate)
+			track := c.anomalies[key]
+
+			switch {
+			case kind != "" && (track == nil || track.kind != kind):
+				if track != nil {
+					c.endAnomaly(key, track, s.scope, d.direction, d.rate, b, now)
+				}
+				c.startAnomaly(key, s.scope, d.direction, kind, d.rate, b, z, now)
+			case kind == "" && track != nil:
+				c.endAnomaly(key, track, s.scope, d.direction, d.rate, b, now)
+			}
+
+			// 异常期间暂停学习，避免基线被污染；持续过久则视为新常态
+			if track = c.anomalies[key]; track == nil || now.Sub(track.since) >= anomalyRelearnAfter {
+				b.update(d.rate)
+				c.saveBaseline(s.scope, d.direction, how, b)
+			}
+		}
+	}
+}
+
+// collectRateSamples 计算最近 1 分钟各范围（整体 / 端口 / 网卡）的平均速率
+func (c *Collector) collectRateSamples(now time.Time) []rateSample {
+	var samples []rateSample
+	since := now.Unix() - 60
+
+	if s, ok := c.snapshotRate("total", `
+		SELECT MIN(ts), MAX(ts), MIN(tx_bytes), MAX(tx_bytes), MIN(rx_bytes), MAX(rx_bytes)
+		FROM traffic_snapshots WHERE iface = 'total' AND ts >= ?`, since); ok {
+		samples = append(samples, s)
+	}
+
+	for _, port := range []int{c.cfg.SnellPort, c.cfg.VlessPort} {
+		if port == 0 {
+			continue
+		}
+		if s, ok := c.snapshotRate(fmt.Sprintf("port:%d", port), `
+			SELECT MIN(ts), MAX(ts), MIN(tx_bytes), MAX(tx_bytes), MIN(rx_bytes), MAX(rx_bytes)
+			FROM port_traffic_snapshots WHERE port = ? AND ts >= ?`, port, since); ok {
+			samples = append(samples, s)
+		}
+	}
+
+	// 多网卡时单独检测每块网卡（单网卡与整体重复，跳过）
+	ifaces := c.readIfaceCounters()
+	if len(ifaces) > 1 && !c.lastIfaceTime.IsZero() {
+		dt := now.Sub(c.lastIfaceTime).Seconds()
+		for name, cur := range ifaces {
+			prev, ok := c.lastIfaces[name]
+			if !ok || dt <= 0 || cur.tx < prev.tx || cur.rx < prev.rx {
+				continue
+			}
+			samples = append(samples, rateSample{
+				scope: "iface:" + name,
+				tx:    float64(cur.tx-prev.tx) / dt,
+				rx:    float64(cur.rx-prev.rx) / dt,
+			})
+		}
+	}
+	c.lastIfaces, c.lastIfaceTime = ifaces, now
+
+	return samples
+}
+
+// snapshotRate 由快照表中的累计计数计算平均速率
+func (c *Collector) snapshotRate(scope, query string, args ...interface{}) (rateSample, bool) {
+	var minTs, maxTs, minTx, maxTx, minRx, maxRx sql.NullInt64
+	if err := c.db.QueryRow(query, args...).Scan(&minTs, &maxTs, &minTx, &maxTx, &minRx, &maxRx); err != nil || !minTs.Valid {
+		return rateSample{}, false
+	}
+	dt := float64(maxTs.Int64 - minTs.Int64)
+	if dt < 30 {
+		return rateSample{}, false
+	}
+	return rateSample{
+		scope: scope,
+		tx:    float64(maxTx.Int64-minTx.Int64) / dt,
+		rx:    float64(maxRx.Int64-minRx.Int64) / dt,
+	}, true
+}
+
+func (c *Collector) loadBaseline(scope, direction string, how int) baseline {
+	var b baseline
+	c.db.QueryRow(
+		"SELECT mean, variance, samples FROM traffic_baseline WHERE scope = ? AND direction = ? AND hour_of_week = ?",
+		scope, direction, how,
+	).Scan(&b.mean, &b.variance, &b.samples)
+	return b
+}
+
+func (c *Collector) saveBaseline(scope, direction string, how int, b baseline) {
+	_, _ = c.db.Exec(`
+		INSERT INTO traffic_baseline (scope, direction, hour_of_week, mean, variance, samples)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(scope, direction, hour_of_week) DO UPDATE SET
+			mean = excluded.mean, variance = excluded.variance, samples = excluded.samples
+	`, scope, direction, how, b.mean, b.variance, b.samples)
+}
+
+// startAnomaly 记录新异常并发送报警
+func (c *Collector) startAnomaly(key, scope, direction, kind string, rate float64, b baseline, z float64, now time.Time) {
+	res, err := c.db.Exec(`
+		INSERT INTO traffic_anomalies (ts, scope, direction, kind, rate, baseline, stddev, zscore)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, now.Unix(), scope, direction, kind, rate, b.mean, b.stddev(), z)
+	if err != nil {
+		log.Printf("保存流量异常失败: %v", err)
+		return
+	}
+	id, _ := res.LastInsertId()
+	c.anomalies[key] = &anomalyTrack{id: id, kind: kind, since: now}
+	log.Printf("检测到流量异常: %s %s %s %.0f B/s (基线 %.0f B/s, z=%.1f)", scope, direction, kind, rate, b.mean, z)
+
+	if c.notifier != nil {
+		if err := c.notifier.SendAnomalyAlert(&notifier.AnomalyAlert{
+			ID: id, Scope: scope, Direction: direction, Kind: kind,
+			Rate: rate, Baseline: b.mean, ZScore: z, Time: now,
+		}); err != nil {
+			log.Printf("发送流量异常报警失败: %v", err)
+		}
+	}
+}
+
+// endAnomaly 标记异常结束并发送恢复通知
+func (c *Collector) endAnomaly(key string, track *anomalyTrack, scope, direction string, rate float64, b baseline, now time.Time) {
+	delete(c.anomalies, key)
+	_, _ = c.db.Exec("UPDATE traffic_anomalies SET ended_at = ? WHERE id = ?", now.Unix(), track.id)
+
+	if c.notifier != nil {
+		if err := c.notifier.SendAnomalyAlert(&notifier.AnomalyAlert{
+			ID: track.id, Scope: scope, Direction: direction, Kind: track.kind,
+			Rate: rate, Baseline: b.mean, Time: now, Recovered: true, Duration: now.Sub(track.since),
+		}); err != nil {
+			log.Printf("发送流量恢复通知失败: %v", err)
+		}
+	}
+}
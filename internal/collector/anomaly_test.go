@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/notifier"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TestBaselineUpdate 测试 EWMA 基线收敛
+func TestBaselineUpdate(t *testing.T) {
+	var b baseline
+	for i := 0; i < 200; i++ {
+		// 在 1000 附近交替波动 ±100
+		x := 1000.0 + 100*float64(1-2*(i%2))
+		b.update(x)
+	}
+	if math.Abs(b.mean-1000) > 10 {
+		t.Errorf("mean = %.1f, 期望接近 1000", b.mean)
+	}
+	if sd := math.Sqrt(b.variance); sd < 80 || sd > 120 {
+		t.Errorf("stddev = %.1f, 期望接近 100", sd)
+	}
+	if b.samples != 200 {
+		t.Errorf("samples = %d, 期望 200", b.samples)
+	}
+}
+
+// TestAnomalyClassify 测试异常判定
+func TestAnomalyClassify(t *testing.T) {
+	p := anomalyParams{zScore: 4, minRate: 512 * 1024, dropRatio: 0.1, minSamples: 30}
+	b := baseline{mean: 2 << 20, variance: math.Pow(200<<10, 2), samples: 100}
+
+	tests := []struct {
+		name string
+		b    baseline
+		x    float64
+		want string
+	}{
+		{"正常波动", b, 2.2 * (1 << 20), ""},
+		{"突增", b, 10 << 20, anomalySpike},
+		{"骤降", b, 100 << 10, anomalyDrop},
+		{"样本不足", baseline{mean: b.mean, variance: b.variance, samples: 10}, 10 << 20, ""},
+		// 低流量时 z-score 很高但绝对增量不足，不报警
+		{"低流量突增", baseline{mean: 1024, variance: 100, samples: 100}, 100 << 10, ""},
+		// 基线低于最小速率时不判定骤降
+		{"低流量归零", baseline{mean: 10 << 10, variance: 100, samples: 100}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := p.classify(tt.b, tt.x)
+			if got != tt.want {
+				t.Errorf("classify() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type anomalyRecorder struct {
+	Notifier
+	alerts []*notifier.AnomalyAlert
+}
+
+func (r *anomalyRecorder) SendAnomalyAlert(a *notifier.AnomalyAlert) error {
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+// TestRestoreAnomalies 测试重启后恢复未结束的异常：继续检测时可正常结束，关闭检测时直接结束
+func TestRestoreAnomalies(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Now().Add(-30 * time.Minute).Truncate(time.Second)
+	insert := func(ts time.Time, scope, kind string) {
+		db.Exec(`INSERT INTO traffic_anomalies (ts, scope, direction, kind, rate, baseline, stddev, zscore)
+			VALUES (?, ?, 'tx', ?, 0, 0, 0, 0)`, ts.Unix(), scope, kind)
+	}
+	insert(start.Add(-time.Hour), "total", anomalySpike) // 同一范围的旧记录
+	insert(start, "total", anomalySpike)
+	insert(start, "port:443", anomalyDrop)
+	openCount := func() int {
+		var n int
+		db.QueryRow("SELECT COUNT(*) FROM traffic_anomalies WHERE ended_at IS NULL").Scan(&n)
+		return n
+	}
+
+	rec := &anomalyRecorder{}
+	c := &Collector{db: db, notifier: rec, anomalies: make(map[string]*anomalyTrack),
+		cfg: &config.Config{AnomalyDetection: true}}
+	c.restoreAnomalies(time.Now())
+	track := c.anomalies["total/tx"]
+	if len(c.anomalies) != 2 || track == nil || track.id != 2 || !track.since.Equal(start) {
+		t.Fatalf("恢复的异常 = %+v", c.anomalies)
+	}
+	if n := openCount(); n != 2 {
+		t.Errorf("同一范围的旧记录应结束，未结束 %d 条", n)
+	}
+
+	c.endAnomaly("total/tx", track, "total", "tx", 0, baseline{}, time.Now())
+	if n := openCount(); n != 1 {
+		t.Errorf("恢复后应能正常结束，未结束 %d 条", n)
+	}
+	if len(rec.alerts) != 1 || !rec.alerts[0].Recovered || rec.alerts[0].Duration < 30*time.Minute {
+		t.Errorf("恢复通知 = %+v", rec.alerts)
+	}
+
+	// 重启后关闭了异常检测
+	c = &Collector{db: db, anomalies: make(map[string]*anomalyTrack), cfg: &config.Config{}}
+	c.restoreAnomalies(time.Now())
+	if n := openCount(); n != 0 || len(c.anomalies) != 0 {
+		t.Errorf("关闭检测时应结束所有异常，未结束 %d 条", n)
+	}
+}
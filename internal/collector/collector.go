@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/notifier"
+	"github.com/hh/heliox-mon/internal/realtime"
 	"github.com/hh/heliox-mon/internal/storage"
 )
 
@@ -33,18 +35,59 @@ type Collector struct {
 	portRxOffset  map[int]uint64
 
 	// CPU 采样（用于计算实时使用率）
-	lastCPUTotal uint64
-	lastCPUIdle  uint64
+	lastCPU   cpuTimes
+	lastCores []cpuTimes
+
+	// 磁盘采样
+	lastDiskStats     map[string]diskStat
+	lastDiskStatsTime time.Time
+	lastDiskUsageTime time.Time
+
+	// 流量异常检测状态（仅在检测协程中访问）
+	anomalies     map[string]*anomalyTrack
+	lastIfaces    map[string]ifaceCounters
+	lastIfaceTime time.Time
+
+	// 服务健康检查连续失败次数（按端口）
+	serviceFails map[int]int
+
+	// 进程采样
+	processMatchers []processMatcher
+	lastProcs       map[int]procSnapshot
+	lastProcsTime   time.Time
+
+	// 容器采样（按容器 ID）
+	lastContainers map[string]containerSnapshot
+	dockerErr      string
+
+	// 网络错误计数采样与报警状态
+	lastNetIfaces    map[string]ifaceCounters
+	lastSNMP         map[string]uint64
+	lastNetStatsTime time.Time
+	netAlerts        map[string]*netAlertTrack
+
+	// 实时网速环形缓冲
+	realtime *realtime.Hub
+	// 流量采集最近一次读到的端口计数（供实时采样复用）
+	portFeedMu sync.Mutex
+	portFeed   portReading
+
+	// 核心采集循环进度（systemd 看门狗）
+	progress loopProgress
 }
 
 // Notifier 通知器接口
 type Notifier interface {
-	SendTrafficAlert(usedGB, limitGB int, percent float64, resetDate string, daysLeft int, threshold int) error
+	SendTrafficAlert(usedGB, limitGB int, percent float64, cycleStart time.Time, resetDate string, daysLeft int, threshold int) error
+	SendReport(r *notifier.Report) error
+	SendAnomalyAlert(a *notifier.AnomalyAlert) error
+	SendServiceAlert(a *notifier.ServiceAlert) error
+	SendNetworkAlert(a *notifier.NetworkAlert) error
 }
 
 // New 创建采集器
 func New(cfg *config.Config, db *storage.DB, notifier Notifier) *Collector {
-	return &Collector{
+	c := &Collector{
 		cfg:          cfg,
 		db:           db,
 		notifier:     notifier,
@@ -53,7 +96,14 @@ func New(cfg *config.Config, db *storage.DB, notifier Notifier) *Collector {
 		lastPortRx:   make(map[int]uint64),
 		portTxOffset: make(map[int]uint64),
 		portRxOffset: make(map[int]uint64),
+		anomalies:    make(map[string]*anomalyTrack),
+		serviceFails: make(map[int]int),
+		netAlerts:    make(map[string]*netAlertTrack),
+
+		processMatchers: parseProcessMatchers(cfg.ProcessMatch),
 	}
+	c.realtime = realtime.NewHub(int(time.Duration(cfg.RealtimeBufferSeconds) * time.Second / c.realtimeResolution()))
+	return c
 }
 
 // Start 启动采集器
@@ -61,6 +111,16 @@ func (c *Collector) Start() {
 	// 初始化计数器偏移量，避免重启导致统计跳变
 	c.initTrafficOffsets()
 
+	// 看门狗只监视写入核心数据的循环
+	c.progress.track("system", 5*time.Second)
+	c.progress.track("traffic", 1*time.Second)
+	c.progress.track("latency", 1*time.Minute)
+	c.progress.track("aggregation", 1*time.Minute)
+
+	// 实时网速（默认每 1 秒，最低 250ms）
+	c.wg.Add(1)
+	go c.collectRealtime()
+
 	// 系统资源采集（每 5 秒）
 	c.wg.Add(1)
 	go c.collectSystemMetrics()
@@ -73,10 +133,42 @@ func (c *Collector) Start() {
 	c.wg.Add(1)
 	go c.collectLatency()
 
+	// 进程资源（每 1 分钟）
+	c.wg.Add(1)
+	go c.collectProcesses()
+
+	// Docker 容器资源（每 1 分钟）
+	if c.cfg.ContainerMetrics {
+		c.wg.Add(1)
+		go c.collectContainers()
+	}
+
+	// 网卡错误与 TCP 重传（每 1 分钟）
+	c.restoreNetAlerts(time.Now())
+	c.wg.Add(1)
+	go c.collectNetStats()
+
+	// 端口连接数（每 1 分钟）
+	c.wg.Add(1)
+	go c.collectConnections()
+
 	// 日汇总任务（每小时检查一次）
 	c.wg.Add(1)
 	go c.runDailyAggregation()
 
+	// 代理服务健康检查（每 30 秒）
+	if c.cfg.ServiceCheck {
+		c.wg.Add(1)
+		go c.runServiceChecks()
+	}
+
+	// 流量异常检测（每 1 分钟）
+	c.restoreAnomalies(time.Now())
+	if c.cfg.AnomalyDetection {
+		c.wg.Add(1)
+		go c.runAnomalyDetection()
+	}
+
 	log.Println("采集器已启动")
 }
 
@@ -99,6 +191,7 @@ func (c *Collector) collectSystemMetrics() {
 			return
 		case <-ticker.C:
 			c.doCollectSystemMetrics()
+			c.progress.beat("system")
 		}
 	}
 }
@@ -118,6 +211,7 @@ func (c *Collector) collectTraffic() {
 			return
 		case <-ticker.C:
 			c.doCollectTraffic()
+			c.progress.beat("traffic")
 		}
 	}
 }
@@ -134,6 +228,7 @@ func (c *Collector) collectLatency() {
 			return
 		case <-ticker.C:
 			c.doCollectLatency()
+			c.progress.beat("latency")
 		}
 	}
 }
@@ -154,6 +249,7 @@ func (c *Collector) runDailyAggregation() {
 			return
 		case <-ticker.C:
 			c.doDailyAggregation()
+			c.progress.beat("aggregation")
 		}
 	}
 }
@@ -172,14 +268,30 @@ func (c *Collector) doDailyAggregation() {
 	c.aggregatePortDailyTraffic(today)
 	c.aggregatePortDailyTraffic(yesterday)
 
+	// 归档已结束的计费周期（依赖昨日汇总已完成）
+	c.finalizeBillingCycles(now)
+
+	// 汇总系统资源峰值（今日 + 昨日）
+	c.aggregateSystemDaily(today)
+	c.aggregateSystemDaily(yesterday)
+
+	// 系统指标历史汇总（1 分钟 / 15 分钟 / 1 小时）
+	c.rollupSystemMetrics(now)
+
 	// 汇总延迟数据（降采样）
 	c.aggregateLatencyData()
 
+	// 连接数降采样
+	c.aggregateConnectionData()
+
 	// 清理过期快照
 	c.cleanupOldSnapshots()
 
 	// 检查配额并发送通知
 	c.checkQuotaAndNotify(now)
+
+	// 定时报告
+	c.checkScheduledReports(now)
 }
 
 func (c *Collector) dayBounds(date string) (int64, int64, bool) {
@@ -287,21 +399,7 @@ func (c *Collector) checkQuotaAndNotify(now time.Time) {
 	`, billingStart.Format("2006-01-02"))
 	row.Scan(&tx, &rx)
 
-	var usedBytes int64
-	switch c.cfg.BillingMode {
-	case "tx_only":
-		usedBytes = tx
-	case "rx_only":
-		usedBytes = rx
-	case "max_value":
-		if tx > rx {
-			usedBytes = tx
-		} else {
-			usedBytes = rx
-		}
-	default: // bidirectional
-		usedBytes = tx + rx
-	}
+	usedBytes := config.BilledBytes(c.cfg.BillingMode, tx, rx)
 
 	limitGB := c.cfg.MonthlyLimitGB
 	limitBytes := int64(limitGB) * 1024 * 1024 * 1024
@@ -321,7 +419,7 @@ func (c *Collector) checkQuotaAndNotify(now time.Time) {
 		}
 		if percent >= float64(threshold) {
 			resetDate := billingEnd.Format("2006-01-02")
-			if err := c.notifier.SendTrafficAlert(usedGB, limitGB, percent, resetDate, daysLeft, threshold); err != nil {
+			if err := c.notifier.SendTrafficAlert(usedGB, limitGB, percent, billingStart, resetDate, daysLeft, threshold); err != nil {
 				log.Printf("发送流量预警失败: %v", err)
 			}
 		}
@@ -5,6 +5,7 @@ package collector
 import (
 	"log"
 	"math/rand"
+	"os"
 	"time"
 )
 
@@ -30,6 +31,7 @@ func (c *Collector) doCollectTraffic() {
 
 	// 模拟端口流量
 	ports := []int{c.cfg.SnellPort, c.cfg.VlessPort}
+	realtimeCounts := make(map[int]ifaceCounters, len(ports))
 	for _, port := range ports {
 		if port == 0 {
 			continue
@@ -46,6 +48,7 @@ func (c *Collector) doCollectTraffic() {
 		}
 		c.lastPortTx[port] += ptx
 		c.lastPortRx[port] += prx
+		realtimeCounts[port] = ifaceCounters{tx: c.lastPortTx[port], rx: c.lastPortRx[port]}
 
 		_, err := c.db.Exec(
 			"INSERT INTO port_traffic_snapshots (ts, port, tx_bytes, rx_bytes) VALUES (?, ?, ?, ?)",
@@ -55,6 +58,7 @@ func (c *Collector) doCollectTraffic() {
 			log.Printf("[Mock] 保存端口 %d 流量快照失败: %v", port, err)
 		}
 	}
+	c.feedRealtimePorts(realtimeCounts, time.Now())
 }
 
 // initTrafficOffsets 模拟初始化 (不做任何事)
@@ -64,27 +68,56 @@ func (c *Collector) initTrafficOffsets() {
 
 // doCollectSystemMetrics 模拟系统资源采集
 func (c *Collector) doCollectSystemMetrics() {
-	now := time.Now().Unix()
+	s := &systemSample{ts: time.Now().Unix()}
+
+	s.cpu.User = rand.Float64() * 50
+	s.cpu.System = rand.Float64() * 20
+	s.cpu.IOWait = rand.Float64() * 5
+	s.cpu.SoftIRQ = rand.Float64() * 10
+	s.cpu.Steal = rand.Float64() * 5
+	s.cpu.Total = s.cpu.User + s.cpu.System + s.cpu.IOWait + s.cpu.SoftIRQ + s.cpu.Steal
+	for i := 0; i < 4; i++ {
+		s.cores = append(s.cores, rand.Float64()*100)
+	}
 
-	cpu := rand.Float64() * 100
-	memTotal := uint64(16 * 1024 * 1024 * 1024) // 16GB
-	memUsed := uint64(rand.Float64() * float64(memTotal))
-	diskTotal := uint64(512 * 1024 * 1024 * 1024) // 512GB
-	diskUsed := uint64(rand.Float64() * float64(diskTotal))
-	load1 := rand.Float64() * 2
-	load5 := rand.Float64() * 2
-	load15 := rand.Float64() * 2
+	s.mem.total = uint64(16 * 1024 * 1024 * 1024) // 16GB
+	s.mem.used = uint64(rand.Float64() * float64(s.mem.total))
+	s.mem.cached = s.mem.total - s.mem.used
+	s.mem.swapTotal = uint64(2 * 1024 * 1024 * 1024)
+	s.mem.swapUsed = uint64(rand.Float64() * float64(s.mem.swapTotal) / 4)
+	s.psiCPU = &psiResource{some: psiStats{avg10: rand.Float64() * 5, avg60: rand.Float64() * 3}}
+	s.diskTotal = uint64(512 * 1024 * 1024 * 1024) // 512GB
+	s.diskUsed = uint64(rand.Float64() * float64(s.diskTotal))
+	s.load1 = rand.Float64() * 2
+	s.load5 = rand.Float64() * 2
+	s.load15 = rand.Float64() * 2
+
+	c.saveSystemSample(s)
+	c.collectDiskMetrics(time.Unix(s.ts, 0))
+}
 
-	_, err := c.db.Exec(
-		`INSERT INTO system_metrics (ts, cpu_percent, mem_used, mem_total, disk_used, disk_total, load_1, load_5, load_15)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		now, cpu, memUsed, memTotal, diskUsed, diskTotal, load1, load5, load15,
-	)
-	if err != nil {
-		log.Printf("[Mock] 保存系统指标失败: %v", err)
+// readDiskUsage 模拟文件系统用量
+func (c *Collector) readDiskUsage() []diskUsage {
+	total := uint64(512 * 1024 * 1024 * 1024)
+	used := uint64(rand.Float64() * float64(total))
+	return []diskUsage{
+		{mount: "/", device: "/dev/disk1s1", fstype: "apfs", total: total, used: used, avail: total - used, inodesTotal: 1 << 24, inodesUsed: 1 << 20},
+		{mount: "/var/log", device: "/dev/disk1s2", fstype: "apfs", total: 10 << 30, used: 9 << 30, avail: 1 << 30, inodesTotal: 1 << 20, inodesUsed: 1 << 10},
 	}
 }
 
+// readDiskStats 模拟块设备计数器（累加随机增量）
+func (c *Collector) readDiskStats() map[string]diskStat {
+	prev := c.lastDiskStats["disk0"]
+	return map[string]diskStat{"disk0": {
+		readOps:    prev.readOps + uint64(rand.Intn(100)),
+		writeOps:   prev.writeOps + uint64(rand.Intn(200)),
+		readBytes:  prev.readBytes + uint64(rand.Intn(4<<20)),
+		writeBytes: prev.writeBytes + uint64(rand.Intn(8<<20)),
+		ioTicks:    prev.ioTicks + uint64(rand.Intn(1000)),
+	}}
+}
+
 // doCollectLatency 模拟延迟采集
 func (c *Collector) doCollectLatency() {
 	now := time.Now().Unix()
@@ -104,3 +137,95 @@ func (c *Collector) doCollectLatency() {
 		}
 	}
 }
+
+// readIfaceCounters 模拟环境不做单网卡检测
+func (c *Collector) readIfaceCounters() map[string]ifaceCounters {
+	return nil
+}
+
+// inspectPort 模拟服务检查（始终在监听，进程为自身）
+func (c *Collector) inspectPort(port int) portStatus {
+	return portStatus{
+		tcpListen: true,
+		udpBound:  true,
+		process:   &processInfo{pid: os.Getpid(), name: "mock", startedAt: mockStartedAt, unit: "mock.service"},
+	}
+}
+
+var mockStartedAt = time.Now().Unix()
+
+// countConnections 模拟端口连接数
+func (c *Collector) countConnections(ports []int) map[int]*connCount {
+	counts := make(map[int]*connCount, len(ports))
+	for _, port := range ports {
+		tcp := rand.Intn(200)
+		counts[port] = &connCount{tcp: tcp, udp: rand.Intn(50), clients: tcp/4 + 1}
+	}
+	return counts
+}
+
+// readProcSnapshots 模拟进程采样（累加随机 CPU 时间）
+func (c *Collector) readProcSnapshots() []procSnapshot {
+	var snapshots []procSnapshot
+	for i, name := range []string{"sing-box", "snell-server", "heliox-mon", "sshd"} {
+		pid := 1000 + i
+		prev := c.lastProcs[pid]
+		snapshots = append(snapshots, procSnapshot{
+			pid:       pid,
+			name:      name,
+			cmdline:   "/usr/local/bin/" + name,
+			startTime: 1,
+			cpuTicks:  prev.cpuTicks + uint64(rand.Intn(3000)),
+			rss:       uint64(20+rand.Intn(100)) << 20,
+			threads:   4 + rand.Intn(8),
+			fds:       10 + rand.Intn(500),
+			rchar:     prev.rchar + uint64(rand.Intn(50<<20)),
+			wchar:     prev.wchar + uint64(rand.Intn(50<<20)),
+		})
+	}
+	return snapshots
+}
+
+// readContainerStats 模拟容器计数器（累加随机增量）
+func (c *Collector) readContainerStats(pid int) (containerStats, error) {
+	var prev containerStats
+	for _, s := range c.lastContainers {
+		if s.pid == pid {
+			prev = s.stats
+		}
+	}
+	return containerStats{
+		cpuUsec:    prev.cpuUsec + uint64(rand.Intn(30e6)),
+		memCurrent: uint64(50+rand.Intn(200)) << 20,
+		memMax:     512 << 20,
+		ioRead:     prev.ioRead + uint64(rand.Intn(1<<20)),
+		ioWrite:    prev.ioWrite + uint64(rand.Intn(4<<20)),
+		netRx:      prev.netRx + uint64(rand.Intn(500<<20)),
+		netTx:      prev.netTx + uint64(rand.Intn(500<<20)),
+	}, nil
+}
+
+// readNetSNMP 模拟协议栈计数器（累加随机增量）
+func (c *Collector) readNetSNMP() map[string]uint64 {
+	snmp := make(map[string]uint64)
+	for k, v := range c.lastSNMP {
+		snmp[k] = v
+	}
+	outSegs := uint64(50000 + rand.Intn(50000))
+	snmp["Tcp.OutSegs"] += outSegs
+	snmp["Tcp.RetransSegs"] += outSegs * uint64(rand.Intn(30)) / 1000
+	snmp["Tcp.InErrs"] += uint64(rand.Intn(5))
+	snmp["Tcp.OutRsts"] += uint64(rand.Intn(200))
+	snmp["TcpExt.ListenOverflows"] += uint64(rand.Intn(2))
+	return snmp
+}
+
+// mockRealtimeIface 模拟网卡累计计数（仅实时采样协程访问）
+var mockRealtimeIface ifaceCounters
+
+// readRealtimeCounters 模拟实时计数器（单网卡，累加随机增量）
+func (c *Collector) readRealtimeCounters() map[string]ifaceCounters {
+	mockRealtimeIface.tx += uint64(rand.Int63n(2 << 20))
+	mockRealtimeIface.rx += uint64(rand.Int63n(8 << 20))
+	return map[string]ifaceCounters{"en0": mockRealtimeIface}
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	// connRawRetention 分钟级连接数保留时长，之后降采样为小时级
+	connRawRetention = 48 * time.Hour
+	// connHourlyRetention 小时级连接数保留时长
+	connHourlyRetention = 90 * 24 * time.Hour
+)
+
+// connCount 端口连接数
+type connCount struct {
+	tcp     int // TCP 已建立连接
+	udp     int // UDP 流
+	clients int // 不同客户端 IP
+}
+
+// collectConnections 采集端口连接数（每 1 分钟）
+func (c *Collector) collectConnections() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.doCollectConnections()
+		}
+	}
+}
+
+// doCollectConnections 执行端口连接数采集
+func (c *Collector) doCollectConnections() {
+	var ports []int
+	for _, port := range []int{c.cfg.SnellPort, c.cfg.VlessPort} {
+		if port > 0 {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return
+	}
+
+	now := time.Now().Unix()
+	for port, cnt := range c.countConnections(ports) {
+		_, err := c.db.Exec(`
+			INSERT INTO port_connections (ts, port, tcp, udp, clients, tcp_max, udp_max, is_aggregated)
+			VALUES (?, ?, ?, ?, ?, ?, ?, 0)
+		`, now, port, cnt.tcp, cnt.udp, cnt.clients, cnt.tcp, cnt.udp)
+		if err != nil {
+			log.Printf("保存端口 %d 连接数失败: %v", port, err)
+		}
+	}
+}
+
+// aggregateConnectionData 连接数降采样：超过 48 小时的分钟数据合并为小时数据（平均值 + 峰值）
+func (c *Collector) aggregateConnectionData() {
+	now := time.Now()
+	cutoff := now.Add(-connRawRetention).Unix() / 3600 * 3600
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO port_connections (ts, port, tcp, udp, clients, tcp_max, udp_max, is_aggregated)
+		SELECT (ts / 3600) * 3600, port, AVG(tcp), AVG(udp), AVG(clients), MAX(tcp_max), MAX(udp_max), 1
+		FROM port_connections
+		WHERE is_aggregated = 0 AND ts < ?
+		GROUP BY ts / 3600, port
+	`, cutoff)
+	if err != nil {
+		log.Printf("连接数降采样失败: %v", err)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM port_connections WHERE is_aggregated = 0 AND ts < ?", cutoff); err != nil {
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM port_connections WHERE ts < ?", now.Add(-connHourlyRetention).Unix()); err != nil {
+		return
+	}
+	tx.Commit()
+}
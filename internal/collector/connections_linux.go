@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// countConnections 统计各端口的 TCP 已建立连接、UDP 流和客户端数
+// UDP 代理通常使用未 connect 的套接字，优先从 conntrack 统计流数；conntrack 不可用时退化为统计已 connect 的 UDP 套接字
+func (c *Collector) countConnections(ports []int) map[int]*connCount {
+	counts := make(map[int]*connCount, len(ports))
+	clients := make(map[int]map[string]bool, len(ports))
+	for _, p := range ports {
+		counts[p] = &connCount{}
+		clients[p] = make(map[string]bool)
+	}
+
+	udpFlows, conntrackOK := readConntrackUDP(counts)
+
+	for _, e := range readAllSockets() {
+		cnt, ok := counts[e.localPort]
+		if !ok || e.state != tcpEstablished {
+			continue
+		}
+		switch e.proto {
+		case "tcp", "tcp6":
+			cnt.tcp++
+		case "udp", "udp6":
+			if conntrackOK {
+				continue
+			}
+			cnt.udp++
+		}
+		clients[e.localPort][e.remoteIP.String()] = true
+	}
+
+	for port, srcs := range udpFlows {
+		counts[port].udp = len(srcs)
+		for _, src := range srcs {
+			clients[port][src] = true
+		}
+	}
+	for port, set := range clients {
+		counts[port].clients = len(set)
+	}
+	return counts
+}
+
+// readConntrackUDP 从 /proc/net/nf_conntrack 读取目标端口为被监控端口的 UDP 流，返回 端口 -> 每条流的源地址
+func readConntrackUDP(ports map[int]*connCount) (map[int][]string, bool) {
+	file, err := os.Open("/proc/net/nf_conntrack")
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	flows := make(map[int][]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		port, src, ok := parseConntrackUDP(scanner.Text())
+		if !ok {
+			continue
+		}
+		if _, watched := ports[port]; watched {
+			flows[port] = append(flows[port], src)
+		}
+	}
+	if scanner.Err() != nil {
+		return nil, false
+	}
+	return flows, true
+}
+
+// parseConntrackUDP 解析一行 conntrack 记录，返回原始方向的目标端口和源地址
+// 格式: ipv4 2 udp 17 29 src=1.2.3.4 dst=5.6.7.8 sport=5000 dport=36890 src=... （第一组为原始方向）
+func parseConntrackUDP(line string) (int, string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 || fields[2] != "udp" {
+		return 0, "", false
+	}
+
+	var src string
+	dport := -1
+	for _, f := range fields[5:] {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			continue
+		}
+		switch {
+		case k == "src" && src == "":
+			src = v
+		case k == "dport" && dport < 0:
+			dport, _ = strconv.Atoi(v)
+		}
+	}
+	if src == "" || dport < 0 {
+		return 0, "", false
+	}
+	return dport, src, true
+}
@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"log"
+	"time"
+)
+
+// containerRetention 容器指标保留时长
+const containerRetention = 7 * 24 * time.Hour
+
+// containerStats 容器的一次采样（累计计数）
+type containerStats struct {
+	cpuUsec    uint64 // cpu.stat usage_usec
+	memCurrent uint64 // memory.current
+	memMax     uint64 // memory.max，0 表示不限制
+	ioRead     uint64 // io.stat rbytes 合计
+	ioWrite    uint64 // io.stat wbytes 合计
+	netRx      uint64 // 容器网络命名空间内除 lo 外的网卡合计
+	netTx      uint64
+
+	hostNetwork bool // 与宿主机共用网络命名空间（network_mode: host），没有独立的网络计数
+}
+
+// containerSnapshot 上一次采样，用于计算增量
+type containerSnapshot struct {
+	pid   int
+	stats containerStats
+	at    time.Time
+}
+
+// collectContainers 容器资源采集（每 1 分钟）
+func (c *Collector) collectContainers() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	docker := newDockerClient(c.cfg.DockerSocket)
+	c.doCollectContainers(docker, time.Now())
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.doCollectContainers(docker, time.Now())
+		}
+	}
+}
+
+// doCollectContainers 通过 Docker API 发现容器，读取 cgroup v2 与容器网络命名空间计数器
+func (c *Collector) doCollectContainers(docker *dockerClient, now time.Time) {
+	containers, err := docker.listContainers()
+	if err != nil {
+		// Docker 未安装或未运行时只记录一次
+		if msg := err.Error(); msg != c.dockerErr {
+			log.Printf("读取 Docker 容器列表失败: %v", err)
+			c.dockerErr = msg
+		}
+		return
+	}
+	c.dockerErr = ""
+
+	prev := c.lastContainers
+	c.lastContainers = make(map[string]containerSnapshot, len(containers))
+	ts := now.Unix()
+	for _, ct := range containers {
+		stats, err := c.readContainerStats(ct.pid)
+		if err != nil {
+			continue
+		}
+		c.lastContainers[ct.id] = containerSnapshot{pid: ct.pid, stats: stats, at: now}
+
+		// 新容器或容器重启（PID 变化）只建立基准
+		p, ok := prev[ct.id]
+		if !ok || p.pid != ct.pid {
+			continue
+		}
+		elapsed := now.Sub(p.at).Seconds()
+		if elapsed <= 0 {
+			continue
+		}
+
+		delta := func(a, b uint64) uint64 {
+			if a < b {
+				return 0
+			}
+			return a - b
+		}
+		cpuPercent := float64(delta(stats.cpuUsec, p.stats.cpuUsec)) / (elapsed * 1e6) * 100
+
+		// host 网络模式的网络列保存为 NULL，避免把宿主机流量算到容器上
+		var rxBytes, txBytes, rxBps, txBps interface{}
+		if !stats.hostNetwork {
+			rx, tx := delta(stats.netRx, p.stats.netRx), delta(stats.netTx, p.stats.netTx)
+			rxBytes, txBytes, rxBps, txBps = rx, tx, float64(rx)/elapsed, float64(tx)/elapsed
+		}
+
+		_, err = c.db.Exec(`
+			INSERT INTO container_metrics (ts, container_id, name, image, cpu_percent, mem_current, mem_max,
+			    io_read_bps, io_write_bps, host_network, net_rx_bytes, net_tx_bytes, net_rx_bps, net_tx_bps)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, ts, shortID(ct.id), ct.name, ct.image, cpuPercent, stats.memCurrent, stats.memMax,
+			float64(delta(stats.ioRead, p.stats.ioRead))/elapsed, float64(delta(stats.ioWrite, p.stats.ioWrite))/elapsed,
+			stats.hostNetwork, rxBytes, txBytes, rxBps, txBps)
+		if err != nil {
+			log.Printf("保存容器指标失败: %v", err)
+		}
+	}
+	_, _ = c.db.Exec("DELETE FROM container_metrics WHERE ts < ?", now.Add(-containerRetention).Unix())
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readContainerStats 读取容器主进程所在 cgroup 及网络命名空间的计数器
+func (c *Collector) readContainerStats(pid int) (containerStats, error) {
+	return readContainerStatsFrom(c.cfg.CgroupRoot, "/proc", pid)
+}
+
+// readContainerStatsFrom 从指定的 cgroupfs 与 procfs 根目录读取（便于测试）
+func readContainerStatsFrom(cgroupRoot, procRoot string, pid int) (containerStats, error) {
+	var st containerStats
+	procDir := filepath.Join(procRoot, strconv.Itoa(pid))
+
+	content, err := os.ReadFile(filepath.Join(procDir, "cgroup"))
+	if err != nil {
+		return st, err
+	}
+	cgPath, ok := cgroupV2Path(string(content))
+	if !ok {
+		return st, fmt.Errorf("进程 %d 不在 cgroup v2 层级中", pid)
+	}
+	dir := filepath.Join(cgroupRoot, cgPath)
+
+	cpuStat, err := os.ReadFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return st, err
+	}
+	st.cpuUsec = parseKeyValue(string(cpuStat))["usage_usec"]
+
+	st.memCurrent = readUintFile(filepath.Join(dir, "memory.current"))
+	st.memMax = readUintFile(filepath.Join(dir, "memory.max")) // "max" 解析失败即为 0
+
+	if ioStat, err := os.ReadFile(filepath.Join(dir, "io.stat")); err == nil {
+		st.ioRead, st.ioWrite = parseIOStat(string(ioStat))
+	}
+
+	// host 网络模式的容器与宿主机共用网络命名空间，net/dev 是宿主机网卡，不能算作容器流量
+	if hostNetNS(procRoot, procDir) {
+		st.hostNetwork = true
+		return st, nil
+	}
+	// 容器网络命名空间内的网卡（通常只有 eth0）
+	if ifaces, err := readNetDev(filepath.Join(procDir, "net", "dev")); err == nil {
+		for iface, ic := range ifaces {
+			if iface == "lo" {
+				continue
+			}
+			st.netRx += ic.rx
+			st.netTx += ic.tx
+		}
+	}
+	return st, nil
+}
+
+// hostNetNS 进程是否与宿主机（PID 1，读取不到时用本进程）处于同一网络命名空间；无法判断时返回 false
+func hostNetNS(procRoot, procDir string) bool {
+	ns, err := os.Readlink(filepath.Join(procDir, "ns", "net"))
+	if err != nil {
+		return false
+	}
+	host, err := os.Readlink(filepath.Join(procRoot, "1", "ns", "net"))
+	if err != nil {
+		host, err = os.Readlink(filepath.Join(procRoot, "self", "ns", "net"))
+	}
+	return err == nil && ns == host
+}
+
+// cgroupV2Path 从 /proc/<pid>/cgroup 中取出 cgroup v2 路径（0::/system.slice/docker-xxx.scope）
+func cgroupV2Path(content string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// parseKeyValue 解析 "key value" 每行一对的文件（cpu.stat 等）
+func parseKeyValue(content string) map[string]uint64 {
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = v
+	}
+	return values
+}
+
+// parseIOStat 汇总 io.stat 各设备的读写字节数
+// 8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+func parseIOStat(content string) (read, write uint64) {
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, f := range fields[1:] {
+			k, v, ok := strings.Cut(f, "=")
+			if !ok {
+				continue
+			}
+			n, _ := strconv.ParseUint(v, 10, 64)
+			switch k {
+			case "rbytes":
+				read += n
+			case "wbytes":
+				write += n
+			}
+		}
+	}
+	return read, write
+}
+
+// readUintFile 读取只包含一个整数的文件，失败返回 0
+func readUintFile(path string) uint64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return v
+}
@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReadContainerStats 使用假的 cgroupfs 与 procfs 目录测试容器计数器读取（含 host 网络模式）
+func TestReadContainerStats(t *testing.T) {
+	root := t.TempDir()
+	cgroupRoot := filepath.Join(root, "cgroup")
+	procRoot := filepath.Join(root, "proc")
+	scope := "/system.slice/docker-a1b2c3d4e5f6.scope"
+
+	files := map[string]string{
+		filepath.Join(procRoot, "4242", "cgroup"): "0::" + scope + "\n",
+		filepath.Join(procRoot, "4242", "net", "dev"): `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:     100       2    0    0    0     0          0         0      100       2    0    0    0     0       0          0
+  eth0: 5000000    4000    0    0    0     0          0         0  7000000    5000    0    0    0     0       0          0
+`,
+		filepath.Join(cgroupRoot, scope, "cpu.stat"):       "usage_usec 123456789\nuser_usec 100000000\nsystem_usec 23456789\n",
+		filepath.Join(cgroupRoot, scope, "memory.current"): "104857600\n",
+		filepath.Join(cgroupRoot, scope, "memory.max"):     "max\n",
+		filepath.Join(cgroupRoot, scope, "io.stat"): "8:0 rbytes=1000 wbytes=2000 rios=1 wios=2 dbytes=0 dios=0\n" +
+			"253:0 rbytes=300 wbytes=400 rios=1 wios=1 dbytes=0 dios=0\n",
+		// cgroup v1 进程
+		filepath.Join(procRoot, "7", "cgroup"): "4:memory:/docker/abc\n1:cpu:/docker/abc\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st, err := readContainerStatsFrom(cgroupRoot, procRoot, 4242)
+	if err != nil {
+		t.Fatalf("readContainerStatsFrom: %v", err)
+	}
+	want := containerStats{
+		cpuUsec:    123456789,
+		memCurrent: 104857600,
+		memMax:     0,
+		ioRead:     1300,
+		ioWrite:    2400,
+		netRx:      5000000,
+		netTx:      7000000,
+	}
+	if st != want {
+		t.Errorf("stats = %+v, 期望 %+v", st, want)
+	}
+
+	// host 网络模式：与 PID 1 同一网络命名空间时不读取网卡计数
+	for pid, ns := range map[string]string{"1": "net:[4026531840]", "4242": "net:[4026531840]"} {
+		os.MkdirAll(filepath.Join(procRoot, pid, "ns"), 0755)
+		if err := os.Symlink(ns, filepath.Join(procRoot, pid, "ns", "net")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st, err = readContainerStatsFrom(cgroupRoot, procRoot, 4242)
+	if err != nil {
+		t.Fatalf("readContainerStatsFrom: %v", err)
+	}
+	if !st.hostNetwork || st.netRx != 0 || st.netTx != 0 || st.cpuUsec != want.cpuUsec {
+		t.Errorf("host 网络模式 stats = %+v", st)
+	}
+
+	if _, err := readContainerStatsFrom(cgroupRoot, procRoot, 7); err == nil {
+		t.Error("cgroup v1 进程应返回错误")
+	}
+	if _, err := readContainerStatsFrom(cgroupRoot, procRoot, 99); err == nil {
+		t.Error("不存在的进程应返回错误")
+	}
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+)
+
+// finalizeBillingCycles 归档已结束的计费周期
+// 从最后一个已归档周期（或最早的日汇总）开始，依次补齐当前周期之前的所有周期。
+// 计费模式和限额取首次归档时的配置，之后不再随配置变化。
+// 补归档历史周期（首次启用或停机跨过多个周期）时无从得知当时的配置，只能按当前配置计算。
+// 日汇总会在次日继续更新前一天，因此最后一天仍在汇总的周期会重新累计流量。
+func (c *Collector) finalizeBillingCycles(now time.Time) {
+	tz := c.cfg.Timezone
+	currentStart, _ := c.getBillingCycleDates(now)
+	yesterday := now.In(tz).AddDate(0, 0, -1).Format("2006-01-02")
+
+	var from time.Time
+	var lastEnd sql.NullString
+	if err := c.db.QueryRow("SELECT MAX(end_date) FROM billing_cycles").Scan(&lastEnd); err != nil {
+		return
+	}
+	if lastEnd.Valid {
+		end, err := time.ParseInLocation("2006-01-02", lastEnd.String, tz)
+		if err != nil {
+			return
+		}
+		from = end.AddDate(0, 0, 1)
+		if lastEnd.String >= yesterday {
+			from, _ = c.getBillingCycleDates(end)
+		}
+	} else {
+		var earliest sql.NullString
+		if err := c.db.QueryRow("SELECT MIN(date) FROM traffic_daily WHERE iface = 'total'").Scan(&earliest); err != nil || !earliest.Valid {
+			return
+		}
+		first, err := time.ParseInLocation("2006-01-02", earliest.String, tz)
+		if err != nil {
+			return
+		}
+		from, _ = c.getBillingCycleDates(first)
+	}
+
+	for start := from; start.Before(currentStart); {
+		_, end := c.getBillingCycleDates(start)
+		if err := c.finalizeBillingCycle(start, end); err != nil {
+			log.Printf("归档计费周期 %s 失败: %v", start.Format("2006-01-02"), err)
+			return
+		}
+		start = end.Add(time.Second)
+	}
+}
+
+// finalizeBillingCycle 写入或更新单个计费周期的汇总与端口明细
+func (c *Collector) finalizeBillingCycle(start, end time.Time) error {
+	startDate := start.Format("2006-01-02")
+	endDate := end.Format("2006-01-02")
+
+	// 已归档的周期沿用当时的计费模式和限额
+	mode, limitGB := c.cfg.BillingMode, c.cfg.MonthlyLimitGB
+	err := c.db.QueryRow("SELECT billing_mode, limit_gb FROM billing_cycles WHERE start_date = ?", startDate).Scan(&mode, &limitGB)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	var tx, rx int64
+	row := c.db.QueryRow(`
+		SELECT COALESCE(SUM(tx_bytes), 0), COALESCE(SUM(rx_bytes), 0)
+		FROM traffic_daily
+		WHERE iface = 'total' AND date >= ? AND date <= ?
+	`, startDate, endDate)
+	if err := row.Scan(&tx, &rx); err != nil {
+		return err
+	}
+
+	// 峰值日按计费模式计算
+	rows, err := c.db.Query(`
+		SELECT date, tx_bytes, rx_bytes
+		FROM traffic_daily
+		WHERE iface = 'total' AND date >= ? AND date <= ?
+	`, startDate, endDate)
+	if err != nil {
+		return err
+	}
+	var peakDate sql.NullString
+	var peakBytes int64
+	for rows.Next() {
+		var date string
+		var dtx, drx int64
+		if err := rows.Scan(&date, &dtx, &drx); err != nil {
+			continue
+		}
+		if billed := config.BilledBytes(mode, dtx, drx); billed > peakBytes {
+			peakBytes = billed
+			peakDate = sql.NullString{String: date, Valid: true}
+		}
+	}
+	rows.Close()
+
+	tx2, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx2.Rollback()
+
+	_, err = tx2.Exec(`
+		INSERT INTO billing_cycles (start_date, end_date, tx_bytes, rx_bytes, billed_bytes, billing_mode, limit_gb, peak_date, peak_bytes, finalized_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(start_date) DO UPDATE SET
+			tx_bytes = excluded.tx_bytes, rx_bytes = excluded.rx_bytes, billed_bytes = excluded.billed_bytes,
+			peak_date = excluded.peak_date, peak_bytes = excluded.peak_bytes
+	`, startDate, endDate, tx, rx, config.BilledBytes(mode, tx, rx), mode, limitGB, peakDate, peakBytes, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx2.Exec(`
+		INSERT INTO billing_cycle_ports (start_date, port, tx_bytes, rx_bytes)
+		SELECT ?, port, SUM(tx_bytes), SUM(rx_bytes)
+		FROM port_traffic_daily
+		WHERE date >= ? AND date <= ?
+		GROUP BY port
+		ON CONFLICT(start_date, port) DO UPDATE SET
+			tx_bytes = excluded.tx_bytes, rx_bytes = excluded.rx_bytes
+	`, startDate, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	return tx2.Commit()
+}
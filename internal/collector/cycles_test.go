@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TestFinalizeBillingCycles 测试补归档历史周期、重置次日继续累计最后一天的流量，以及归档后沿用当时的计费模式与限额
+func TestFinalizeBillingCycles(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{Timezone: time.UTC, ResetDay: 1, BillingMode: "tx_only", MonthlyLimitGB: 1000}
+	c := &Collector{db: db, cfg: cfg}
+	const gb = int64(1) << 30
+	setDaily := func(date string, tx, rx int64) {
+		db.Exec(`INSERT INTO traffic_daily (date, iface, tx_bytes, rx_bytes) VALUES (?, 'total', ?, ?)
+			ON CONFLICT(date, iface) DO UPDATE SET tx_bytes = excluded.tx_bytes, rx_bytes = excluded.rx_bytes`, date, tx, rx)
+		db.Exec(`INSERT INTO port_traffic_daily (date, port, tx_bytes, rx_bytes) VALUES (?, 443, ?, ?)
+			ON CONFLICT(date, port) DO UPDATE SET tx_bytes = excluded.tx_bytes, rx_bytes = excluded.rx_bytes`, date, tx/2, rx/2)
+	}
+	type cycle struct {
+		tx, billed int64
+		mode       string
+		limit      int
+		portTx     int64
+	}
+	get := func(start string) (cy cycle) {
+		t.Helper()
+		if err := db.QueryRow("SELECT tx_bytes, billed_bytes, billing_mode, limit_gb FROM billing_cycles WHERE start_date = ?", start).
+			Scan(&cy.tx, &cy.billed, &cy.mode, &cy.limit); err != nil {
+			t.Fatalf("周期 %s 未归档: %v", start, err)
+		}
+		db.QueryRow("SELECT tx_bytes FROM billing_cycle_ports WHERE start_date = ? AND port = 443", start).Scan(&cy.portTx)
+		return
+	}
+
+	// 首次启用：补归档 2025-12 与 2026-01 两个周期（按当前配置）
+	setDaily("2025-12-15", 10*gb, 1*gb)
+	setDaily("2026-01-10", 20*gb, 2*gb)
+	setDaily("2026-01-31", 5*gb, 1*gb)
+	c.finalizeBillingCycles(time.Date(2026, 2, 1, 0, 1, 0, 0, time.UTC))
+	if cy := get("2025-12-01"); cy.tx != 10*gb || cy.mode != "tx_only" {
+		t.Errorf("2025-12 = %+v", cy)
+	}
+	if cy := get("2026-01-01"); cy.tx != 25*gb || cy.billed != 25*gb || cy.portTx != 25*gb/2 {
+		t.Errorf("2026-01 = %+v", cy)
+	}
+
+	// 重置当天最后一天的日汇总继续增长，配置也已修改：流量更新，模式与限额保持归档时的值
+	setDaily("2026-01-31", 8*gb, 1*gb)
+	cfg.BillingMode, cfg.MonthlyLimitGB = "max_value", 2000
+	c.finalizeBillingCycles(time.Date(2026, 2, 1, 0, 30, 0, 0, time.UTC))
+	if cy := get("2026-01-01"); cy.tx != 28*gb || cy.billed != 28*gb || cy.mode != "tx_only" || cy.limit != 1000 || cy.portTx != 28*gb/2 {
+		t.Errorf("重新累计后 2026-01 = %+v", cy)
+	}
+
+	// 之后不再改动已结束的周期
+	setDaily("2026-01-31", 9*gb, 1*gb)
+	c.finalizeBillingCycles(time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC))
+	if cy := get("2026-01-01"); cy.tx != 28*gb {
+		t.Errorf("次日之后 2026-01 = %+v", cy)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM billing_cycles").Scan(&count)
+	if count != 2 {
+		t.Errorf("归档周期数 = %d, want 2", count)
+	}
+}
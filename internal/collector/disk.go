@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// diskUsageInterval 文件系统用量采样间隔（用量变化缓慢，无需每 5 秒采集）
+	diskUsageInterval = time.Minute
+	// diskUsageRetention 文件系统用量保留时长
+	diskUsageRetention = 7 * 24 * time.Hour
+)
+
+// diskUsage 单个文件系统用量
+type diskUsage struct {
+	mount       string
+	device      string
+	fstype      string
+	total       uint64 // used + avail（与 df 一致，不含 root 保留块）
+	used        uint64
+	avail       uint64 // 普通用户可用（Bavail）
+	inodesTotal uint64
+	inodesUsed  uint64
+}
+
+// diskStat /proc/diskstats 中的累计计数
+type diskStat struct {
+	readOps, writeOps     uint64
+	readBytes, writeBytes uint64
+	ioTicks               uint64 // 设备忙碌时间（毫秒）
+}
+
+// diskIORate 块设备 I/O 速率
+type diskIORate struct {
+	device      string
+	readBps     float64
+	writeBps    float64
+	readIOPS    float64
+	writeIOPS   float64
+	utilPercent float64
+}
+
+// diskIORateBetween 计算两次采样之间的 I/O 速率，计数器回退时返回 false
+func diskIORateBetween(device string, prev, cur diskStat, elapsed time.Duration) (diskIORate, bool) {
+	secs := elapsed.Seconds()
+	if secs <= 0 || cur.readOps < prev.readOps || cur.writeOps < prev.writeOps ||
+		cur.readBytes < prev.readBytes || cur.writeBytes < prev.writeBytes || cur.ioTicks < prev.ioTicks {
+		return diskIORate{}, false
+	}
+
+	util := float64(cur.ioTicks-prev.ioTicks) / (secs * 1000) * 100
+	if util > 100 {
+		util = 100
+	}
+	return diskIORate{
+		device:      device,
+		readBps:     float64(cur.readBytes-prev.readBytes) / secs,
+		writeBps:    float64(cur.writeBytes-prev.writeBytes) / secs,
+		readIOPS:    float64(cur.readOps-prev.readOps) / secs,
+		writeIOPS:   float64(cur.writeOps-prev.writeOps) / secs,
+		utilPercent: util,
+	}, true
+}
+
+// ignoreMount 判断挂载点是否在忽略列表中（文件系统类型或挂载点前缀）
+func (c *Collector) ignoreMount(mount, fstype string) bool {
+	for _, t := range c.cfg.DiskIgnoreFSTypes {
+		if fstype == t {
+			return true
+		}
+	}
+	for _, prefix := range c.cfg.DiskIgnoreMounts {
+		if mount == prefix || strings.HasPrefix(mount, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// collectDiskMetrics 采集磁盘 I/O（每次）与文件系统用量（每分钟）
+func (c *Collector) collectDiskMetrics(now time.Time) {
+	ts := now.Unix()
+
+	stats := c.readDiskStats()
+	if c.lastDiskStats != nil {
+		elapsed := now.Sub(c.lastDiskStatsTime)
+		for dev, cur := range stats {
+			prev, ok := c.lastDiskStats[dev]
+			if !ok {
+				continue
+			}
+			r, ok := diskIORateBetween(dev, prev, cur, elapsed)
+			if !ok {
+				continue
+			}
+			_, err := c.db.Exec(`
+				INSERT INTO disk_io (ts, device, read_bps, write_bps, read_iops, write_iops, util_percent)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, ts, r.device, r.readBps, r.writeBps, r.readIOPS, r.writeIOPS, r.utilPercent)
+			if err != nil {
+				log.Printf("保存磁盘 I/O 失败: %v", err)
+			}
+		}
+	}
+	c.lastDiskStats, c.lastDiskStatsTime = stats, now
+	_, _ = c.db.Exec("DELETE FROM disk_io WHERE ts < ?", ts-3600)
+
+	if now.Sub(c.lastDiskUsageTime) < diskUsageInterval {
+		return
+	}
+	c.lastDiskUsageTime = now
+
+	for _, u := range c.readDiskUsage() {
+		_, err := c.db.Exec(`
+			INSERT INTO disk_usage (ts, mount, device, fstype, total, used, avail, inodes_total, inodes_used)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, ts, u.mount, u.device, u.fstype, u.total, u.used, u.avail, u.inodesTotal, u.inodesUsed)
+		if err != nil {
+			log.Printf("保存文件系统用量失败: %v", err)
+		}
+	}
+	_, _ = c.db.Exec("DELETE FROM disk_usage WHERE ts < ?", now.Add(-diskUsageRetention).Unix())
+}
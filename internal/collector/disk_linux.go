@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// readDiskUsage 读取 /proc/mounts 中所有真实文件系统的用量（同一设备只统计一次，忽略 bind mount）
+func (c *Collector) readDiskUsage() []diskUsage {
+	file, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var result []diskUsage
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// device mount fstype options dump pass
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		device, mount, fstype := fields[0], unescapeMount(fields[1]), fields[2]
+		if c.ignoreMount(mount, fstype) || seen[device] {
+			continue
+		}
+
+		u, ok := statDisk(mount)
+		if !ok {
+			continue
+		}
+		seen[device] = true
+		u.device, u.fstype = device, fstype
+		result = append(result, u)
+	}
+	return result
+}
+
+// unescapeMount 还原 /proc/mounts 中的八进制转义（空格为 \040）
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// statDisk 通过 statfs 获取文件系统用量，容量为 0 的伪文件系统返回 false
+func statDisk(mount string) (diskUsage, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(mount, &stat); err != nil || stat.Blocks == 0 {
+		return diskUsage{}, false
+	}
+
+	bsize := uint64(stat.Bsize)
+	u := diskUsage{mount: mount}
+	u.used = (stat.Blocks - stat.Bfree) * bsize
+	u.avail = stat.Bavail * bsize
+	u.total = u.used + u.avail
+	u.inodesTotal = stat.Files
+	if stat.Files >= stat.Ffree {
+		u.inodesUsed = stat.Files - stat.Ffree
+	}
+	return u, true
+}
+
+// readDiskStats 读取 /proc/diskstats 中整盘设备的累计计数（跳过分区、loop 和 ram 设备）
+func (c *Collector) readDiskStats() map[string]diskStat {
+	file, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	stats := make(map[string]diskStat)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// major minor name reads merged sectors ms writes merged sectors ms in_flight io_ticks ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 {
+			continue
+		}
+		name := fields[2]
+		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+			continue
+		}
+		// 只有整盘设备出现在 /sys/block 下
+		if _, err := os.Stat("/sys/block/" + name); err != nil {
+			continue
+		}
+
+		parse := func(i int) uint64 {
+			v, _ := strconv.ParseUint(fields[i], 10, 64)
+			return v
+		}
+		stats[name] = diskStat{
+			readOps:    parse(3),
+			readBytes:  parse(5) * 512,
+			writeOps:   parse(7),
+			writeBytes: parse(9) * 512,
+			ioTicks:    parse(12),
+		}
+	}
+	return stats
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// dockerClient 通过 unix 套接字访问 Docker Engine API（只读）
+type dockerClient struct {
+	http *http.Client
+}
+
+// dockerContainer 运行中的容器
+type dockerContainer struct {
+	id    string
+	name  string
+	image string
+	pid   int // 容器主进程在宿主机上的 PID
+}
+
+// newDockerClient 创建 Docker 客户端
+func newDockerClient(socket string) *dockerClient {
+	return &dockerClient{
+		http: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// get 请求 API 并解析 JSON（主机名无意义，由 DialContext 决定连接目标）
+func (d *dockerClient) get(path string, v interface{}) error {
+	resp, err := d.http.Get("http://docker" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// listContainers 列出运行中的容器及其主进程 PID
+func (d *dockerClient) listContainers() ([]dockerContainer, error) {
+	var list []struct {
+		ID    string   `json:"Id"`
+		Names []string `json:"Names"`
+		Image string   `json:"Image"`
+	}
+	if err := d.get("/containers/json", &list); err != nil {
+		return nil, err
+	}
+
+	containers := make([]dockerContainer, 0, len(list))
+	for _, item := range list {
+		// 列表接口不含 PID，需逐个 inspect
+		var detail struct {
+			State struct {
+				Running bool `json:"Running"`
+				Pid     int  `json:"Pid"`
+			} `json:"State"`
+		}
+		if err := d.get("/containers/"+item.ID+"/json", &detail); err != nil {
+			continue // 容器可能刚好退出
+		}
+		if !detail.State.Running || detail.State.Pid == 0 {
+			continue
+		}
+
+		name := shortID(item.ID)
+		if len(item.Names) > 0 {
+			name = strings.TrimPrefix(item.Names[0], "/")
+		}
+		containers = append(containers, dockerContainer{
+			id:    item.ID,
+			name:  name,
+			image: item.Image,
+			pid:   detail.State.Pid,
+		})
+	}
+	return containers, nil
+}
+
+// shortID 截取容器 ID 前 12 位（与 docker ps 一致）
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
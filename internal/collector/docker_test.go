@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDockerListContainers 使用假的 Docker 套接字测试容器发现
+func TestDockerListContainers(t *testing.T) {
+	// unix 套接字路径长度有限，不使用 t.TempDir()
+	dir, err := os.MkdirTemp("", "dock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "docker.sock")
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("无法创建 unix 套接字: %v", err)
+	}
+	const snellID = "a1b2c3d4e5f60000000000000000000000000000000000000000000000000000"
+	const exitedID = "ffffffffffff0000000000000000000000000000000000000000000000000000"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"Id": snellID, "Names": []string{"/heliox-snell"}, "Image": "heliox/snell:latest"},
+			{"Id": exitedID, "Names": []string{"/gone"}, "Image": "busybox"},
+		})
+	})
+	mux.HandleFunc("/containers/"+snellID+"/json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"State": map[string]interface{}{"Running": true, "Pid": 4242}})
+	})
+	mux.HandleFunc("/containers/"+exitedID+"/json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"State": map[string]interface{}{"Running": false, "Pid": 0}})
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	containers, err := newDockerClient(socket).listContainers()
+	if err != nil {
+		t.Fatalf("listContainers: %v", err)
+	}
+	if len(containers) != 1 {
+		t.Fatalf("容器数 = %d, 期望 1: %+v", len(containers), containers)
+	}
+	got := containers[0]
+	if got.id != snellID || got.name != "heliox-snell" || got.image != "heliox/snell:latest" || got.pid != 4242 {
+		t.Errorf("容器 = %+v", got)
+	}
+
+	// 套接字不存在时返回错误
+	if _, err := newDockerClient(filepath.Join(dir, "missing.sock")).listContainers(); err == nil {
+		t.Error("套接字不存在时应返回错误")
+	}
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minStallTimeout 判定采集循环停滞的最短时间，避免短周期循环偶尔变慢就误报
+const minStallTimeout = time.Minute
+
+// loopProgress 各采集循环最近一次完成的时间，供 systemd 看门狗判断采集是否卡住
+type loopProgress struct {
+	mu       sync.Mutex
+	last     map[string]time.Time
+	interval map[string]time.Duration
+}
+
+// track 登记需要监视的循环，以登记时间作为起点（循环一直没跑起来也能发现）
+func (p *loopProgress) track(name string, interval time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.last == nil {
+		p.last = make(map[string]time.Time)
+		p.interval = make(map[string]time.Duration)
+	}
+	p.last[name] = time.Now()
+	p.interval[name] = interval
+}
+
+// beat 循环完成一轮
+func (p *loopProgress) beat(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.last[name]; ok {
+		p.last[name] = time.Now()
+	}
+}
+
+// stalled 超过 3 个周期（至少 1 分钟）没有完成一轮的循环及其停滞时长
+func (p *loopProgress) stalled(now time.Time) map[string]time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out map[string]time.Duration
+	for name, last := range p.last {
+		timeout := 3 * p.interval[name]
+		if timeout < minStallTimeout {
+			timeout = minStallTimeout
+		}
+		if idle := now.Sub(last); idle > timeout {
+			if out == nil {
+				out = make(map[string]time.Duration)
+			}
+			out[name] = idle
+		}
+	}
+	return out
+}
+
+// CheckProgress 核心采集循环是否都在正常推进，停滞时返回说明
+func (c *Collector) CheckProgress() error {
+	stalled := c.progress.stalled(time.Now())
+	if len(stalled) == 0 {
+		return nil
+	}
+	parts := make([]string, 0, len(stalled))
+	for name, idle := range stalled {
+		parts = append(parts, fmt.Sprintf("%s %s 未完成", name, idle.Truncate(time.Second)))
+	}
+	sort.Strings(parts)
+	return fmt.Errorf("采集停滞: %s", strings.Join(parts, ", "))
+}
@@ -0,0 +1,33 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoopProgress 测试采集循环停滞判定：超过 3 个周期且至少 1 分钟未完成一轮
+func TestLoopProgress(t *testing.T) {
+	c := &Collector{}
+	c.progress.track("traffic", time.Second)
+	c.progress.track("latency", time.Minute)
+	if err := c.CheckProgress(); err != nil {
+		t.Fatalf("刚启动不应判定停滞: %v", err)
+	}
+
+	now := time.Now()
+	if stalled := c.progress.stalled(now.Add(2 * time.Minute)); len(stalled) != 1 || stalled["traffic"] == 0 {
+		t.Errorf("2 分钟后停滞 = %v", stalled)
+	}
+
+	c.progress.beat("traffic")
+	c.progress.beat("unknown") // 未登记的循环忽略
+	if stalled := c.progress.stalled(now.Add(4 * time.Minute)); len(stalled) != 2 {
+		t.Errorf("4 分钟后停滞 = %v", stalled)
+	}
+
+	c.progress.last["latency"] = now.Add(-5 * time.Minute)
+	if err := c.CheckProgress(); err == nil || !strings.Contains(err.Error(), "latency") {
+		t.Errorf("CheckProgress = %v", err)
+	}
+}
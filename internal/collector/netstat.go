@@ -0,0 +1,301 @@
+package collector
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/notifier"
+)
+
+const (
+	// netStatsRetention 网络错误计数保留时长
+	netStatsRetention = 7 * 24 * time.Hour
+	// netAlertMinutes 连续超过阈值的分钟数，达到后才报警
+	netAlertMinutes = 3
+	// netRetransMinSegs 每分钟发送报文段少于该值时不计算重传率（样本太少）
+	netRetransMinSegs = 1000
+)
+
+// 网络报警指标
+const (
+	netAlertRetrans         = "tcp_retrans"      // TCP 重传率（%）
+	netAlertListenOverflows = "listen_overflows" // 全连接队列溢出（次/分钟）
+	netAlertIfaceDrops      = "iface_drops"      // 网卡丢包与错误（个/分钟）
+)
+
+// parseSNMP 解析 /proc/net/snmp 与 /proc/net/netstat（表头行与数值行成对出现）
+// Tcp: RtoAlgorithm RtoMin ... RetransSegs ...
+// Tcp: 1 200 ... 1234 ...
+// 返回 "Tcp.RetransSegs"、"TcpExt.ListenOverflows" 形式的键
+func parseSNMP(content string) map[string]uint64 {
+	values := make(map[string]uint64)
+	var header []string
+	var prefix string
+	for _, line := range strings.Split(content, "\n") {
+		p, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if header == nil || p != prefix {
+			header, prefix = fields, p
+			continue
+		}
+		for i, f := range fields {
+			if i >= len(header) {
+				break
+			}
+			// Tcp.MaxConn 为 -1，解析失败忽略
+			if v, err := strconv.ParseUint(f, 10, 64); err == nil {
+				values[p+"."+header[i]] = v
+			}
+		}
+		header = nil
+	}
+	return values
+}
+
+// counterDelta 计数器增量，回绕或重置时返回 0
+func counterDelta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// tcpStatsDelta 一分钟内的 TCP/UDP 协议栈计数
+type tcpStatsDelta struct {
+	outSegs, retransSegs     uint64
+	inErrs, outRsts          uint64
+	estabResets, attemptFail uint64
+	listenOverflows          uint64
+	listenDrops              uint64
+	synRetrans, timeouts     uint64
+	udpInErrors, udpRcvbuf   uint64
+}
+
+// retransPercent 重传率（重传报文段 / 发送报文段）
+func (d tcpStatsDelta) retransPercent() float64 {
+	if d.outSegs == 0 {
+		return 0
+	}
+	return float64(d.retransSegs) / float64(d.outSegs) * 100
+}
+
+// tcpDelta 由两次 SNMP 采样计算增量
+func tcpDelta(cur, prev map[string]uint64) tcpStatsDelta {
+	d := func(key string) uint64 { return counterDelta(cur[key], prev[key]) }
+	return tcpStatsDelta{
+		outSegs:         d("Tcp.OutSegs"),
+		retransSegs:     d("Tcp.RetransSegs"),
+		inErrs:          d("Tcp.InErrs"),
+		outRsts:         d("Tcp.OutRsts"),
+		estabResets:     d("Tcp.EstabResets"),
+		attemptFail:     d("Tcp.AttemptFails"),
+		listenOverflows: d("TcpExt.ListenOverflows"),
+		listenDrops:     d("TcpExt.ListenDrops"),
+		synRetrans:      d("TcpExt.TCPSynRetrans"),
+		timeouts:        d("TcpExt.TCPTimeouts"),
+		udpInErrors:     d("Udp.InErrors"),
+		udpRcvbuf:       d("Udp.RcvbufErrors"),
+	}
+}
+
+// netAlertTrack 网络报警状态（连续超阈值计数与进行中的报警）
+type netAlertTrack struct {
+	over  int   // 连续超过阈值的分钟数
+	id    int64 // 进行中的报警 ID，0 表示未报警
+	since time.Time
+}
+
+// netAlertThreshold 指标对应的报警阈值（<= 0 表示关闭）
+func (c *Collector) netAlertThreshold(metric string) float64 {
+	switch metric {
+	case netAlertRetrans:
+		return c.cfg.NetAlertRetransPercent
+	case netAlertListenOverflows:
+		return float64(c.cfg.NetAlertListenOverflowsPerMin)
+	case netAlertIfaceDrops:
+		return float64(c.cfg.NetAlertDropsPerMin)
+	}
+	return 0
+}
+
+// restoreNetAlerts 启动时接管上次运行未结束的网络报警，之后回落时能正常结束并发送恢复通知；
+// 报警已关闭（或该指标阈值已关闭）时直接结束这些记录
+func (c *Collector) restoreNetAlerts(now time.Time) {
+	rows, err := c.db.Query("SELECT id, ts, metric, scope FROM net_alerts WHERE ended_at IS NULL ORDER BY id")
+	if err != nil {
+		log.Printf("读取未结束的网络报警失败: %v", err)
+		return
+	}
+	var stale []int64
+	for rows.Next() {
+		var id, ts int64
+		var metric, scope string
+		if err := rows.Scan(&id, &ts, &metric, &scope); err != nil {
+			continue
+		}
+		if !c.cfg.NetAlerts || c.netAlertThreshold(metric) <= 0 {
+			stale = append(stale, id)
+			continue
+		}
+		key := metric + "|" + scope
+		// 同一指标与范围只保留最新一条
+		if prev := c.netAlerts[key]; prev != nil {
+			stale = append(stale, prev.id)
+		}
+		c.netAlerts[key] = &netAlertTrack{over: netAlertMinutes, id: id, since: time.Unix(ts, 0)}
+	}
+	rows.Close()
+	for _, id := range stale {
+		_, _ = c.db.Exec("UPDATE net_alerts SET ended_at = ? WHERE id = ?", now.Unix(), id)
+	}
+	if len(stale) > 0 {
+		log.Printf("结束 %d 条未结束的网络报警", len(stale))
+	}
+}
+
+// collectNetStats 网络错误与 TCP 协议栈计数采集（每 1 分钟）
+func (c *Collector) collectNetStats() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	// 首次采样只建立基准
+	c.doCollectNetStats(time.Now())
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.doCollectNetStats(time.Now())
+		}
+	}
+}
+
+// doCollectNetStats 计算每分钟增量、保存并检查报警阈值
+func (c *Collector) doCollectNetStats(now time.Time) {
+	ifaces := c.readIfaceCounters()
+	snmp := c.readNetSNMP()
+	prevIfaces, prevSNMP, prevTime := c.lastNetIfaces, c.lastSNMP, c.lastNetStatsTime
+	c.lastNetIfaces, c.lastSNMP, c.lastNetStatsTime = ifaces, snmp, now
+
+	if prevTime.IsZero() {
+		return
+	}
+	// 按分钟归一化，采样间隔抖动时保持阈值含义一致
+	perMin := 60 / now.Sub(prevTime).Seconds()
+	ts := now.Unix()
+
+	names := make([]string, 0, len(ifaces))
+	for name := range ifaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cur, ok := ifaces[name]
+		prev, okPrev := prevIfaces[name]
+		if !ok || !okPrev {
+			continue
+		}
+		rxPackets, txPackets := counterDelta(cur.rxPackets, prev.rxPackets), counterDelta(cur.txPackets, prev.txPackets)
+		rxErrs, txErrs := counterDelta(cur.rxErrs, prev.rxErrs), counterDelta(cur.txErrs, prev.txErrs)
+		rxDrop, txDrop := counterDelta(cur.rxDrop, prev.rxDrop), counterDelta(cur.txDrop, prev.txDrop)
+		rxFifo, txFifo := counterDelta(cur.rxFifo, prev.rxFifo), counterDelta(cur.txFifo, prev.txFifo)
+
+		_, err := c.db.Exec(`
+			INSERT INTO net_iface_stats (ts, iface, rx_packets, tx_packets, rx_errs, tx_errs, rx_drop, tx_drop, rx_fifo, tx_fifo)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, ts, name, rxPackets, txPackets, rxErrs, txErrs, rxDrop, txDrop, rxFifo, txFifo)
+		if err != nil {
+			log.Printf("保存网卡计数失败: %v", err)
+		}
+
+		drops := float64(rxErrs+txErrs+rxDrop+txDrop) * perMin
+		c.checkNetAlert(netAlertIfaceDrops, "iface:"+name, drops, c.netAlertThreshold(netAlertIfaceDrops), now)
+	}
+
+	if prevSNMP != nil && snmp != nil {
+		d := tcpDelta(snmp, prevSNMP)
+		_, err := c.db.Exec(`
+			INSERT INTO net_tcp_stats (ts, out_segs, retrans_segs, retrans_percent, in_errs, out_rsts, estab_resets,
+			    attempt_fails, listen_overflows, listen_drops, syn_retrans, timeouts, udp_in_errors, udp_rcvbuf_errors)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, ts, d.outSegs, d.retransSegs, d.retransPercent(), d.inErrs, d.outRsts, d.estabResets,
+			d.attemptFail, d.listenOverflows, d.listenDrops, d.synRetrans, d.timeouts, d.udpInErrors, d.udpRcvbuf)
+		if err != nil {
+			log.Printf("保存 TCP 计数失败: %v", err)
+		}
+
+		// 流量很小时几次重传就会造成高重传率，不参与判定
+		retrans := 0.0
+		if float64(d.outSegs)*perMin >= netRetransMinSegs {
+			retrans = d.retransPercent()
+		}
+		c.checkNetAlert(netAlertRetrans, "tcp", retrans, c.netAlertThreshold(netAlertRetrans), now)
+		c.checkNetAlert(netAlertListenOverflows, "tcp", float64(d.listenOverflows)*perMin,
+			c.netAlertThreshold(netAlertListenOverflows), now)
+	}
+
+	cutoff := now.Add(-netStatsRetention).Unix()
+	_, _ = c.db.Exec("DELETE FROM net_iface_stats WHERE ts < ?", cutoff)
+	_, _ = c.db.Exec("DELETE FROM net_tcp_stats WHERE ts < ?", cutoff)
+}
+
+// checkNetAlert 连续 netAlertMinutes 分钟超过阈值时报警，回落到阈值以下时恢复（阈值 <= 0 表示关闭）
+func (c *Collector) checkNetAlert(metric, scope string, value, threshold float64, now time.Time) {
+	if threshold <= 0 || !c.cfg.NetAlerts {
+		return
+	}
+	key := metric + "|" + scope
+	track := c.netAlerts[key]
+	if track == nil {
+		track = &netAlertTrack{}
+		c.netAlerts[key] = track
+	}
+
+	if value < threshold {
+		track.over = 0
+		if track.id != 0 {
+			_, _ = c.db.Exec("UPDATE net_alerts SET ended_at = ? WHERE id = ?", now.Unix(), track.id)
+			c.sendNetAlert(&notifier.NetworkAlert{
+				ID: track.id, Metric: metric, Scope: scope, Value: value, Threshold: threshold,
+				Time: now, Recovered: true, Duration: now.Sub(track.since),
+			})
+			track.id = 0
+		}
+		return
+	}
+
+	track.over++
+	if track.id != 0 || track.over < netAlertMinutes {
+		return
+	}
+	res, err := c.db.Exec(
+		"INSERT INTO net_alerts (ts, metric, scope, value, threshold) VALUES (?, ?, ?, ?, ?)",
+		now.Unix(), metric, scope, value, threshold,
+	)
+	if err != nil {
+		log.Printf("保存网络报警失败: %v", err)
+		return
+	}
+	track.id, _ = res.LastInsertId()
+	track.since = now
+	log.Printf("网络报警: %s %s = %.2f (阈值 %.2f)", scope, metric, value, threshold)
+	c.sendNetAlert(&notifier.NetworkAlert{
+		ID: track.id, Metric: metric, Scope: scope, Value: value, Threshold: threshold, Time: now,
+	})
+}
+
+func (c *Collector) sendNetAlert(a *notifier.NetworkAlert) {
+	if c.notifier == nil {
+		return
+	}
+	if err := c.notifier.SendNetworkAlert(a); err != nil {
+		log.Printf("发送网络报警失败: %v", err)
+	}
+}
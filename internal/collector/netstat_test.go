@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/notifier"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TestParseSNMP 测试 /proc/net/snmp 与 /proc/net/netstat 解析
+func TestParseSNMP(t *testing.T) {
+	content := `Ip: Forwarding DefaultTTL InReceives
+Ip: 1 64 123456
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 500 600 7 8 20 100000 200000 3000 2 40 0
+Udp: InDatagrams NoPorts InErrors OutDatagrams RcvbufErrors SndbufErrors InCsumErrors IgnoredMulti MemErrors
+Udp: 1000 5 3 900 2 0 0 0 0
+TcpExt: SyncookiesSent SyncookiesRecv ListenOverflows ListenDrops TCPTimeouts TCPSynRetrans
+TcpExt: 0 0 11 12 13 14
+`
+	got := parseSNMP(content)
+	for key, want := range map[string]uint64{
+		"Ip.InReceives":          123456,
+		"Tcp.OutSegs":            200000,
+		"Tcp.RetransSegs":        3000,
+		"Tcp.AttemptFails":       7,
+		"Udp.RcvbufErrors":       2,
+		"TcpExt.ListenOverflows": 11,
+		"TcpExt.TCPSynRetrans":   14,
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %d, 期望 %d", key, got[key], want)
+		}
+	}
+	if _, ok := got["Tcp.MaxConn"]; ok {
+		t.Error("Tcp.MaxConn 为 -1，应忽略")
+	}
+
+	prev := parseSNMP(content)
+	prev["Tcp.OutSegs"], prev["Tcp.RetransSegs"] = 100000, 1000
+	d := tcpDelta(got, prev)
+	if d.outSegs != 100000 || d.retransSegs != 2000 || d.retransPercent() != 2 {
+		t.Errorf("delta = %+v, 重传率 %.2f", d, d.retransPercent())
+	}
+}
+
+type netAlertRecorder struct {
+	Notifier
+	alerts []*notifier.NetworkAlert
+}
+
+func (r *netAlertRecorder) SendNetworkAlert(a *notifier.NetworkAlert) error {
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+// TestCheckNetAlert 测试连续超阈值报警与恢复
+func TestCheckNetAlert(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rec := &netAlertRecorder{}
+	c := &Collector{db: db, notifier: rec, netAlerts: make(map[string]*netAlertTrack),
+		cfg: &config.Config{Timezone: time.UTC, NetAlerts: true}}
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	values := []float64{8, 8, 1, 8, 8, 8, 9, 2}
+	for i, v := range values {
+		c.checkNetAlert(netAlertRetrans, "tcp", v, 5, now.Add(time.Duration(i)*time.Minute))
+	}
+
+	// 前两分钟超阈值后回落不报警；第 4~6 分钟连续超阈值报警一次，第 8 分钟恢复
+	if len(rec.alerts) != 2 {
+		t.Fatalf("通知数 = %d, 期望 2", len(rec.alerts))
+	}
+	start, end := rec.alerts[0], rec.alerts[1]
+	if start.Recovered || start.Value != 8 || !start.Time.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("报警 = %+v", start)
+	}
+	if !end.Recovered || end.ID != start.ID || end.Duration != 2*time.Minute {
+		t.Errorf("恢复 = %+v", end)
+	}
+
+	var endedAt int64
+	if err := db.QueryRow("SELECT ended_at FROM net_alerts WHERE id = ?", start.ID).Scan(&endedAt); err != nil {
+		t.Fatal(err)
+	}
+	if endedAt != now.Add(7*time.Minute).Unix() {
+		t.Errorf("ended_at = %d", endedAt)
+	}
+
+	// 阈值 <= 0 时关闭
+	c.checkNetAlert(netAlertIfaceDrops, "iface:eth0", 1e6, 0, now)
+	if len(rec.alerts) != 2 {
+		t.Error("阈值为 0 时不应报警")
+	}
+}
+
+// TestRestoreNetAlerts 测试重启后接管未结束的网络报警，不重复插入并能正常恢复
+func TestRestoreNetAlerts(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Now().Add(-30 * time.Minute).Truncate(time.Second)
+	insert := func(ts time.Time, metric, scope string) {
+		db.Exec("INSERT INTO net_alerts (ts, metric, scope, value, threshold) VALUES (?, ?, ?, 8, 5)",
+			ts.Unix(), metric, scope)
+	}
+	insert(start.Add(-time.Hour), netAlertRetrans, "tcp") // 同一指标的旧记录
+	insert(start, netAlertRetrans, "tcp")
+	insert(start, netAlertIfaceDrops, "iface:eth0") // 该指标阈值已关闭
+	openCount := func() int {
+		var n int
+		db.QueryRow("SELECT COUNT(*) FROM net_alerts WHERE ended_at IS NULL").Scan(&n)
+		return n
+	}
+
+	rec := &netAlertRecorder{}
+	c := &Collector{db: db, notifier: rec, netAlerts: make(map[string]*netAlertTrack),
+		cfg: &config.Config{Timezone: time.UTC, NetAlerts: true, NetAlertRetransPercent: 5}}
+	c.restoreNetAlerts(time.Now())
+	track := c.netAlerts[netAlertRetrans+"|tcp"]
+	if len(c.netAlerts) != 1 || track == nil || track.id != 2 || !track.since.Equal(start) {
+		t.Fatalf("恢复的报警 = %+v", c.netAlerts)
+	}
+	if n := openCount(); n != 1 {
+		t.Errorf("旧记录与已关闭指标的记录应结束，未结束 %d 条", n)
+	}
+
+	// 条件仍存在时不重复插入
+	c.checkNetAlert(netAlertRetrans, "tcp", 9, 5, time.Now())
+	var total int
+	db.QueryRow("SELECT COUNT(*) FROM net_alerts").Scan(&total)
+	if total != 3 || len(rec.alerts) != 0 {
+		t.Errorf("条件仍存在时不应新增报警: %d 条记录, %d 条通知", total, len(rec.alerts))
+	}
+
+	c.checkNetAlert(netAlertRetrans, "tcp", 1, 5, time.Now())
+	if n := openCount(); n != 0 {
+		t.Errorf("回落后应结束报警，未结束 %d 条", n)
+	}
+	if len(rec.alerts) != 1 || !rec.alerts[0].Recovered || rec.alerts[0].ID != 2 || rec.alerts[0].Duration < 30*time.Minute {
+		t.Errorf("恢复通知 = %+v", rec.alerts)
+	}
+
+	// 报警关闭时直接结束
+	insert(start, netAlertRetrans, "tcp")
+	c = &Collector{db: db, netAlerts: make(map[string]*netAlertTrack), cfg: &config.Config{NetAlertRetransPercent: 5}}
+	c.restoreNetAlerts(time.Now())
+	if n := openCount(); n != 0 || len(c.netAlerts) != 0 {
+		t.Errorf("NET_ALERTS 关闭时应结束未结束的报警，未结束 %d 条", n)
+	}
+}
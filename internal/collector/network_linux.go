@@ -108,14 +108,55 @@ func (c *Collector) initTrafficOffsets() {
 	}
 }
 
-// readProcNetDev 从 /proc/net/dev 读取网络流量
+// readProcNetDev 从 /proc/net/dev 读取网络流量（所有物理网卡合计）
 func (c *Collector) readProcNetDev() (tx, rx uint64, err error) {
-	file, err := os.Open("/proc/net/dev")
+	ifaces, err := readProcNetDevIfaces()
 	if err != nil {
 		return 0, 0, err
 	}
+	for _, ic := range ifaces {
+		tx += ic.tx
+		rx += ic.rx
+	}
+	return tx, rx, nil
+}
+
+// readIfaceCounters 读取各网卡计数器（用于异常检测）
+func (c *Collector) readIfaceCounters() map[string]ifaceCounters {
+	ifaces, err := readProcNetDevIfaces()
+	if err != nil {
+		return nil
+	}
+	return ifaces
+}
+
+// readProcNetDevIfaces 从 /proc/net/dev 读取各网卡计数器
+func readProcNetDevIfaces() (map[string]ifaceCounters, error) {
+	all, err := readNetDev("/proc/net/dev")
+	if err != nil {
+		return nil, err
+	}
+
+	ifaces := make(map[string]ifaceCounters, len(all))
+	for iface, ic := range all {
+		// 跳过 lo 和 docker 网桥
+		if iface == "lo" || strings.HasPrefix(iface, "docker") || strings.HasPrefix(iface, "br-") || strings.HasPrefix(iface, "veth") {
+			continue
+		}
+		ifaces[iface] = ic
+	}
+	return ifaces, nil
+}
+
+// readNetDev 解析 net/dev 格式的文件（宿主机或 /proc/<pid>/net/dev），返回全部网卡
+func readNetDev(path string) (map[string]ifaceCounters, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
+	ifaces := make(map[string]ifaceCounters)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -131,26 +172,47 @@ func (c *Collector) readProcNetDev() (tx, rx uint64, err error) {
 		}
 
 		iface := strings.TrimSpace(parts[0])
-		// 跳过 lo 和 docker 网桥
-		if iface == "lo" || strings.HasPrefix(iface, "docker") || strings.HasPrefix(iface, "br-") || strings.HasPrefix(iface, "veth") {
-			continue
-		}
-
 		fields := strings.Fields(parts[1])
-		if len(fields) < 10 {
+		if len(fields) < 16 {
 			continue
 		}
 
-		// 字段顺序: rx_bytes, rx_packets, ..., tx_bytes, tx_packets, ...
-		// 索引 0 = rx_bytes, 索引 8 = tx_bytes
-		ifaceRx, _ := strconv.ParseUint(fields[0], 10, 64)
-		ifaceTx, _ := strconv.ParseUint(fields[8], 10, 64)
+		// 字段顺序: rx_bytes packets errs drop fifo frame compressed multicast,
+		//          tx_bytes packets errs drop fifo colls carrier compressed
+		var v [16]uint64
+		for i := range v {
+			v[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
 
-		rx += ifaceRx
-		tx += ifaceTx
+		ifaces[iface] = ifaceCounters{
+			rx: v[0], rxPackets: v[1], rxErrs: v[2], rxDrop: v[3], rxFifo: v[4],
+			tx: v[8], txPackets: v[9], txErrs: v[10], txDrop: v[11], txFifo: v[12],
+		}
 	}
 
-	return tx, rx, scanner.Err()
+	return ifaces, scanner.Err()
+}
+
+// readRealtimeCounters 读取各物理网卡计数器（端口计数由流量采集提供）
+func (c *Collector) readRealtimeCounters() map[string]ifaceCounters {
+	ifaces, _ := readProcNetDevIfaces()
+	return ifaces
+}
+
+// readNetSNMP 读取 /proc/net/snmp 与 /proc/net/netstat 的协议栈计数器
+func (c *Collector) readNetSNMP() map[string]uint64 {
+	var content strings.Builder
+	for _, path := range []string{"/proc/net/snmp", "/proc/net/netstat"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		content.Write(data)
+	}
+	if content.Len() == 0 {
+		return nil
+	}
+	return parseSNMP(content.String())
 }
 
 // collectPortTraffic 采集端口流量（通过 iptables）
@@ -170,9 +232,11 @@ func (c *Collector) collectPortTraffic(now int64) {
 	counters, err := c.readIptablesPortsTraffic(ports)
 	if err != nil {
 		// iptables 规则可能不存在，静默失败
+		c.feedRealtimePorts(nil, time.Now())
 		return
 	}
 
+	realtimeCounts := make(map[int]ifaceCounters, len(ports))
 	for _, port := range ports {
 		stats, ok := counters[port]
 		if !ok || !stats.txOK || !stats.rxOK {
@@ -180,6 +244,7 @@ func (c *Collector) collectPortTraffic(now int64) {
 		}
 		tx := stats.tx
 		rx := stats.rx
+		realtimeCounts[port] = ifaceCounters{tx: tx, rx: rx}
 
 		// 检测计数器重置
 		lastTx := c.lastPortTx[port]
@@ -208,6 +273,7 @@ func (c *Collector) collectPortTraffic(now int64) {
 		c.lastPortTx[port] = tx
 		c.lastPortRx[port] = rx
 	}
+	c.feedRealtimePorts(realtimeCounts, time.Now())
 }
 
 type portCounters struct {
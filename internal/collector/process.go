@@ -0,0 +1,204 @@
+package collector
+
+import (
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// clockTicks USER_HZ，Linux 上几乎总是 100
+	clockTicks = 100
+	// processRetention 进程指标保留时长
+	processRetention = 7 * 24 * time.Hour
+)
+
+// procSnapshot 单个进程的一次采样（累计计数）
+type procSnapshot struct {
+	pid       int
+	name      string
+	cmdline   string
+	cgroup    string // /proc/<pid>/cgroup 原始路径
+	startTime uint64 // 开机后的时钟滴答数，用于识别 PID 复用
+	cpuTicks  uint64 // utime + stime
+	rss       uint64 // 字节
+	threads   int
+	fds       int
+
+	readBytes, writeBytes uint64 // 块设备 I/O
+	rchar, wchar          uint64 // 所有 read/write 系统调用（含网络）
+}
+
+// processMatcher 进程匹配规则：name=<进程名>、cmdline=<正则>、cgroup=<路径子串>
+type processMatcher struct {
+	kind    string
+	pattern string
+	re      *regexp.Regexp
+}
+
+// parseProcessMatchers 解析匹配规则，无效规则记录日志后忽略
+func parseProcessMatchers(rules []string) []processMatcher {
+	var matchers []processMatcher
+	for _, rule := range rules {
+		kind, pattern, ok := strings.Cut(rule, "=")
+		if !ok {
+			kind, pattern = "name", rule
+		}
+		m := processMatcher{kind: strings.TrimSpace(kind), pattern: strings.TrimSpace(pattern)}
+		switch m.kind {
+		case "name", "cgroup":
+		case "cmdline":
+			re, err := regexp.Compile(m.pattern)
+			if err != nil {
+				log.Printf("进程匹配规则无效 %q: %v", rule, err)
+				continue
+			}
+			m.re = re
+		default:
+			log.Printf("未知的进程匹配规则类型: %q", rule)
+			continue
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers
+}
+
+// match 判断进程是否命中规则
+func (m processMatcher) match(p *procSnapshot) bool {
+	switch m.kind {
+	case "name":
+		return p.name == m.pattern
+	case "cmdline":
+		return m.re.MatchString(p.cmdline)
+	case "cgroup":
+		return strings.Contains(p.cgroup, m.pattern)
+	}
+	return false
+}
+
+// procSample 计算后的进程指标
+type procSample struct {
+	procSnapshot
+	matched    string // 命中的规则，未命中（Top-N）为空
+	cpuPercent float64
+	readRate   float64 // 字节/秒
+	writeRate  float64
+	rcharRate  float64
+	wcharRate  float64
+}
+
+// collectProcesses 进程资源采集（每 1 分钟）
+func (c *Collector) collectProcesses() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	// 首次采样只建立基准
+	c.doCollectProcesses(time.Now())
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.doCollectProcesses(time.Now())
+		}
+	}
+}
+
+// doCollectProcesses 采样所有进程，保存命中规则的进程和 CPU 占用最高的 N 个其他进程
+func (c *Collector) doCollectProcesses(now time.Time) {
+	snapshots := c.readProcSnapshots()
+	prev, elapsed := c.lastProcs, now.Sub(c.lastProcsTime).Seconds()
+
+	c.lastProcs = make(map[int]procSnapshot, len(snapshots))
+	for _, s := range snapshots {
+		c.lastProcs[s.pid] = s
+	}
+	c.lastProcsTime = now
+	if prev == nil || elapsed <= 0 {
+		return
+	}
+
+	samples := selectProcesses(computeProcSamples(prev, snapshots, elapsed), c.processMatchers, c.cfg.ProcessTopN)
+
+	ts := now.Unix()
+	for _, s := range samples {
+		_, err := c.db.Exec(`
+			INSERT INTO process_metrics (ts, pid, name, cmdline, matched, cpu_percent, rss, threads, fds,
+			    read_bps, write_bps, rchar_bps, wchar_bps)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, ts, s.pid, s.name, truncate(s.cmdline, 200), s.matched, s.cpuPercent, s.rss, s.threads, s.fds,
+			s.readRate, s.writeRate, s.rcharRate, s.wcharRate)
+		if err != nil {
+			log.Printf("保存进程指标失败: %v", err)
+		}
+	}
+	_, _ = c.db.Exec("DELETE FROM process_metrics WHERE ts < ?", now.Add(-processRetention).Unix())
+}
+
+// computeProcSamples 根据两次采样计算 CPU 使用率和 I/O 速率（新进程或 PID 复用时跳过）
+func computeProcSamples(prev map[int]procSnapshot, cur []procSnapshot, elapsed float64) []procSample {
+	rate := func(a, b uint64) float64 {
+		if a < b {
+			return 0
+		}
+		return float64(a-b) / elapsed
+	}
+
+	samples := make([]procSample, 0, len(cur))
+	for _, s := range cur {
+		p, ok := prev[s.pid]
+		if !ok || p.startTime != s.startTime || s.cpuTicks < p.cpuTicks {
+			continue
+		}
+		samples = append(samples, procSample{
+			procSnapshot: s,
+			cpuPercent:   float64(s.cpuTicks-p.cpuTicks) / clockTicks / elapsed * 100,
+			readRate:     rate(s.readBytes, p.readBytes),
+			writeRate:    rate(s.writeBytes, p.writeBytes),
+			rcharRate:    rate(s.rchar, p.rchar),
+			wcharRate:    rate(s.wchar, p.wchar),
+		})
+	}
+	return samples
+}
+
+// selectProcesses 保留命中规则的进程，以及其余进程中 CPU 使用率最高的 topN 个
+func selectProcesses(samples []procSample, matchers []processMatcher, topN int) []procSample {
+	var matched, others []procSample
+	for _, s := range samples {
+		hit := ""
+		for _, m := range matchers {
+			if m.match(&s.procSnapshot) {
+				hit = m.kind + "=" + m.pattern
+				break
+			}
+		}
+		if hit != "" {
+			s.matched = hit
+			matched = append(matched, s)
+		} else if s.cpuPercent > 0 {
+			others = append(others, s)
+		}
+	}
+
+	sort.Slice(others, func(i, j int) bool { return others[i].cpuPercent > others[j].cpuPercent })
+	if len(others) > topN {
+		others = others[:topN]
+	}
+	return append(matched, others...)
+}
+
+// truncate 截断到最多 n 字节，不切断多字节字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
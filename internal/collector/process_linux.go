@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readProcSnapshots 读取所有进程的 /proc/<pid>/{stat,status,io,cmdline,cgroup,fd}
+func (c *Collector) readProcSnapshots() []procSnapshot {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	var snapshots []procSnapshot
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		s, ok := readProcSnapshot(pid, pageSize)
+		if !ok {
+			continue // 进程已退出
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots
+}
+
+// readProcSnapshot 读取单个进程
+func readProcSnapshot(pid int, pageSize uint64) (procSnapshot, bool) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	s := procSnapshot{pid: pid}
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return s, false
+	}
+	// pid (comm) state ppid ...，comm 可能包含空格和括号
+	open := strings.IndexByte(string(stat), '(')
+	closing := strings.LastIndexByte(string(stat), ')')
+	if open < 0 || closing < open {
+		return s, false
+	}
+	s.name = string(stat[open+1 : closing])
+	fields := strings.Fields(string(stat[closing+1:]))
+	if len(fields) < 22 {
+		return s, false
+	}
+	// fields[0] 为 state（第 3 个字段），utime 为第 14 个字段
+	parse := func(i int) uint64 {
+		v, _ := strconv.ParseUint(fields[i], 10, 64)
+		return v
+	}
+	s.cpuTicks = parse(11) + parse(12)
+	s.threads = int(parse(17))
+	s.startTime = parse(19)
+	s.rss = parse(21) * pageSize
+
+	// status 中的 VmRSS 更精确（stat 的 rss 在多线程进程上可能滞后）
+	if status, err := os.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			k, v, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			fields := strings.Fields(v)
+			if len(fields) == 0 {
+				continue
+			}
+			n, _ := strconv.ParseUint(fields[0], 10, 64)
+			switch k {
+			case "VmRSS":
+				s.rss = n * 1024
+			case "Threads":
+				s.threads = int(n)
+			}
+		}
+	}
+
+	// 内核线程没有 cmdline，也不需要统计
+	cmdline, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if len(cmdline) == 0 {
+		return s, false
+	}
+	s.cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+
+	if cg, err := os.ReadFile(filepath.Join(dir, "cgroup")); err == nil {
+		s.cgroup = strings.TrimSpace(string(cg))
+	}
+
+	// /proc/<pid>/io 需要 root 或同一用户
+	if io, err := os.ReadFile(filepath.Join(dir, "io")); err == nil {
+		for _, line := range strings.Split(string(io), "\n") {
+			k, v, ok := strings.Cut(line, ": ")
+			if !ok {
+				continue
+			}
+			n, _ := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			switch k {
+			case "rchar":
+				s.rchar = n
+			case "wchar":
+				s.wchar = n
+			case "read_bytes":
+				s.readBytes = n
+			case "write_bytes":
+				s.writeBytes = n
+			}
+		}
+	}
+
+	if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		s.fds = len(fds)
+	}
+	return s, true
+}
@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"math"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestSelectProcesses 测试进程匹配、CPU 计算与 Top-N 选取
+func TestSelectProcesses(t *testing.T) {
+	matchers := parseProcessMatchers([]string{
+		"name=sing-box",
+		"cmdline=snell-server .*-c",
+		"cgroup=xray.service",
+		"cmdline=[", // 无效正则，忽略
+		"bogus=x",   // 未知类型，忽略
+	})
+	if len(matchers) != 3 {
+		t.Fatalf("规则数 = %d, 期望 3", len(matchers))
+	}
+
+	prev := map[int]procSnapshot{
+		1: {pid: 1, name: "sing-box", startTime: 10, cpuTicks: 1000, rchar: 0},
+		2: {pid: 2, name: "snell", cmdline: "/usr/bin/snell-server -c /etc/snell.conf", startTime: 10, cpuTicks: 0},
+		3: {pid: 3, name: "xray", cgroup: "0::/system.slice/xray.service", startTime: 10},
+		4: {pid: 4, name: "busy", startTime: 10},
+		5: {pid: 5, name: "idle", startTime: 10},
+		6: {pid: 6, name: "reused", startTime: 10},
+		7: {pid: 7, name: "other", startTime: 10},
+	}
+	cur := []procSnapshot{
+		{pid: 1, name: "sing-box", startTime: 10, cpuTicks: 1600, rchar: 6000},
+		{pid: 2, name: "snell", cmdline: "/usr/bin/snell-server -c /etc/snell.conf", startTime: 10, cpuTicks: 60},
+		{pid: 3, name: "xray", cgroup: "0::/system.slice/xray.service", startTime: 10},
+		{pid: 4, name: "busy", startTime: 10, cpuTicks: 3000},
+		{pid: 5, name: "idle", startTime: 10},
+		{pid: 6, name: "reused", startTime: 99, cpuTicks: 5000}, // PID 复用，跳过
+		{pid: 7, name: "other", startTime: 10, cpuTicks: 120},
+		{pid: 8, name: "new", startTime: 50, cpuTicks: 9000}, // 新进程，没有基准
+	}
+
+	samples := selectProcesses(computeProcSamples(prev, cur, 60), matchers, 1)
+
+	want := map[string]float64{"sing-box": 10, "snell": 1, "xray": 0, "busy": 50}
+	if len(samples) != len(want) {
+		t.Fatalf("选取进程数 = %d, 期望 %d: %+v", len(samples), len(want), samples)
+	}
+	for _, s := range samples {
+		cpu, ok := want[s.name]
+		if !ok {
+			t.Errorf("不应选取进程 %s", s.name)
+			continue
+		}
+		if math.Abs(s.cpuPercent-cpu) > 1e-9 {
+			t.Errorf("%s CPU = %.2f%%, 期望 %.2f%%", s.name, s.cpuPercent, cpu)
+		}
+		if (s.matched == "") != (s.name == "busy") {
+			t.Errorf("%s matched = %q", s.name, s.matched)
+		}
+		if s.name == "sing-box" && s.rcharRate != 100 {
+			t.Errorf("sing-box rchar 速率 = %.2f, 期望 100", s.rcharRate)
+		}
+	}
+}
+
+// TestTruncate 测试命令行截断不切断多字节字符
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"sing-box run", 20, "sing-box run"},
+		{"sing-box run", 8, "sing-box"},
+		{"/opt/代理/bin", 7, "/opt/"},
+		{"/opt/代理/bin", 8, "/opt/代"},
+		{"/opt/代理/bin", 10, "/opt/代"},
+	} {
+		got := truncate(tc.s, tc.n)
+		if got != tc.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, 期望 %q", tc.s, tc.n, got, tc.want)
+		}
+	}
+}
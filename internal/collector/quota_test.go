@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+type quotaRecorder struct {
+	Notifier
+	cycleStart time.Time
+	resetDate  string
+}
+
+func (r *quotaRecorder) SendTrafficAlert(usedGB, limitGB int, percent float64, cycleStart time.Time, resetDate string, daysLeft int, threshold int) error {
+	r.cycleStart, r.resetDate = cycleStart, resetDate
+	return nil
+}
+
+// TestQuotaAlertCycle 测试流量报警携带的计费周期（31 天的月份与二月）
+func TestQuotaAlertCycle(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	cases := []struct {
+		now       time.Time
+		resetDay  int
+		wantStart string
+		wantReset string
+	}{
+		{time.Date(2025, 10, 31, 12, 0, 0, 0, time.UTC), 1, "2025-10-01", "2025-10-31"},
+		{time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), 1, "2026-02-01", "2026-02-28"},
+		{time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), 15, "2026-02-15", "2026-03-14"},
+	}
+	for _, tc := range cases {
+		db.Exec("DELETE FROM traffic_daily")
+		db.Exec("INSERT INTO traffic_daily (date, iface, tx_bytes, rx_bytes) VALUES (?, 'total', ?, 0)",
+			tc.now.Format("2006-01-02"), int64(900)<<30)
+
+		rec := &quotaRecorder{}
+		c := &Collector{db: db, notifier: rec, cfg: &config.Config{
+			Timezone:        time.UTC,
+			ResetDay:        tc.resetDay,
+			MonthlyLimitGB:  1000,
+			BillingMode:     "tx_only",
+			AlertThresholds: []int{80},
+		}}
+		c.checkQuotaAndNotify(tc.now)
+		if got := rec.cycleStart.Format("2006-01-02"); got != tc.wantStart || rec.resetDate != tc.wantReset {
+			t.Errorf("%s: cycleStart = %s, resetDate = %s", tc.now.Format("2006-01-02"), got, rec.resetDate)
+		}
+	}
+}
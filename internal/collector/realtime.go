@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/hh/heliox-mon/internal/realtime"
+)
+
+const (
+	// realtimeMinResolution 实时采样最小间隔
+	realtimeMinResolution = 250 * time.Millisecond
+	// realtimePortStale 端口计数由流量采集（每 1 秒）顺带提供，超过该时长未更新视为不可用
+	realtimePortStale = 3 * time.Second
+)
+
+// portReading 流量采集读到的端口计数器（counts 为 nil 表示读取失败）
+type portReading struct {
+	counts map[int]ifaceCounters
+	at     time.Time
+}
+
+// realtimeState 实时采样的上一次计数（仅在采样协程中访问）
+type realtimeState struct {
+	ifaces   map[string]ifaceCounters
+	ifacesAt time.Time
+	ports    map[int]ifaceCounters
+	portsAt  time.Time             // 所用端口计数的读取时间
+	portRate map[int]realtime.Rate // 端口计数更新之间沿用上一次速率
+}
+
+// Realtime 实时网速缓冲（供 SSE 推送订阅）
+func (c *Collector) Realtime() *realtime.Hub {
+	return c.realtime
+}
+
+// feedRealtimePorts 流量采集读取端口计数后交给实时采样，避免重复调用 iptables
+func (c *Collector) feedRealtimePorts(counts map[int]ifaceCounters, now time.Time) {
+	c.portFeedMu.Lock()
+	c.portFeed = portReading{counts: counts, at: now}
+	c.portFeedMu.Unlock()
+}
+
+func (c *Collector) latestPorts() portReading {
+	c.portFeedMu.Lock()
+	defer c.portFeedMu.Unlock()
+	return c.portFeed
+}
+
+// realtimeResolution 实时采样间隔（不低于 250ms）
+func (c *Collector) realtimeResolution() time.Duration {
+	if c.cfg.RealtimeResolution < realtimeMinResolution {
+		return realtimeMinResolution
+	}
+	return c.cfg.RealtimeResolution
+}
+
+// collectRealtime 按配置的间隔采样网卡与端口计数器，写入内存环形缓冲
+func (c *Collector) collectRealtime() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.realtimeResolution())
+	defer ticker.Stop()
+
+	var st realtimeState
+	c.sampleRealtime(&st, time.Now())
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			c.sampleRealtime(&st, now)
+		}
+	}
+}
+
+// sampleRealtime 计算一次速率并发布
+func (c *Collector) sampleRealtime(st *realtimeState, now time.Time) {
+	ifaces := c.readRealtimeCounters()
+
+	sample := realtime.Sample{
+		TS:     now,
+		Ifaces: make(map[string]realtime.Rate, len(ifaces)),
+		Ports:  make(map[int]realtime.Rate),
+	}
+
+	ready := !st.ifacesAt.IsZero()
+	if dt := now.Sub(st.ifacesAt).Seconds(); ready && dt > 0 {
+		for name, cur := range ifaces {
+			prev, ok := st.ifaces[name]
+			if !ok {
+				continue
+			}
+			r := realtime.Rate{
+				Tx: float64(counterDelta(cur.tx, prev.tx)) / dt,
+				Rx: float64(counterDelta(cur.rx, prev.rx)) / dt,
+			}
+			sample.Ifaces[name] = r
+			sample.Total.Tx += r.Tx
+			sample.Total.Rx += r.Rx
+		}
+	}
+	st.ifaces, st.ifacesAt = ifaces, now
+
+	feed := c.latestPorts()
+	switch {
+	case feed.at.IsZero() || now.Sub(feed.at) > realtimePortStale:
+		// 流量采集没有提供新计数，不再发布旧速率
+		st.ports, st.portsAt, st.portRate = nil, time.Time{}, nil
+	case !feed.at.Equal(st.portsAt):
+		// 读取失败时清空速率，下次成功读取后重新建立基准
+		st.portRate = nil
+		if dt := feed.at.Sub(st.portsAt).Seconds(); feed.counts != nil && st.ports != nil && dt > 0 {
+			st.portRate = make(map[int]realtime.Rate, len(feed.counts))
+			for port, cur := range feed.counts {
+				if prev, ok := st.ports[port]; ok {
+					st.portRate[port] = realtime.Rate{
+						Tx: float64(counterDelta(cur.tx, prev.tx)) / dt,
+						Rx: float64(counterDelta(cur.rx, prev.rx)) / dt,
+					}
+				}
+			}
+		}
+		st.ports, st.portsAt = feed.counts, feed.at
+	}
+	for port, r := range st.portRate {
+		sample.Ports[port] = r
+	}
+
+	if ready {
+		c.realtime.Publish(sample)
+	}
+}
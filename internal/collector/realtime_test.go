@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/realtime"
+)
+
+// TestRealtimePorts 测试端口速率来自流量采集的计数：读取失败或停止更新后不再发布旧速率
+func TestRealtimePorts(t *testing.T) {
+	c := &Collector{cfg: &config.Config{}, realtime: realtime.NewHub(16)}
+	var st realtimeState
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	at := func(sec float64) time.Time { return start.Add(time.Duration(sec * float64(time.Second))) }
+	port := func(now time.Time) (realtime.Rate, bool) {
+		c.sampleRealtime(&st, now)
+		s, _ := c.realtime.Latest()
+		r, ok := s.Ports[443]
+		return r, ok
+	}
+
+	c.sampleRealtime(&st, at(0))
+	c.feedRealtimePorts(map[int]ifaceCounters{443: {tx: 1000, rx: 2000}}, at(0))
+	if _, ok := port(at(0.25)); ok {
+		t.Error("只有一次端口计数时不应有速率")
+	}
+	c.feedRealtimePorts(map[int]ifaceCounters{443: {tx: 3000, rx: 6000}}, at(1))
+	if r, ok := port(at(1.25)); !ok || r.Tx != 2000 || r.Rx != 4000 {
+		t.Errorf("端口速率 = %+v", r)
+	}
+	// 计数更新之间沿用上一次速率
+	if r, ok := port(at(1.5)); !ok || r.Tx != 2000 {
+		t.Errorf("应沿用上一次速率: %+v", r)
+	}
+
+	// 读取失败后清空速率，恢复后重新建立基准
+	c.feedRealtimePorts(nil, at(2))
+	if r, ok := port(at(2.25)); ok {
+		t.Errorf("读取失败后不应发布旧速率: %+v", r)
+	}
+	c.feedRealtimePorts(map[int]ifaceCounters{443: {tx: 5000, rx: 8000}}, at(3))
+	if _, ok := port(at(3.25)); ok {
+		t.Error("失败后第一次读取只建立基准")
+	}
+	c.feedRealtimePorts(map[int]ifaceCounters{443: {tx: 6000, rx: 9000}}, at(4))
+	if r, ok := port(at(4.25)); !ok || r.Tx != 1000 {
+		t.Errorf("恢复后的端口速率 = %+v", r)
+	}
+
+	// 流量采集停止更新后不再发布
+	if r, ok := port(at(4 + realtimePortStale.Seconds() + 1)); ok {
+		t.Errorf("端口计数过期后不应发布旧速率: %+v", r)
+	}
+}
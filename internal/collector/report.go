@@ -0,0 +1,256 @@
+package collector
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/notifier"
+)
+
+// reportSchedule 报告发送时间
+type reportSchedule struct {
+	weekday time.Weekday // 仅周报使用
+	hour    int
+	minute  int
+}
+
+// parseReportSchedule 解析报告时间（格式: HH:MM 或 Mon HH:MM）
+func parseReportSchedule(s string, weekly bool) (reportSchedule, error) {
+	var sched reportSchedule
+	fields := strings.Fields(s)
+	clock := s
+	if weekly {
+		if len(fields) != 2 {
+			return sched, fmt.Errorf("周报时间格式应为 'Mon 09:00': %q", s)
+		}
+		wd, ok := parseWeekday(fields[0])
+		if !ok {
+			return sched, fmt.Errorf("无效的星期: %q", fields[0])
+		}
+		sched.weekday = wd
+		clock = fields[1]
+	}
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return sched, fmt.Errorf("无效的时间: %q", clock)
+	}
+	sched.hour, sched.minute = t.Hour(), t.Minute()
+	return sched, nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(s)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// due 判断当天是否已到发送时间
+func (s reportSchedule) due(now time.Time) bool {
+	return now.Hour() > s.hour || (now.Hour() == s.hour && now.Minute() >= s.minute)
+}
+
+// aggregateSystemDaily 记录当日 CPU / 负载峰值（system_metrics 只保留最近 1 小时）
+func (c *Collector) aggregateSystemDaily(date string) {
+	startTs, endTs, ok := c.dayBounds(date)
+	if !ok {
+		return
+	}
+
+	var cpuMax, loadMax sql.NullFloat64
+	row := c.db.QueryRow(`
+		SELECT MAX(cpu_percent), MAX(load_1)
+		FROM system_metrics
+		WHERE ts >= ? AND ts <= ?
+	`, startTs, endTs)
+	if err := row.Scan(&cpuMax, &loadMax); err != nil || !cpuMax.Valid {
+		return
+	}
+
+	_, _ = c.db.Exec(`
+		INSERT INTO system_daily (date, cpu_max, load_max)
+		VALUES (?, ?, ?)
+		ON CONFLICT(date) DO UPDATE SET
+			cpu_max = MAX(cpu_max, excluded.cpu_max),
+			load_max = MAX(load_max, excluded.load_max)
+	`, date, cpuMax.Float64, loadMax.Float64)
+}
+
+// checkScheduledReports 检查并发送定时报告
+func (c *Collector) checkScheduledReports(now time.Time) {
+	if c.notifier == nil {
+		return
+	}
+	// 发送时间与日期边界都按配置时区计算
+	now = now.In(c.cfg.Timezone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.cfg.Timezone)
+
+	if c.cfg.ReportDaily != "" {
+		sched, err := parseReportSchedule(c.cfg.ReportDaily, false)
+		if err != nil {
+			log.Printf("REPORT_DAILY 配置无效: %v", err)
+		} else if sched.due(now) {
+			yesterday := today.AddDate(0, 0, -1)
+			c.sendReportOnce("daily", today.Format("2006-01-02"), func() *notifier.Report {
+				return c.buildReport("daily", "📊 每日报告", yesterday, yesterday, now)
+			})
+		}
+	}
+
+	if c.cfg.ReportWeekly != "" {
+		sched, err := parseReportSchedule(c.cfg.ReportWeekly, true)
+		if err != nil {
+			log.Printf("REPORT_WEEKLY 配置无效: %v", err)
+		} else if now.Weekday() == sched.weekday && sched.due(now) {
+			c.sendReportOnce("weekly", today.Format("2006-01-02"), func() *notifier.Report {
+				return c.buildReport("weekly", "📈 每周报告", today.AddDate(0, 0, -7), today.AddDate(0, 0, -1), now)
+			})
+		}
+	}
+
+	if c.cfg.ReportCycle {
+		c.sendCycleReport(now)
+	}
+}
+
+// sendCycleReport 发送最近一个已归档周期的报告（仅限重置后一天内，避免补发历史周期）
+func (c *Collector) sendCycleReport(now time.Time) {
+	var startDate, endDate string
+	var finalizedAt int64
+	row := c.db.QueryRow("SELECT start_date, end_date, finalized_at FROM billing_cycles ORDER BY start_date DESC LIMIT 1")
+	if err := row.Scan(&startDate, &endDate, &finalizedAt); err != nil {
+		return
+	}
+	if now.Unix()-finalizedAt > 86400 {
+		return
+	}
+
+	start, err1 := time.ParseInLocation("2006-01-02", startDate, c.cfg.Timezone)
+	end, err2 := time.ParseInLocation("2006-01-02", endDate, c.cfg.Timezone)
+	if err1 != nil || err2 != nil {
+		return
+	}
+
+	c.sendReportOnce("cycle", startDate, func() *notifier.Report {
+		r := c.buildReport("cycle", "🧾 计费周期报告", start, end, now)
+		// 使用归档值，而非按当前配置重新计算
+		var billed int64
+		var limitGB int
+		var mode string
+		row := c.db.QueryRow("SELECT billed_bytes, limit_gb, billing_mode FROM billing_cycles WHERE start_date = ?", startDate)
+		if err := row.Scan(&billed, &limitGB, &mode); err == nil {
+			r.CycleStart, r.CycleEnd = startDate, endDate
+			r.CycleUsed, r.CycleLimitGB, r.BillingMode = billed, limitGB, mode
+			if limitGB > 0 {
+				r.CyclePercent = float64(billed) / float64(int64(limitGB)*1024*1024*1024) * 100
+			}
+		}
+		return r
+	})
+}
+
+// sendReportOnce 同一类型、同一周期的报告只发送一次
+func (c *Collector) sendReportOnce(kind, period string, build func() *notifier.Report) {
+	var count int
+	c.db.QueryRow("SELECT COUNT(*) FROM report_records WHERE kind = ? AND period = ?", kind, period).Scan(&count)
+	if count > 0 {
+		return
+	}
+
+	if err := c.notifier.SendReport(build()); err != nil {
+		log.Printf("发送%s报告失败: %v", kind, err)
+		return
+	}
+
+	_, _ = c.db.Exec("INSERT OR IGNORE INTO report_records (kind, period, ts) VALUES (?, ?, ?)", kind, period, time.Now().Unix())
+}
+
+// buildReport 汇总 [from, to] 日期范围内的报告数据
+func (c *Collector) buildReport(kind, title string, from, to, now time.Time) *notifier.Report {
+	tz := c.cfg.Timezone
+	fromDate := from.Format("2006-01-02")
+	toDate := to.Format("2006-01-02")
+
+	r := &notifier.Report{
+		Kind:        kind,
+		Title:       title,
+		ServerName:  c.cfg.ServerName,
+		PeriodStart: fromDate,
+		PeriodEnd:   toDate,
+		GeneratedAt: now.Format("2006-01-02 15:04 MST"),
+		BillingMode: c.cfg.BillingMode,
+	}
+
+	// 区间流量
+	c.db.QueryRow(`
+		SELECT COALESCE(SUM(tx_bytes), 0), COALESCE(SUM(rx_bytes), 0)
+		FROM traffic_daily
+		WHERE iface = 'total' AND date >= ? AND date <= ?
+	`, fromDate, toDate).Scan(&r.Tx, &r.Rx)
+
+	// 端口流量
+	for _, p := range []struct {
+		port int
+		name string
+	}{{c.cfg.SnellPort, "Snell"}, {c.cfg.VlessPort, "VLESS"}} {
+		if p.port == 0 {
+			continue
+		}
+		pu := notifier.PortUsage{Port: p.port, Name: p.name}
+		c.db.QueryRow(`
+			SELECT COALESCE(SUM(tx_bytes), 0), COALESCE(SUM(rx_bytes), 0)
+			FROM port_traffic_daily
+			WHERE port = ? AND date >= ? AND date <= ?
+		`, p.port, fromDate, toDate).Scan(&pu.Tx, &pu.Rx)
+		r.Ports = append(r.Ports, pu)
+	}
+
+	// 当前计费周期用量
+	cycleStart, cycleEnd := c.getBillingCycleDates(now)
+	var cycleTx, cycleRx int64
+	c.db.QueryRow(`
+		SELECT COALESCE(SUM(tx_bytes), 0), COALESCE(SUM(rx_bytes), 0)
+		FROM traffic_daily
+		WHERE iface = 'total' AND date >= ?
+	`, cycleStart.Format("2006-01-02")).Scan(&cycleTx, &cycleRx)
+	r.CycleStart = cycleStart.Format("2006-01-02")
+	r.CycleEnd = cycleEnd.Format("2006-01-02")
+	r.CycleUsed = config.BilledBytes(c.cfg.BillingMode, cycleTx, cycleRx)
+	r.CycleLimitGB = c.cfg.MonthlyLimitGB
+	if r.CycleLimitGB > 0 {
+		r.CyclePercent = float64(r.CycleUsed) / float64(int64(r.CycleLimitGB)*1024*1024*1024) * 100
+	}
+
+	// 延迟与丢包
+	startTs := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, tz).Unix()
+	endTs := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, tz).AddDate(0, 0, 1).Unix() - 1
+	for _, pt := range c.cfg.PingTargets {
+		var avg sql.NullFloat64
+		var sent, lost int64
+		c.db.QueryRow(`
+			SELECT AVG(rtt_ms), COALESCE(SUM(sent), 0), COALESCE(SUM(lost), 0)
+			FROM latency_records
+			WHERE target = ? AND ts >= ? AND ts <= ?
+		`, pt.Tag, startTs, endTs).Scan(&avg, &sent, &lost)
+		ls := notifier.LatencySummary{Tag: pt.Tag, AvgMs: avg.Float64, HasRTT: avg.Valid}
+		if sent > 0 {
+			ls.LossPercent = float64(lost) / float64(sent) * 100
+		}
+		r.Latency = append(r.Latency, ls)
+	}
+
+	// CPU 峰值
+	var peak sql.NullFloat64
+	c.db.QueryRow("SELECT MAX(cpu_max) FROM system_daily WHERE date >= ? AND date <= ?", fromDate, toDate).Scan(&peak)
+	r.PeakCPU = peak.Float64
+
+	return r
+}
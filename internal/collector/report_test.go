@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/notifier"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+func TestParseReportSchedule(t *testing.T) {
+	cases := []struct {
+		in      string
+		weekly  bool
+		want    reportSchedule
+		wantErr bool
+	}{
+		{"09:00", false, reportSchedule{hour: 9}, false},
+		{"23:59", false, reportSchedule{hour: 23, minute: 59}, false},
+		{"Mon 09:30", true, reportSchedule{weekday: time.Monday, hour: 9, minute: 30}, false},
+		{"sunday 18:00", true, reportSchedule{weekday: time.Sunday, hour: 18}, false},
+		{"25:00", false, reportSchedule{}, true},
+		{"9am", false, reportSchedule{}, true},
+		{"09:00", true, reportSchedule{}, true},     // 周报缺少星期
+		{"Xyz 09:00", true, reportSchedule{}, true}, // 无效星期
+		{"Mon 09:00 UTC", true, reportSchedule{}, true},
+	}
+	for _, tc := range cases {
+		got, err := parseReportSchedule(tc.in, tc.weekly)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseReportSchedule(%q, %v) err = %v", tc.in, tc.weekly, err)
+			continue
+		}
+		if !tc.wantErr && got != tc.want {
+			t.Errorf("parseReportSchedule(%q, %v) = %+v, want %+v", tc.in, tc.weekly, got, tc.want)
+		}
+	}
+}
+
+func TestReportScheduleDue(t *testing.T) {
+	sched := reportSchedule{hour: 9, minute: 30}
+	cases := []struct {
+		hour, minute int
+		want         bool
+	}{
+		{0, 0, false},
+		{9, 29, false},
+		{9, 30, true},
+		{9, 31, true},
+		{10, 0, true},
+		{23, 59, true},
+	}
+	for _, tc := range cases {
+		now := time.Date(2026, 3, 2, tc.hour, tc.minute, 0, 0, time.UTC)
+		if got := sched.due(now); got != tc.want {
+			t.Errorf("due(%02d:%02d) = %v, want %v", tc.hour, tc.minute, got, tc.want)
+		}
+	}
+}
+
+type reportRecorder struct {
+	Notifier
+	reports []*notifier.Report
+	fail    bool
+}
+
+func (r *reportRecorder) SendReport(rep *notifier.Report) error {
+	if r.fail {
+		return errors.New("send failed")
+	}
+	r.reports = append(r.reports, rep)
+	return nil
+}
+
+// TestScheduledReports 测试按配置时区判断发送时间与日期边界，同一周期只发送一次，发送失败下次重试
+func TestScheduledReports(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	cst := time.FixedZone("CST", 8*3600)
+	rec := &reportRecorder{}
+	c := &Collector{db: db, notifier: rec, cfg: &config.Config{
+		Timezone:     cst,
+		ResetDay:     1,
+		ReportDaily:  "09:00",
+		ReportWeekly: "Mon 09:00",
+	}}
+	utc := func(day, hour, minute int) time.Time { return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC) }
+
+	steps := []struct {
+		name string
+		now  time.Time
+		fail bool
+		sent []string // kind:PeriodStart~PeriodEnd
+	}{
+		{"周一 08:30 CST 未到时间", utc(2, 0, 30), false, nil},
+		{"发送失败不记录", utc(2, 1, 0), true, nil},
+		{"周一 09:05 CST 发送日报与周报", utc(2, 1, 5), false, []string{"daily:2026-03-01~2026-03-01", "weekly:2026-02-23~2026-03-01"}},
+		{"同一天不重复发送", utc(2, 10, 0), false, nil},
+		{"UTC 仍是周一但 CST 已是周二 00:30", utc(2, 16, 30), false, nil},
+		{"周二 09:00 CST 只发日报", utc(3, 1, 0), false, []string{"daily:2026-03-02~2026-03-02"}},
+	}
+	for _, step := range steps {
+		rec.reports, rec.fail = nil, step.fail
+		c.checkScheduledReports(step.now)
+		var got []string
+		for _, r := range rec.reports {
+			got = append(got, r.Kind+":"+r.PeriodStart+"~"+r.PeriodEnd)
+		}
+		if len(got) != len(step.sent) {
+			t.Errorf("%s: 发送 %v, want %v", step.name, got, step.sent)
+			continue
+		}
+		for i := range got {
+			if got[i] != step.sent[i] {
+				t.Errorf("%s: 发送 %v, want %v", step.name, got, step.sent)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// rollupTier 系统指标汇总层级
+type rollupTier struct {
+	seconds   int64
+	retention time.Duration
+}
+
+// rollupMetrics 参与汇总的 system_metrics 列（每列生成 <列>_avg 和 <列>_max）
+var rollupMetrics = []string{
+	"cpu_percent", "cpu_iowait", "cpu_steal", "cpu_softirq",
+	"mem_used", "swap_used",
+	"load_1",
+	"psi_cpu_some_avg10", "psi_mem_some_avg10", "psi_io_some_avg10",
+}
+
+// rollupGauges 只取最大值的列（容量类）
+var rollupGauges = []string{"mem_total", "disk_used", "disk_total"}
+
+// rollupTiers 1 分钟 / 15 分钟 / 1 小时，后一级由前一级汇总
+func (c *Collector) rollupTiers() []rollupTier {
+	return []rollupTier{
+		{seconds: 60, retention: time.Duration(c.cfg.SystemRetention1mDays) * 24 * time.Hour},
+		{seconds: 900, retention: time.Duration(c.cfg.SystemRetention15mDays) * 24 * time.Hour},
+		{seconds: 3600, retention: time.Duration(c.cfg.SystemRetention1hDays) * 24 * time.Hour},
+	}
+}
+
+// rollupSystemMetrics 将已结束的时间桶汇总到各层级并清理过期数据
+func (c *Collector) rollupSystemMetrics(now time.Time) {
+	var prev int64 // 上一层级（0 表示原始数据）
+	for _, tier := range c.rollupTiers() {
+		if err := c.rollupTier(tier.seconds, prev, now); err != nil {
+			log.Printf("系统指标汇总失败 (%ds): %v", tier.seconds, err)
+			return
+		}
+		if tier.retention > 0 {
+			_, _ = c.db.Exec("DELETE FROM system_metrics_rollup WHERE tier = ? AND ts < ?",
+				tier.seconds, now.Add(-tier.retention).Unix())
+		}
+		prev = tier.seconds
+	}
+}
+
+// rollupTier 汇总一个层级：从上次汇总的位置开始，到当前未结束的时间桶为止
+func (c *Collector) rollupTier(tier, source int64, now time.Time) error {
+	end := now.Unix() / tier * tier
+
+	var last int64
+	c.db.QueryRow("SELECT COALESCE(MAX(ts), 0) FROM system_metrics_rollup WHERE tier = ?", tier).Scan(&last)
+	start := int64(0)
+	if last > 0 {
+		start = last + tier
+	}
+	if start >= end {
+		return nil
+	}
+
+	cols := []string{"tier", "ts", "samples"}
+	var exprs []string
+	var from string
+	var args []interface{}
+	if source == 0 {
+		exprs = []string{"?", "(ts / ?) * ?", "COUNT(*)"}
+		args = []interface{}{tier, tier, tier}
+		for _, m := range rollupMetrics {
+			cols = append(cols, m+"_avg", m+"_max")
+			exprs = append(exprs, fmt.Sprintf("AVG(%s)", m), fmt.Sprintf("MAX(%s)", m))
+		}
+		for _, g := range rollupGauges {
+			cols = append(cols, g+"_max")
+			exprs = append(exprs, fmt.Sprintf("MAX(%s)", g))
+		}
+		from = "system_metrics WHERE ts >= ? AND ts < ?"
+		args = append(args, start, end)
+	} else {
+		// 由下一级汇总：均值按样本数加权
+		exprs = []string{"?", "(ts / ?) * ?", "SUM(samples)"}
+		args = []interface{}{tier, tier, tier}
+		for _, m := range rollupMetrics {
+			cols = append(cols, m+"_avg", m+"_max")
+			exprs = append(exprs,
+				fmt.Sprintf("SUM(%s_avg * samples) / SUM(CASE WHEN %s_avg IS NULL THEN 0 ELSE samples END)", m, m),
+				fmt.Sprintf("MAX(%s_max)", m))
+		}
+		for _, g := range rollupGauges {
+			cols = append(cols, g+"_max")
+			exprs = append(exprs, fmt.Sprintf("MAX(%s_max)", g))
+		}
+		from = "system_metrics_rollup WHERE tier = ? AND ts >= ? AND ts < ?"
+		args = append(args, source, start, end)
+	}
+
+	query := fmt.Sprintf(
+		"INSERT OR REPLACE INTO system_metrics_rollup (%s) SELECT %s FROM %s GROUP BY ts / %d",
+		strings.Join(cols, ", "), strings.Join(exprs, ", "), from, tier,
+	)
+	_, err := c.db.Exec(query, args...)
+	return err
+}
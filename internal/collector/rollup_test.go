@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// TestRollupSystemMetrics 测试系统指标逐级汇总
+func TestRollupSystemMetrics(t *testing.T) {
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	c := &Collector{db: db, cfg: &config.Config{
+		Timezone:               time.UTC,
+		SystemRetention1mDays:  2,
+		SystemRetention15mDays: 31,
+		SystemRetention1hDays:  365,
+	}}
+
+	// 两个完整小时的 5 秒采样：第一个小时 CPU 10%，第二个小时 CPU 30%，其中一个采样峰值 90%
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC).Unix()
+	for ts := base; ts < base+7200; ts += 5 {
+		cpu := 10.0
+		if ts >= base+3600 {
+			cpu = 30.0
+		}
+		if ts == base+4000 {
+			cpu = 90.0
+		}
+		if _, err := db.Exec("INSERT INTO system_metrics (ts, cpu_percent, mem_used, mem_total, disk_used, disk_total, load_1, load_5, load_15) VALUES (?, ?, 100, 1000, 5, 50, 0.5, 0, 0)", ts, cpu); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Unix(base+7200+30, 0)
+	c.rollupSystemMetrics(now)
+	// 重复执行不应产生重复数据
+	c.rollupSystemMetrics(now)
+
+	for _, tt := range []struct {
+		tier  int64
+		count int
+	}{{60, 120}, {900, 8}, {3600, 2}} {
+		var n int
+		db.QueryRow("SELECT COUNT(*) FROM system_metrics_rollup WHERE tier = ?", tt.tier).Scan(&n)
+		if n != tt.count {
+			t.Errorf("tier %d: %d 行, 期望 %d", tt.tier, n, tt.count)
+		}
+	}
+
+	var samples int
+	var avg, max float64
+	db.QueryRow("SELECT samples, cpu_percent_avg, cpu_percent_max FROM system_metrics_rollup WHERE tier = 3600 AND ts = ?", base+3600).
+		Scan(&samples, &avg, &max)
+	wantAvg := (30.0*719 + 90.0) / 720
+	if samples != 720 || math.Abs(avg-wantAvg) > 1e-6 || max != 90 {
+		t.Errorf("第二小时: samples=%d avg=%.4f max=%.1f, 期望 720 / %.4f / 90", samples, avg, max, wantAvg)
+	}
+}
@@ -0,0 +1,238 @@
+package collector
+
+import (
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/notifier"
+)
+
+const (
+	// serviceCheckInterval 服务健康检查间隔
+	serviceCheckInterval = 30 * time.Second
+	// serviceFailThreshold 连续失败次数达到该值才判定为宕机，避免重启瞬间误报
+	serviceFailThreshold = 2
+	// probeTimeout 协议探测超时
+	probeTimeout = 5 * time.Second
+)
+
+// 服务状态与事件
+const (
+	serviceUp      = "up"
+	serviceDown    = "down"
+	serviceRestart = "restart"
+)
+
+// processInfo 套接字所属进程
+type processInfo struct {
+	pid       int
+	name      string
+	startedAt int64  // 进程启动时间（Unix 秒）
+	unit      string // systemd 单元或容器 ID
+}
+
+// portStatus 端口检查结果
+type portStatus struct {
+	tcpListen  bool
+	udpBound   bool
+	listenAddr net.IP
+	process    *processInfo // nil 表示无法定位进程
+}
+
+// serviceTarget 被检查的代理服务
+type serviceTarget struct {
+	port  int
+	name  string
+	probe string // tcp / tls
+}
+
+// serviceTargets 返回需要检查的服务
+func (c *Collector) serviceTargets() []serviceTarget {
+	var targets []serviceTarget
+	if c.cfg.SnellPort > 0 {
+		// Snell 流量经过混淆且需要 PSK，只做 TCP 建连探测
+		targets = append(targets, serviceTarget{port: c.cfg.SnellPort, name: "Snell", probe: "tcp"})
+	}
+	if c.cfg.VlessPort > 0 {
+		// VLESS (TLS / Reality) 完成 TLS 握手即视为可用
+		targets = append(targets, serviceTarget{port: c.cfg.VlessPort, name: "VLESS", probe: "tls"})
+	}
+	return targets
+}
+
+// runServiceChecks 代理服务健康检查（每 30 秒）
+func (c *Collector) runServiceChecks() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(serviceCheckInterval)
+	defer ticker.Stop()
+
+	c.checkServices(time.Now())
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.checkServices(time.Now())
+		}
+	}
+}
+
+// checkServices 检查所有服务
+func (c *Collector) checkServices(now time.Time) {
+	for _, t := range c.serviceTargets() {
+		st := c.inspectPort(t.port)
+
+		healthy, reason := true, ""
+		if !st.tcpListen {
+			healthy, reason = false, fmt.Sprintf("端口 %d 无 TCP 监听", t.port)
+		} else if c.cfg.ServiceProbe {
+			if err := probeService(t, st.listenAddr, c.cfg.ServiceProbeSNI); err != nil {
+				healthy, reason = false, fmt.Sprintf("%s 探测失败: %v", t.probe, err)
+			}
+		}
+
+		c.updateServiceStatus(t, st, healthy, reason, now)
+	}
+}
+
+// probeService 协议层探测：建立 TCP 连接，tls 模式下额外完成 TLS 握手
+func probeService(t serviceTarget, listenAddr net.IP, sni string) error {
+	host := "127.0.0.1"
+	if listenAddr != nil && !listenAddr.IsUnspecified() {
+		host = listenAddr.String()
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(t.port))
+
+	conn, err := net.DialTimeout("tcp", addr, probeTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if t.probe != "tls" {
+		return nil
+	}
+	conn.SetDeadline(time.Now().Add(probeTimeout))
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         sni,
+		InsecureSkipVerify: true, // 只验证服务能完成握手，不校验证书
+	})
+	return tlsConn.Handshake()
+}
+
+// updateServiceStatus 更新服务状态，状态变化或进程重启时记录事件并通知
+func (c *Collector) updateServiceStatus(t serviceTarget, st portStatus, healthy bool, reason string, now time.Time) {
+	var prevState string
+	var since, prevStartedAt int64
+	var restarts int
+	err := c.db.QueryRow(
+		"SELECT state, since, COALESCE(started_at, 0), restarts FROM service_status WHERE port = ?", t.port,
+	).Scan(&prevState, &since, &prevStartedAt, &restarts)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("读取服务状态失败: %v", err)
+		return
+	}
+
+	// 连续失败达到阈值才判定宕机
+	state := serviceUp
+	if healthy {
+		c.serviceFails[t.port] = 0
+	} else {
+		c.serviceFails[t.port]++
+		state = serviceDown
+		if prevState == serviceUp && c.serviceFails[t.port] < serviceFailThreshold {
+			state = serviceUp
+		}
+	}
+
+	var pid, startedAt int64
+	var process, unit string
+	if st.process != nil {
+		pid, startedAt = int64(st.process.pid), st.process.startedAt
+		process, unit = st.process.name, st.process.unit
+	}
+
+	// 进程启动时间变化视为重启
+	restarted := startedAt > 0 && prevStartedAt > 0 && startedAt != prevStartedAt
+	if restarted {
+		restarts++
+	}
+	if startedAt == 0 {
+		startedAt = prevStartedAt // 宕机期间保留最后一次的启动时间
+	}
+
+	prevSince := since
+	transition := state != prevState
+	if transition {
+		since = now.Unix()
+	}
+
+	_, err = c.db.Exec(`
+		INSERT INTO service_status (port, name, state, since, last_check, pid, process, unit, started_at, restarts, tcp_listen, udp_bound, last_error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(port) DO UPDATE SET
+			name = excluded.name, state = excluded.state, since = excluded.since,
+			last_check = excluded.last_check, pid = excluded.pid, process = excluded.process,
+			unit = excluded.unit, started_at = excluded.started_at, restarts = excluded.restarts,
+			tcp_listen = excluded.tcp_listen, udp_bound = excluded.udp_bound, last_error = excluded.last_error
+	`, t.port, t.name, state, since, now.Unix(), pid, process, unit, startedAt, restarts,
+		st.tcpListen, st.udpBound, reason)
+	if err != nil {
+		log.Printf("保存服务状态失败: %v", err)
+		return
+	}
+
+	switch {
+	case transition && prevState == "" && state == serviceUp:
+		// 首次检查且正常时不记录事件
+	case transition:
+		var downtime time.Duration
+		if state == serviceUp {
+			downtime = now.Sub(time.Unix(prevSince, 0))
+		}
+		c.recordServiceEvent(t, state, reason, st.process, downtime, now)
+	case restarted && state == serviceUp:
+		detail := fmt.Sprintf("进程启动于 %s", time.Unix(startedAt, 0).In(c.cfg.Timezone).Format("2006-01-02 15:04:05"))
+		c.recordServiceEvent(t, serviceRestart, detail, st.process, 0, now)
+	}
+}
+
+// recordServiceEvent 记录服务事件并发送通知
+func (c *Collector) recordServiceEvent(t serviceTarget, event, detail string, proc *processInfo, downtime time.Duration, now time.Time) {
+	res, err := c.db.Exec(
+		"INSERT INTO service_events (ts, port, name, event, detail) VALUES (?, ?, ?, ?, ?)",
+		now.Unix(), t.port, t.name, event, detail,
+	)
+	if err != nil {
+		log.Printf("保存服务事件失败: %v", err)
+		return
+	}
+	id, _ := res.LastInsertId()
+	log.Printf("服务 %s (端口 %d) %s %s", t.name, t.port, event, detail)
+
+	if c.notifier == nil {
+		return
+	}
+	alert := &notifier.ServiceAlert{
+		ID:       id,
+		Name:     t.name,
+		Port:     t.port,
+		Event:    event,
+		Detail:   detail,
+		Downtime: downtime,
+		Time:     now,
+	}
+	if proc != nil {
+		alert.Process = proc.name
+		alert.PID = proc.pid
+		alert.Unit = proc.unit
+	}
+	if err := c.notifier.SendServiceAlert(alert); err != nil {
+		log.Printf("发送服务报警失败: %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package collector
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TCP 连接状态（include/net/tcp_states.h）
+const (
+	tcpEstablished = 0x01
+	tcpListen      = 0x0A
+	udpUnconnected = 0x07 // UDP 未 connect 的套接字复用 TCP_CLOSE
+)
+
+// socketEntry /proc/net/{tcp,udp}[6] 中的一条套接字记录
+type socketEntry struct {
+	proto      string // tcp / tcp6 / udp / udp6
+	localIP    net.IP
+	localPort  int
+	remoteIP   net.IP
+	remotePort int
+	state      int
+	inode      uint64
+}
+
+// readSockets 读取指定协议的套接字表（proto 为 tcp / tcp6 / udp / udp6）
+func readSockets(proto string) ([]socketEntry, error) {
+	file, err := os.Open("/proc/net/" + proto)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []socketEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // 跳过表头
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		localIP, localPort, err := parseSocketAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		remoteIP, remotePort, err := parseSocketAddr(fields[2])
+		if err != nil {
+			continue
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			continue
+		}
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+
+		entries = append(entries, socketEntry{
+			proto:      proto,
+			localIP:    localIP,
+			localPort:  localPort,
+			remoteIP:   remoteIP,
+			remotePort: remotePort,
+			state:      int(state),
+			inode:      inode,
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// readAllSockets 读取 tcp / tcp6 / udp / udp6 全部套接字（不存在的表忽略，如禁用 IPv6）
+func readAllSockets() []socketEntry {
+	var all []socketEntry
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		entries, err := readSockets(proto)
+		if err != nil {
+			continue
+		}
+		all = append(all, entries...)
+	}
+	return all
+}
+
+// parseSocketAddr 解析 "0100007F:1F90" 形式的地址（IP 按主机字节序的 32 位字存储）
+func parseSocketAddr(s string) (net.IP, int, error) {
+	idx := strings.IndexByte(s, ':')
+	if idx < 0 {
+		return nil, 0, fmt.Errorf("无效地址: %s", s)
+	}
+	raw, err := hex.DecodeString(s[:idx])
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, fmt.Errorf("无效地址: %s", s)
+	}
+	port, err := strconv.ParseUint(s[idx+1:], 16, 16)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 每 4 字节一组按小端翻转
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip, int(port), nil
+}
+
+// findSocketOwners 扫描 /proc/*/fd，返回 inode 对应的进程 PID
+func findSocketOwners(inodes map[uint64]bool) map[uint64]int {
+	owners := make(map[uint64]int)
+	if len(inodes) == 0 {
+		return owners
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return owners
+	}
+	for _, p := range procs {
+		pid, err := strconv.Atoi(p.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", p.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue // 进程已退出或无权限
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(link[len("socket:["):], "]"), 10, 64)
+			if err != nil || !inodes[inode] {
+				continue
+			}
+			if _, ok := owners[inode]; !ok {
+				owners[inode] = pid
+			}
+		}
+		if len(owners) == len(inodes) {
+			break
+		}
+	}
+	return owners
+}
+
+// readProcessInfo 读取进程名称、启动时间和所属 systemd 单元/容器
+func readProcessInfo(pid int) (processInfo, error) {
+	info := processInfo{pid: pid}
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return info, err
+	}
+	info.name = strings.TrimSpace(string(comm))
+
+	// /proc/<pid>/stat 第 22 个字段为启动时间（开机后的时钟滴答数）
+	if stat, err := os.ReadFile(filepath.Join(dir, "stat")); err == nil {
+		// comm 可能包含空格，从最后一个 ')' 之后开始切分
+		if idx := strings.LastIndexByte(string(stat), ')'); idx > 0 {
+			fields := strings.Fields(string(stat)[idx+1:])
+			if len(fields) > 19 {
+				if ticks, err := strconv.ParseInt(fields[19], 10, 64); err == nil {
+					if boot := readBootTime(); boot > 0 {
+						info.startedAt = boot + ticks/clockTicks
+					}
+				}
+			}
+		}
+	}
+
+	if cg, err := os.ReadFile(filepath.Join(dir, "cgroup")); err == nil {
+		info.unit = parseCgroupUnit(string(cg))
+	}
+	return info, nil
+}
+
+// readBootTime 从 /proc/stat 读取开机时间
+func readBootTime() int64 {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			v, _ := strconv.ParseInt(strings.TrimSpace(line[len("btime "):]), 10, 64)
+			return v
+		}
+	}
+	return 0
+}
+
+// parseCgroupUnit 从 /proc/<pid>/cgroup 中提取 systemd 单元或容器 ID
+func parseCgroupUnit(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		// 格式: hierarchy-ID:controllers:path，cgroup v2 为 0::/system.slice/xxx.service
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+
+		segments := strings.Split(path, "/")
+		for i := len(segments) - 1; i >= 0; i-- {
+			seg := segments[i]
+			switch {
+			case strings.HasPrefix(seg, "docker-") && strings.HasSuffix(seg, ".scope"):
+				return "docker:" + shortID(strings.TrimSuffix(strings.TrimPrefix(seg, "docker-"), ".scope"))
+			case i > 0 && segments[i-1] == "docker" && len(seg) == 64:
+				return "docker:" + shortID(seg)
+			case strings.HasSuffix(seg, ".service"):
+				return seg
+			}
+		}
+	}
+	return ""
+}
+
+// inspectPort 检查端口监听状态及所属进程
+func (c *Collector) inspectPort(port int) portStatus {
+	st := portStatus{}
+	inodes := make(map[uint64]bool)
+	for _, e := range readAllSockets() {
+		if e.localPort != port {
+			continue
+		}
+		switch {
+		case (e.proto == "tcp" || e.proto == "tcp6") && e.state == tcpListen:
+			if !st.tcpListen {
+				st.listenAddr = e.localIP
+			}
+			st.tcpListen = true
+			inodes[e.inode] = true
+		case (e.proto == "udp" || e.proto == "udp6") && e.state == udpUnconnected:
+			st.udpBound = true
+			inodes[e.inode] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return st
+	}
+
+	// 套接字 inode 为 0 表示属于其他网络命名空间或无权限，无法定位进程
+	for _, pid := range findSocketOwners(inodes) {
+		if info, err := readProcessInfo(pid); err == nil {
+			st.process = &info
+			break
+		}
+	}
+	return st
+}
@@ -0,0 +1,69 @@
+package collector
+
+import "testing"
+
+// TestParseSocketAddr 测试 /proc/net/tcp 地址解析
+func TestParseSocketAddr(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantIP   string
+		wantPort int
+	}{
+		{"0100007F:1F90", "127.0.0.1", 8080},
+		{"00000000:01BB", "0.0.0.0", 443},
+		{"00000000000000000000000000000000:9002", "::", 36866},
+		{"00000000000000000000000001000000:0016", "::1", 22},
+		{"0000000000000000FFFF00000100007F:0050", "127.0.0.1", 80},
+	}
+
+	for _, tt := range tests {
+		ip, port, err := parseSocketAddr(tt.in)
+		if err != nil {
+			t.Fatalf("parseSocketAddr(%q) 错误: %v", tt.in, err)
+		}
+		if ip.String() != tt.wantIP || port != tt.wantPort {
+			t.Errorf("parseSocketAddr(%q) = %s:%d, 期望 %s:%d", tt.in, ip, port, tt.wantIP, tt.wantPort)
+		}
+	}
+
+	if _, _, err := parseSocketAddr("zz:0050"); err == nil {
+		t.Error("无效地址应返回错误")
+	}
+}
+
+// TestParseCgroupUnit 测试 systemd 单元 / 容器识别
+func TestParseCgroupUnit(t *testing.T) {
+	id := "4f1d2c3b4a5968778695a4b3c2d1e0f04f1d2c3b4a5968778695a4b3c2d1e0f0"
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"cgroup v2 服务", "0::/system.slice/snell.service\n", "snell.service"},
+		{"cgroup v2 docker", "0::/system.slice/docker-" + id + ".scope\n", "docker:4f1d2c3b4a59"},
+		{"cgroup v1 docker", "12:pids:/docker/" + id + "\n", "docker:4f1d2c3b4a59"},
+		{"用户会话", "0::/user.slice/user-0.slice/session-1.scope\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCgroupUnit(tt.in); got != tt.want {
+				t.Errorf("parseCgroupUnit() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseConntrackUDP 测试 conntrack UDP 记录解析
+func TestParseConntrackUDP(t *testing.T) {
+	line := "ipv4     2 udp      17 29 src=203.0.113.5 dst=198.51.100.1 sport=51234 dport=36890 src=198.51.100.1 dst=203.0.113.5 sport=36890 dport=51234 [ASSURED] mark=0 zone=0 use=2"
+	port, src, ok := parseConntrackUDP(line)
+	if !ok || port != 36890 || src != "203.0.113.5" {
+		t.Errorf("parseConntrackUDP() = %d, %q, %v", port, src, ok)
+	}
+
+	tcp := "ipv4     2 tcp      6 431999 ESTABLISHED src=203.0.113.5 dst=198.51.100.1 sport=51234 dport=443"
+	if _, _, ok := parseConntrackUDP(tcp); ok {
+		t.Error("TCP 记录应被忽略")
+	}
+}
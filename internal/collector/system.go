@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// cpuTimes /proc/stat 中一行 cpu 时间（单位：时钟滴答）
+// guest / guest_nice 已计入 user / nice，不重复累加
+type cpuTimes struct {
+	user, nice, system, idle, iowait, irq, softirq, steal uint64
+}
+
+func (t cpuTimes) total() uint64 {
+	return t.user + t.nice + t.system + t.idle + t.iowait + t.irq + t.softirq + t.steal
+}
+
+// cpuUsage 两次采样之间的 CPU 使用率分解（百分比）
+type cpuUsage struct {
+	Total   float64 // 非 idle 时间占比（含 iowait，与旧版 cpu_percent 口径一致）
+	User    float64 // user + nice
+	System  float64
+	IOWait  float64
+	IRQ     float64
+	SoftIRQ float64
+	Steal   float64
+}
+
+// cpuUsageBetween 计算两次采样之间的使用率，计数器回绕或无变化时返回零值
+func cpuUsageBetween(prev, cur cpuTimes) cpuUsage {
+	if cur.total() <= prev.total() {
+		return cpuUsage{}
+	}
+	delta := float64(cur.total() - prev.total())
+	pct := func(a, b uint64) float64 {
+		if a < b {
+			return 0
+		}
+		return 100.0 * float64(a-b) / delta
+	}
+
+	return cpuUsage{
+		Total:   100.0 - pct(cur.idle, prev.idle),
+		User:    pct(cur.user+cur.nice, prev.user+prev.nice),
+		System:  pct(cur.system, prev.system),
+		IOWait:  pct(cur.iowait, prev.iowait),
+		IRQ:     pct(cur.irq, prev.irq),
+		SoftIRQ: pct(cur.softirq, prev.softirq),
+		Steal:   pct(cur.steal, prev.steal),
+	}
+}
+
+// memInfo 内存与 swap 信息（字节）
+type memInfo struct {
+	used, total         uint64
+	buffers, cached     uint64
+	dirty, writeback    uint64
+	swapUsed, swapTotal uint64
+}
+
+// psiStats 一行 PSI 数据（some 或 full）
+type psiStats struct {
+	avg10 float64 // 最近 10 秒停顿时间占比（%）
+	avg60 float64
+	total uint64 // 累计停顿时间（微秒）
+}
+
+// psiResource 一种资源的压力，cpu 在系统级别没有 full
+type psiResource struct {
+	some psiStats
+	full *psiStats
+}
+
+// parsePSI 解析 /proc/pressure/<resource> 内容
+// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+// full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePSI(content string) (*psiResource, bool) {
+	var res psiResource
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var st psiStats
+		for _, f := range fields[1:] {
+			k, v, ok := strings.Cut(f, "=")
+			if !ok {
+				continue
+			}
+			switch k {
+			case "avg10":
+				st.avg10, _ = strconv.ParseFloat(v, 64)
+			case "avg60":
+				st.avg60, _ = strconv.ParseFloat(v, 64)
+			case "total":
+				st.total, _ = strconv.ParseUint(v, 10, 64)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			res.some, found = st, true
+		case "full":
+			full := st
+			res.full = &full
+		}
+	}
+	return &res, found
+}
+
+// systemSample 一次系统资源采样
+type systemSample struct {
+	ts    int64
+	cpu   cpuUsage
+	cores []float64 // 每个核心的使用率
+
+	mem      memInfo
+	oomKills uint64 // 开机以来 OOM kill 次数（/proc/vmstat oom_kill）
+
+	// PSI（内核未启用时为 nil）
+	psiCPU, psiMemory, psiIO *psiResource
+
+	diskUsed, diskTotal uint64
+
+	load1, load5, load15 float64
+}
+
+// psiColumns 将 PSI 展开为列值，不可用时为 NULL
+func psiColumns(r *psiResource, withFull bool) []interface{} {
+	n := 3
+	if withFull {
+		n = 6
+	}
+	cols := make([]interface{}, n)
+	if r == nil {
+		return cols
+	}
+	cols[0], cols[1], cols[2] = r.some.avg10, r.some.avg60, r.some.total
+	if withFull && r.full != nil {
+		cols[3], cols[4], cols[5] = r.full.avg10, r.full.avg60, r.full.total
+	}
+	return cols
+}
+
+// saveSystemSample 保存系统资源采样
+func (c *Collector) saveSystemSample(s *systemSample) {
+	cores, _ := json.Marshal(s.cores)
+
+	args := []interface{}{
+		s.ts, s.cpu.Total, s.mem.used, s.mem.total, s.diskUsed, s.diskTotal, s.load1, s.load5, s.load15,
+		s.cpu.User, s.cpu.System, s.cpu.IOWait, s.cpu.IRQ, s.cpu.SoftIRQ, s.cpu.Steal, string(cores),
+		s.mem.buffers, s.mem.cached, s.mem.dirty, s.mem.writeback, s.mem.swapUsed, s.mem.swapTotal, s.oomKills,
+	}
+	args = append(args, psiColumns(s.psiCPU, false)...)
+	args = append(args, psiColumns(s.psiMemory, true)...)
+	args = append(args, psiColumns(s.psiIO, true)...)
+
+	_, err := c.db.Exec(
+		`INSERT INTO system_metrics (ts, cpu_percent, mem_used, mem_total, disk_used, disk_total, load_1, load_5, load_15,
+		     cpu_user, cpu_system, cpu_iowait, cpu_irq, cpu_softirq, cpu_steal, cpu_cores,
+		     mem_buffers, mem_cached, mem_dirty, mem_writeback, swap_used, swap_total, oom_kills,
+		     psi_cpu_some_avg10, psi_cpu_some_avg60, psi_cpu_some_total,
+		     psi_mem_some_avg10, psi_mem_some_avg60, psi_mem_some_total, psi_mem_full_avg10, psi_mem_full_avg60, psi_mem_full_total,
+		     psi_io_some_avg10, psi_io_some_avg60, psi_io_some_total, psi_io_full_avg10, psi_io_full_avg60, psi_io_full_total)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		args...,
+	)
+	if err != nil {
+		log.Printf("保存系统指标失败: %v", err)
+	}
+}
@@ -2,11 +2,9 @@ package collector
 
 import (
 	"bufio"
-	"log"
 	"os"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 )
 
@@ -14,93 +12,97 @@ import (
 func (c *Collector) doCollectSystemMetrics() {
 	now := time.Now().Unix()
 
-	cpu := c.getCPUPercent()
-	memUsed, memTotal := c.getMemoryInfo()
-	diskUsed, diskTotal := c.getDiskInfo()
-	load1, load5, load15 := c.getLoadAvg()
+	s := &systemSample{ts: now}
+	s.cpu, s.cores = c.getCPUUsage()
+	s.mem = c.getMemoryInfo()
+	s.oomKills = readOOMKills()
+	s.psiCPU, s.psiMemory, s.psiIO = readPSI("cpu"), readPSI("memory"), readPSI("io")
+	s.diskUsed, s.diskTotal = c.getDiskInfo()
+	s.load1, s.load5, s.load15 = c.getLoadAvg()
 
-	_, err := c.db.Exec(
-		`INSERT INTO system_metrics (ts, cpu_percent, mem_used, mem_total, disk_used, disk_total, load_1, load_5, load_15)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		now, cpu, memUsed, memTotal, diskUsed, diskTotal, load1, load5, load15,
-	)
-	if err != nil {
-		log.Printf("保存系统指标失败: %v", err)
-	}
+	c.saveSystemSample(s)
+	c.collectDiskMetrics(time.Unix(now, 0))
 
 	// 清理旧数据，只保留最近 1 小时
 	_, _ = c.db.Exec("DELETE FROM system_metrics WHERE ts < ?", now-3600)
 }
 
-// getCPUPercent 获取 CPU 使用率（通过两次采样差值计算）
-func (c *Collector) getCPUPercent() float64 {
-	total, idle := c.readCPUStat()
-	if total == 0 {
-		return 0
+// getCPUUsage 获取 CPU 使用率分解及各核心使用率（通过两次采样差值计算）
+func (c *Collector) getCPUUsage() (cpuUsage, []float64) {
+	total, cores, err := readCPUStat()
+	if err != nil {
+		return cpuUsage{}, nil
 	}
 
-	// 首次采样，保存基准值
-	if c.lastCPUTotal == 0 {
-		c.lastCPUTotal, c.lastCPUIdle = total, idle
-		return 0
+	// 首次采样或核心数变化（CPU 热插拔），保存基准值
+	if c.lastCPU.total() == 0 || len(cores) != len(c.lastCores) {
+		c.lastCPU, c.lastCores = total, cores
+		return cpuUsage{}, nil
 	}
 
-	// 计算差值
-	deltaTotal := total - c.lastCPUTotal
-	deltaIdle := idle - c.lastCPUIdle
-	c.lastCPUTotal, c.lastCPUIdle = total, idle
-
-	if deltaTotal == 0 {
-		return 0
+	usage := cpuUsageBetween(c.lastCPU, total)
+	corePercents := make([]float64, len(cores))
+	for i := range cores {
+		corePercents[i] = cpuUsageBetween(c.lastCores[i], cores[i]).Total
 	}
+	c.lastCPU, c.lastCores = total, cores
 
-	return 100.0 * float64(deltaTotal-deltaIdle) / float64(deltaTotal)
+	return usage, corePercents
 }
 
-// readCPUStat 读取 /proc/stat 获取 CPU 时间
-func (c *Collector) readCPUStat() (total, idle uint64) {
+// readCPUStat 读取 /proc/stat 获取总体及各核心 CPU 时间
+func readCPUStat() (total cpuTimes, cores []cpuTimes, err error) {
 	file, err := os.Open("/proc/stat")
 	if err != nil {
-		return 0, 0
+		return total, nil, err
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
-	if !scanner.Scan() {
-		return 0, 0
-	}
-
-	line := scanner.Text()
-	if !strings.HasPrefix(line, "cpu ") {
-		return 0, 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "cpu") {
+			break // cpu 行总是位于文件开头
+		}
+		fields := strings.Fields(line)
+		t, ok := parseCPUTimes(fields)
+		if !ok {
+			continue
+		}
+		if fields[0] == "cpu" {
+			total = t
+		} else {
+			cores = append(cores, t)
+		}
 	}
+	return total, cores, scanner.Err()
+}
 
-	fields := strings.Fields(line)
+// parseCPUTimes 解析 cpu user nice system idle iowait irq softirq steal guest guest_nice
+func parseCPUTimes(fields []string) (cpuTimes, bool) {
 	if len(fields) < 5 {
-		return 0, 0
+		return cpuTimes{}, false
 	}
-
-	// cpu user nice system idle iowait irq softirq steal guest guest_nice
-	for i := 1; i < len(fields); i++ {
-		v, _ := strconv.ParseUint(fields[i], 10, 64)
-		total += v
-		if i == 4 { // idle
-			idle = v
-		}
+	var v [8]uint64
+	for i := 1; i < len(fields) && i <= len(v); i++ {
+		v[i-1], _ = strconv.ParseUint(fields[i], 10, 64)
 	}
-
-	return total, idle
+	return cpuTimes{
+		user: v[0], nice: v[1], system: v[2], idle: v[3],
+		iowait: v[4], irq: v[5], softirq: v[6], steal: v[7],
+	}, true
 }
 
-// getMemoryInfo 获取内存信息
-func (c *Collector) getMemoryInfo() (used, total uint64) {
+// getMemoryInfo 获取内存与 swap 信息
+func (c *Collector) getMemoryInfo() memInfo {
+	var m memInfo
 	file, err := os.Open("/proc/meminfo")
 	if err != nil {
-		return 0, 0
+		return m
 	}
 	defer file.Close()
 
-	var memTotal, memAvailable, memFree, buffers, cached uint64
+	var memAvailable, memFree, swapFree uint64
 	hasMemAvailable := false
 
 	scanner := bufio.NewScanner(file)
@@ -116,40 +118,74 @@ func (c *Collector) getMemoryInfo() (used, total uint64) {
 
 		switch fields[0] {
 		case "MemTotal:":
-			memTotal = value
+			m.total = value
 		case "MemAvailable:":
 			memAvailable = value
 			hasMemAvailable = true
 		case "MemFree:":
 			memFree = value
 		case "Buffers:":
-			buffers = value
+			m.buffers = value
 		case "Cached:":
-			cached = value
+			m.cached = value
+		case "Dirty:":
+			m.dirty = value
+		case "Writeback:":
+			m.writeback = value
+		case "SwapTotal:":
+			m.swapTotal = value
+		case "SwapFree:":
+			swapFree = value
 		}
 	}
 
 	// 优先使用 MemAvailable（更准确），否则回退到传统计算
 	if hasMemAvailable {
-		used = memTotal - memAvailable
+		m.used = m.total - memAvailable
 	} else {
-		used = memTotal - memFree - buffers - cached
+		m.used = m.total - memFree - m.buffers - m.cached
 	}
-	return used, memTotal
+	if m.swapTotal >= swapFree {
+		m.swapUsed = m.swapTotal - swapFree
+	}
+	return m
 }
 
-// getDiskInfo 获取磁盘使用情况（根目录）
-func (c *Collector) getDiskInfo() (used, total uint64) {
-	var stat syscall.Statfs_t
-	if err := syscall.Statfs("/", &stat); err != nil {
-		return 0, 0
+// readOOMKills 读取开机以来的 OOM kill 次数（内核 4.13+）
+func readOOMKills() uint64 {
+	data, err := os.ReadFile("/proc/vmstat")
+	if err != nil {
+		return 0
 	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			n, _ := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
 
-	total = stat.Blocks * uint64(stat.Bsize)
-	free := stat.Bfree * uint64(stat.Bsize)
-	used = total - free
+// readPSI 读取 /proc/pressure/<resource>（内核 4.20+ 且启用 CONFIG_PSI），不可用时返回 nil
+func readPSI(resource string) *psiResource {
+	data, err := os.ReadFile("/proc/pressure/" + resource)
+	if err != nil {
+		return nil
+	}
+	res, ok := parsePSI(string(data))
+	if !ok {
+		return nil
+	}
+	return res
+}
 
-	return used, total
+// getDiskInfo 获取根目录磁盘使用情况（总量 = 已用 + 普通用户可用，与 df 一致）
+func (c *Collector) getDiskInfo() (used, total uint64) {
+	u, ok := statDisk("/")
+	if !ok {
+		return 0, 0
+	}
+	return u.used, u.total
 }
 
 // getLoadAvg 获取系统负载
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestCPUUsageBetween 测试 CPU 使用率分解
+func TestCPUUsageBetween(t *testing.T) {
+	prev := cpuTimes{user: 1000, nice: 0, system: 500, idle: 8000, iowait: 100, irq: 10, softirq: 90, steal: 300}
+	// 共 1000 滴答：user 200 + nice 50，system 100，idle 400，iowait 50，irq 0，softirq 100，steal 100
+	cur := cpuTimes{user: 1200, nice: 50, system: 600, idle: 8400, iowait: 150, irq: 10, softirq: 190, steal: 400}
+
+	got := cpuUsageBetween(prev, cur)
+	want := cpuUsage{Total: 60, User: 25, System: 10, IOWait: 5, IRQ: 0, SoftIRQ: 10, Steal: 10}
+
+	for _, f := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"Total", got.Total, want.Total},
+		{"User", got.User, want.User},
+		{"System", got.System, want.System},
+		{"IOWait", got.IOWait, want.IOWait},
+		{"IRQ", got.IRQ, want.IRQ},
+		{"SoftIRQ", got.SoftIRQ, want.SoftIRQ},
+		{"Steal", got.Steal, want.Steal},
+	} {
+		if math.Abs(f.got-f.want) > 1e-9 {
+			t.Errorf("%s = %.2f, 期望 %.2f", f.name, f.got, f.want)
+		}
+	}
+
+	// 计数器回退（如 VM 迁移后）时返回零值
+	if u := cpuUsageBetween(cur, prev); u != (cpuUsage{}) {
+		t.Errorf("计数器回退时应返回零值，实际 %+v", u)
+	}
+}
+
+// TestParsePSI 测试 PSI 解析
+func TestParsePSI(t *testing.T) {
+	res, ok := parsePSI("some avg10=1.50 avg60=0.75 avg300=0.20 total=123456\nfull avg10=0.30 avg60=0.10 avg300=0.00 total=7890\n")
+	if !ok {
+		t.Fatal("parsePSI 应成功")
+	}
+	if res.some.avg10 != 1.5 || res.some.avg60 != 0.75 || res.some.total != 123456 {
+		t.Errorf("some = %+v", res.some)
+	}
+	if res.full == nil || res.full.avg10 != 0.3 || res.full.total != 7890 {
+		t.Errorf("full = %+v", res.full)
+	}
+
+	// 旧内核的 cpu 只有 some 行
+	res, ok = parsePSI("some avg10=0.00 avg60=0.00 avg300=0.00 total=0\n")
+	if !ok || res.full != nil {
+		t.Errorf("仅 some 行时 full 应为 nil")
+	}
+
+	if _, ok := parsePSI(""); ok {
+		t.Error("空内容应返回 false")
+	}
+}
+
+// TestDiskIORateBetween 测试磁盘 I/O 速率计算
+func TestDiskIORateBetween(t *testing.T) {
+	prev := diskStat{readOps: 100, writeOps: 200, readBytes: 1 << 20, writeBytes: 2 << 20, ioTicks: 1000}
+	cur := diskStat{readOps: 150, writeOps: 300, readBytes: 6 << 20, writeBytes: 12 << 20, ioTicks: 3500}
+
+	r, ok := diskIORateBetween("vda", prev, cur, 5*time.Second)
+	if !ok {
+		t.Fatal("diskIORateBetween 应成功")
+	}
+	if r.readIOPS != 10 || r.writeIOPS != 20 {
+		t.Errorf("IOPS = %.1f/%.1f, 期望 10/20", r.readIOPS, r.writeIOPS)
+	}
+	if r.readBps != 1<<20 || r.writeBps != 2<<20 {
+		t.Errorf("吞吐 = %.0f/%.0f, 期望 1MiB/2MiB", r.readBps, r.writeBps)
+	}
+	if r.utilPercent != 50 {
+		t.Errorf("util = %.1f, 期望 50", r.utilPercent)
+	}
+
+	if _, ok := diskIORateBetween("vda", cur, prev, 5*time.Second); ok {
+		t.Error("计数器回退时应返回 false")
+	}
+}
@@ -2,7 +2,8 @@
 package config
 
 import (
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -30,9 +31,24 @@ type Config struct {
 	DataDir string
 
 	// HTTP 服务
-	ListenAddr string
-	Username   string
-	Password   string
+	ListenAddr     string      // host:port 或 unix:/path/to.sock；systemd 套接字激活时忽略
+	SocketMode     os.FileMode // Unix 套接字文件权限
+	Username       string      // 引导管理员（HELIOX_MON_PASS 为空时不创建）
+	Password       string
+	BasePath       string   // 挂载在反向代理的子路径下，如 /mon（为空表示根路径）
+	TrustedProxies []string // 可信反向代理（IP 或 CIDR），只采信它们设置的 X-Forwarded-* 与 CF-Connecting-IP
+
+	// HTTPS（证书文件与 ACME 二选一，都为空时监听明文 HTTP）
+	TLSCertFile     string
+	TLSKeyFile      string
+	ACMEDomains     []string // 自动申请证书的域名
+	ACMEEmail       string
+	ACMEDirectory   string // ACME 目录地址（测试时可指向本地 Pebble）
+	ACMECAFile      string // 额外信任的 ACME 服务器根证书（Pebble 等自签 CA）
+	ACMEHTTPListen  string // HTTP-01 验证与跳转 HTTPS 的监听地址，如 :80（为空时只使用 TLS-ALPN-01）
+	TLSClientCAFile string // 客户端证书 CA，设置后启用 mTLS
+	TLSClientAuth   string // require: 所有请求都需要客户端证书；api: 只有 /api 与 /metrics 需要
+	HSTSMaxAge      int    // HTTPS 时 Strict-Transport-Security 的 max-age（秒），0 关闭
 
 	// Heliox 配置路径
 	HelioxEnvPath string
@@ -41,12 +57,69 @@ type Config struct {
 	SnellPort int
 	VlessPort int
 
+	// 磁盘监控
+	DiskIgnoreFSTypes []string // 忽略的文件系统类型
+	DiskIgnoreMounts  []string // 忽略的挂载点（含子目录）
+
+	// 进程监控
+	ProcessMatch []string // 匹配规则：name=<进程名>、cmdline=<正则>、cgroup=<路径子串>（以分号分隔）
+	ProcessTopN  int      // 额外记录 CPU 占用最高的 N 个其他进程
+
+	// 容器监控
+	ContainerMetrics bool   // 采集 Docker 容器资源
+	DockerSocket     string // Docker Engine API 套接字
+	CgroupRoot       string // cgroup v2 挂载点
+
+	// 网络质量报警（阈值 <= 0 关闭对应报警）
+	NetAlerts                     bool
+	NetAlertRetransPercent        float64 // TCP 重传率（%）
+	NetAlertListenOverflowsPerMin int     // 全连接队列溢出（次/分钟）
+	NetAlertDropsPerMin           int     // 单网卡丢包与错误（个/分钟）
+
+	// 登录会话
+	SessionIdleTimeout time.Duration // 空闲超时
+	SessionMaxAge      time.Duration // 绝对有效期
+
+	// 登录失败限制（按 IP 与用户名分别计数，0 表示关闭）
+	LoginBackoffAfter int           // 连续失败多少次后开始指数退避
+	LoginLockoutAfter int           // 连续失败多少次后临时锁定
+	LoginLockout      time.Duration // 锁定时长，同时也是失败计数的过期时间
+
+	// 外部身份认证（Cloudflare Access / OIDC / 可信代理请求头）
+	CFAccessTeamDomain string // <team>.cloudflareaccess.com
+	CFAccessAUD        string // Access 应用的 Audience Tag
+	OIDCIssuer         string // 为空表示关闭 OIDC 登录
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCRedirectURL    string // https://<域名>/api/oidc/callback
+	OIDCScopes         []string
+	OIDCUsernameClaim  string   // 作为用户名的声明，默认 email
+	TrustedAuthHeader  string   // 如 Remote-User，为空表示关闭
+	TrustedAuthProxies []string // 允许设置该请求头的代理地址（IP 或 CIDR）
+	SSODefaultRole     string   // 外部身份首次登录时自动创建用户的角色，为空表示只允许已存在的用户
+
+	// 实时网速
+	RealtimeResolution    time.Duration // 采样间隔（最低 250ms）
+	RealtimeBufferSeconds int           // 内存中保留的时长
+	RealtimeReplaySeconds int           // SSE 连接建立时回放的时长
+
+	// 系统指标历史保留天数（按汇总层级）
+	SystemRetention1mDays  int
+	SystemRetention15mDays int
+	SystemRetention1hDays  int
+
 	// 时区
 	Timezone *time.Location
 
 	// Telegram
-	TelegramBotToken string
-	TelegramChatID   string
+	TelegramBotToken    string
+	TelegramChatID      string
+	TelegramAPIBase     string // Telegram Bot API 地址（测试或自建代理时可修改）
+	TelegramBotCommands bool   // 启用机器人命令（getUpdates 长轮询，多台共用同一机器人时只应开启一台）
+
+	// 通知队列
+	NotifyRatePerMinute int // 每个通道每分钟最多发送条数
+	NotifyMaxAttempts   int // 最大重试次数，超过后进入死信状态
 
 	// 流量报警
 	MonthlyLimitGB  int
@@ -54,6 +127,24 @@ type Config struct {
 	ResetDay        int    // 计费周期重置日 (1-28)
 	AlertThresholds []int  // 报警阈值百分比，如 [80, 90, 95]
 
+	// 定时报告
+	ReportDaily       string // 每日报告发送时间，如 09:00（空为关闭）
+	ReportWeekly      string // 每周报告发送时间，如 Mon 09:00（空为关闭）
+	ReportCycle       bool   // 计费周期结束时发送周期报告
+	ReportTemplateDir string // 自定义报告模板目录（daily.tmpl / weekly.tmpl / cycle.tmpl）
+
+	// 代理服务健康检查
+	ServiceCheck    bool   // 是否启用
+	ServiceProbe    bool   // 是否进行协议层探测（Snell 建连 / VLESS TLS 握手）
+	ServiceProbeSNI string // VLESS TLS 握手使用的 SNI（Reality 需填写伪装域名）
+
+	// 流量异常检测
+	AnomalyDetection  bool    // 是否启用
+	AnomalyZScore     float64 // 突增判定的 z-score 阈值
+	AnomalyMinRateKBs int     // 最小速率差（KB/s），低于此值不视为异常
+	AnomalyDropRatio  float64 // 速率低于基线的该比例视为骤降
+	AnomalyMinSamples int     // 基线样本数达到该值后才开始检测
+
 	// 延迟监控目标
 	PingTargets []PingTarget
 	PingCount   int
@@ -70,23 +161,111 @@ type Config struct {
 // Load 加载配置
 func Load() (*Config, error) {
 	cfg := &Config{
-		DataDir:            getEnv("HELIOX_MON_DATA_DIR", "/var/lib/heliox-mon"),
-		ListenAddr:         getEnv("HELIOX_MON_LISTEN", "127.0.0.1:9100"),
-		Username:           getEnv("HELIOX_MON_USER", "admin"),
-		Password:           getEnv("HELIOX_MON_PASS", ""),
-		HelioxEnvPath:      getEnv("HELIOX_ENV_PATH", "../heliox/.env"),
-		TelegramBotToken:   getEnv("TELEGRAM_BOT_TOKEN", ""),
-		TelegramChatID:     getEnv("TELEGRAM_CHAT_ID", ""),
-		MonthlyLimitGB:     getEnvInt("MONTHLY_LIMIT_GB", 1000),
-		BillingMode:        getEnv("BILLING_MODE", "bidirectional"),
-		ResetDay:           getEnvInt("RESET_DAY", 1),
-		ServerName:         getEnv("SERVER_NAME", "Heliox"),
-		PingCount:          getEnvInt("PING_COUNT", 5),
-		PingTimeout:        time.Duration(getEnvInt("PING_TIMEOUT_MS", 1000)) * time.Millisecond,
-		PingGap:            time.Duration(getEnvInt("PING_GAP_MS", 200)) * time.Millisecond,
-		TurnstileSecretKey: getEnv("HELIOX_TURNSTILE_SECRET", ""),
+		DataDir:             getEnv("HELIOX_MON_DATA_DIR", "/var/lib/heliox-mon"),
+		ListenAddr:          getEnv("HELIOX_MON_LISTEN", "127.0.0.1:9100"),
+		SocketMode:          getEnvFileMode("HELIOX_MON_SOCKET_MODE", 0660),
+		Username:            getEnv("HELIOX_MON_USER", "admin"),
+		Password:            getEnv("HELIOX_MON_PASS", ""),
+		HelioxEnvPath:       getEnv("HELIOX_ENV_PATH", "../heliox/.env"),
+		TelegramBotToken:    getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatID:      getEnv("TELEGRAM_CHAT_ID", ""),
+		TelegramAPIBase:     strings.TrimRight(getEnv("TELEGRAM_API_BASE", "https://api.telegram.org"), "/"),
+		TelegramBotCommands: getEnvBool("TELEGRAM_BOT_COMMANDS", false),
+		NotifyRatePerMinute: getEnvInt("NOTIFY_RATE_PER_MINUTE", 20),
+		NotifyMaxAttempts:   getEnvInt("NOTIFY_MAX_ATTEMPTS", 8),
+		MonthlyLimitGB:      getEnvInt("MONTHLY_LIMIT_GB", 1000),
+		BillingMode:         getEnv("BILLING_MODE", "bidirectional"),
+		ResetDay:            getEnvInt("RESET_DAY", 1),
+		ReportDaily:         getEnv("REPORT_DAILY", ""),
+		ReportWeekly:        getEnv("REPORT_WEEKLY", ""),
+		ReportCycle:         getEnvBool("REPORT_CYCLE", false),
+		ServiceCheck:        getEnvBool("SERVICE_CHECK", true),
+		ServiceProbe:        getEnvBool("SERVICE_PROBE", false),
+		ServiceProbeSNI:     getEnv("SERVICE_PROBE_SNI", ""),
+		AnomalyDetection:    getEnvBool("ANOMALY_DETECTION", true),
+		AnomalyZScore:       getEnvFloat("ANOMALY_ZSCORE", 4),
+		AnomalyMinRateKBs:   getEnvInt("ANOMALY_MIN_RATE_KBS", 512),
+		AnomalyDropRatio:    getEnvFloat("ANOMALY_DROP_RATIO", 0.1),
+		AnomalyMinSamples:   getEnvInt("ANOMALY_MIN_SAMPLES", 30),
+		ServerName:          getEnv("SERVER_NAME", "Heliox"),
+		PingCount:           getEnvInt("PING_COUNT", 5),
+		PingTimeout:         time.Duration(getEnvInt("PING_TIMEOUT_MS", 1000)) * time.Millisecond,
+		PingGap:             time.Duration(getEnvInt("PING_GAP_MS", 200)) * time.Millisecond,
+		TurnstileSecretKey:  getEnv("HELIOX_TURNSTILE_SECRET", ""),
 	}
 
+	cfg.ReportTemplateDir = getEnv("REPORT_TEMPLATE_DIR", cfg.DataPath("templates"))
+
+	// 反向代理
+	cfg.BasePath = normalizeBasePath(getEnv("BASE_PATH", ""))
+	cfg.TrustedProxies = getEnvList("TRUSTED_PROXIES", "127.0.0.1,::1")
+
+	// HTTPS
+	cfg.TLSCertFile = getEnv("TLS_CERT_FILE", "")
+	cfg.TLSKeyFile = getEnv("TLS_KEY_FILE", "")
+	cfg.ACMEDomains = getEnvList("ACME_DOMAINS", "")
+	cfg.ACMEEmail = getEnv("ACME_EMAIL", "")
+	cfg.ACMEDirectory = getEnv("ACME_DIRECTORY_URL", "https://acme-v02.api.letsencrypt.org/directory")
+	cfg.ACMECAFile = getEnv("ACME_CA_FILE", "")
+	cfg.ACMEHTTPListen = getEnv("ACME_HTTP_LISTEN", "")
+	cfg.TLSClientCAFile = getEnv("TLS_CLIENT_CA_FILE", "")
+	cfg.TLSClientAuth = getEnv("TLS_CLIENT_AUTH", "require")
+	cfg.HSTSMaxAge = getEnvInt("HSTS_MAX_AGE", 31536000)
+
+	// 系统指标历史保留天数
+	cfg.SystemRetention1mDays = getEnvInt("SYSTEM_RETENTION_1M_DAYS", 2)
+	cfg.SystemRetention15mDays = getEnvInt("SYSTEM_RETENTION_15M_DAYS", 31)
+	cfg.SystemRetention1hDays = getEnvInt("SYSTEM_RETENTION_1H_DAYS", 365)
+
+	// 进程监控
+	// 规则以分号分隔，正则中可以出现逗号（如 {1,3}）
+	cfg.ProcessMatch = getEnvListSep("PROCESS_MATCH", "name=sing-box;name=xray;name=snell-server;name=heliox-mon", ";")
+	cfg.ProcessTopN = getEnvInt("PROCESS_TOP_N", 5)
+
+	// 容器监控
+	cfg.ContainerMetrics = getEnvBool("CONTAINER_METRICS", true)
+	cfg.DockerSocket = getEnv("DOCKER_SOCKET", "/var/run/docker.sock")
+	cfg.CgroupRoot = getEnv("CGROUP_ROOT", "/sys/fs/cgroup")
+
+	// 网络质量报警
+	cfg.NetAlerts = getEnvBool("NET_ALERTS", true)
+	cfg.NetAlertRetransPercent = getEnvFloat("NET_ALERT_RETRANS_PERCENT", 5)
+	cfg.NetAlertListenOverflowsPerMin = getEnvInt("NET_ALERT_LISTEN_OVERFLOWS", 10)
+	cfg.NetAlertDropsPerMin = getEnvInt("NET_ALERT_DROPS", 100)
+
+	// 登录会话
+	cfg.SessionIdleTimeout = time.Duration(getEnvInt("SESSION_IDLE_HOURS", 72)) * time.Hour
+	cfg.SessionMaxAge = time.Duration(getEnvInt("SESSION_MAX_DAYS", 30)) * 24 * time.Hour
+
+	// 登录失败限制
+	cfg.LoginBackoffAfter = getEnvInt("LOGIN_BACKOFF_AFTER", 3)
+	cfg.LoginLockoutAfter = getEnvInt("LOGIN_LOCKOUT_AFTER", 10)
+	cfg.LoginLockout = time.Duration(getEnvInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute
+
+	// 外部身份认证
+	cfg.CFAccessTeamDomain = getEnv("CF_ACCESS_TEAM_DOMAIN", "")
+	cfg.CFAccessAUD = getEnv("CF_ACCESS_AUD", "")
+	cfg.OIDCIssuer = getEnv("OIDC_ISSUER", "")
+	cfg.OIDCClientID = getEnv("OIDC_CLIENT_ID", "")
+	cfg.OIDCClientSecret = getEnv("OIDC_CLIENT_SECRET", "")
+	cfg.OIDCRedirectURL = getEnv("OIDC_REDIRECT_URL", "")
+	cfg.OIDCScopes = getEnvList("OIDC_SCOPES", "openid,email,profile")
+	cfg.OIDCUsernameClaim = getEnv("OIDC_USERNAME_CLAIM", "email")
+	cfg.TrustedAuthHeader = getEnv("TRUSTED_AUTH_HEADER", "")
+	cfg.TrustedAuthProxies = getEnvList("TRUSTED_AUTH_PROXIES", "")
+	cfg.SSODefaultRole = getEnv("SSO_DEFAULT_ROLE", "")
+
+	// 实时网速
+	cfg.RealtimeResolution = time.Duration(getEnvInt("REALTIME_RESOLUTION_MS", 1000)) * time.Millisecond
+	cfg.RealtimeBufferSeconds = getEnvInt("REALTIME_BUFFER_SECONDS", 300)
+	cfg.RealtimeReplaySeconds = getEnvInt("REALTIME_REPLAY_SECONDS", 60)
+
+	// 磁盘监控忽略列表
+	cfg.DiskIgnoreFSTypes = getEnvList("DISK_IGNORE_FSTYPES",
+		"tmpfs,devtmpfs,devpts,proc,sysfs,cgroup,cgroup2,overlay,squashfs,nsfs,autofs,mqueue,hugetlbfs,"+
+			"debugfs,tracefs,securityfs,pstore,bpf,configfs,fusectl,binfmt_misc,efivarfs,rpc_pipefs,ramfs,fuse.lxcfs")
+	cfg.DiskIgnoreMounts = getEnvList("DISK_IGNORE_MOUNTS", "/proc,/sys,/dev,/run,/snap,/var/lib/docker")
+
 	// 解析报警阈值
 	thresholds := getEnv("ALERT_THRESHOLDS", "80,90,95")
 	for _, t := range strings.Split(thresholds, ",") {
@@ -126,11 +305,6 @@ func Load() (*Config, error) {
 		cfg.VlessPort = 443
 	}
 
-	// 验证必填项
-	if cfg.Password == "" {
-		return nil, fmt.Errorf("HELIOX_MON_PASS 未设置")
-	}
-
 	return cfg, nil
 }
 
@@ -180,6 +354,71 @@ func (c *Config) DataPath(name string) string {
 	return filepath.Join(c.DataDir, name)
 }
 
+// Snapshot 用于审计的配置快照（键为环境变量名），密钥类配置只保留指纹，不记录明文
+func (c *Config) Snapshot() map[string]string {
+	secret := func(v string) string {
+		if v == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(v))
+		return "sha256:" + hex.EncodeToString(sum[:4])
+	}
+	targets := make([]string, len(c.PingTargets))
+	for i, t := range c.PingTargets {
+		targets[i] = t.Tag + ":" + t.IP
+	}
+	thresholds := make([]string, len(c.AlertThresholds))
+	for i, v := range c.AlertThresholds {
+		thresholds[i] = strconv.Itoa(v)
+	}
+
+	return map[string]string{
+		"HELIOX_MON_LISTEN":       c.ListenAddr,
+		"BASE_PATH":               c.BasePath,
+		"TRUSTED_PROXIES":         strings.Join(c.TrustedProxies, ","),
+		"TLS_CERT_FILE":           c.TLSCertFile,
+		"ACME_DOMAINS":            strings.Join(c.ACMEDomains, ","),
+		"ACME_DIRECTORY_URL":      c.ACMEDirectory,
+		"TLS_CLIENT_CA_FILE":      c.TLSClientCAFile,
+		"TLS_CLIENT_AUTH":         c.TLSClientAuth,
+		"HELIOX_MON_USER":         c.Username,
+		"HELIOX_MON_PASS":         secret(c.Password),
+		"SNELL_PORT":              strconv.Itoa(c.SnellPort),
+		"VLESS_PORT":              strconv.Itoa(c.VlessPort),
+		"MONTHLY_LIMIT_GB":        strconv.Itoa(c.MonthlyLimitGB),
+		"BILLING_MODE":            c.BillingMode,
+		"RESET_DAY":               strconv.Itoa(c.ResetDay),
+		"ALERT_THRESHOLDS":        strings.Join(thresholds, ","),
+		"PING_TARGETS":            strings.Join(targets, ","),
+		"TELEGRAM_BOT_TOKEN":      secret(c.TelegramBotToken),
+		"TELEGRAM_CHAT_ID":        c.TelegramChatID,
+		"SESSION_IDLE_HOURS":      strconv.Itoa(int(c.SessionIdleTimeout.Hours())),
+		"SESSION_MAX_DAYS":        strconv.Itoa(int(c.SessionMaxAge.Hours() / 24)),
+		"LOGIN_BACKOFF_AFTER":     strconv.Itoa(c.LoginBackoffAfter),
+		"LOGIN_LOCKOUT_AFTER":     strconv.Itoa(c.LoginLockoutAfter),
+		"LOGIN_LOCKOUT_MINUTES":   strconv.Itoa(int(c.LoginLockout.Minutes())),
+		"CF_ACCESS_TEAM_DOMAIN":   c.CFAccessTeamDomain,
+		"CF_ACCESS_AUD":           c.CFAccessAUD,
+		"OIDC_ISSUER":             c.OIDCIssuer,
+		"OIDC_CLIENT_ID":          c.OIDCClientID,
+		"OIDC_CLIENT_SECRET":      secret(c.OIDCClientSecret),
+		"OIDC_REDIRECT_URL":       c.OIDCRedirectURL,
+		"TRUSTED_AUTH_HEADER":     c.TrustedAuthHeader,
+		"TRUSTED_AUTH_PROXIES":    strings.Join(c.TrustedAuthProxies, ","),
+		"SSO_DEFAULT_ROLE":        c.SSODefaultRole,
+		"HELIOX_TURNSTILE_SECRET": secret(c.TurnstileSecretKey),
+	}
+}
+
+// normalizeBasePath 规范为以 / 开头、不以 / 结尾的形式，根路径返回空字符串
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
 func getEnv(key, defaultVal string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -195,3 +434,64 @@ func getEnvInt(key string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+func getEnvFloat(key string, defaultVal float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
+// getEnvList 读取逗号分隔的列表
+func getEnvList(key, defaultVal string) []string {
+	return getEnvListSep(key, defaultVal, ",")
+}
+
+// getEnvListSep 按指定分隔符拆分列表，忽略空项
+func getEnvListSep(key, defaultVal, sep string) []string {
+	var list []string
+	for _, v := range strings.Split(getEnv(key, defaultVal), sep) {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// getEnvFileMode 读取八进制文件权限，如 0660
+func getEnvFileMode(key string, defaultVal os.FileMode) os.FileMode {
+	if v := os.Getenv(key); v != "" {
+		if m, err := strconv.ParseUint(v, 8, 32); err == nil {
+			return os.FileMode(m) & os.ModePerm
+		}
+	}
+	return defaultVal
+}
+
+func getEnvBool(key string, defaultVal bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
+// BilledBytes 按计费模式计算计费流量
+func BilledBytes(mode string, tx, rx int64) int64 {
+	switch mode {
+	case "tx_only":
+		return tx
+	case "rx_only":
+		return rx
+	case "max_value":
+		if tx > rx {
+			return tx
+		}
+		return rx
+	default: // bidirectional
+		return tx + rx
+	}
+}
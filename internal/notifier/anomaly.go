@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"fmt"
+	"time"
+)
+
+// AnomalyAlert 流量异常报警
+type AnomalyAlert struct {
+	ID        int64
+	Scope     string // total / port:N / iface:X
+	Direction string // tx / rx
+	Kind      string // spike / drop
+	Rate      float64
+	Baseline  float64
+	ZScore    float64
+	Time      time.Time
+
+	// 恢复通知
+	Recovered bool
+	Duration  time.Duration
+}
+
+// SendAnomalyAlert 发送流量异常报警或恢复通知（写入通知队列）
+func (n *Notifier) SendAnomalyAlert(a *AnomalyAlert) error {
+	if n.cfg.TelegramBotToken == "" || n.cfg.TelegramChatID == "" {
+		return nil
+	}
+
+	// 静默期内不发送
+	if until := n.silencedUntil(); time.Now().Before(until) {
+		return nil
+	}
+
+	direction := "上传"
+	if a.Direction == "rx" {
+		direction = "下载"
+	}
+	kind := "突增"
+	if a.Kind == "drop" {
+		kind = "骤降"
+	}
+
+	var msg, stage string
+	if a.Recovered {
+		stage = "end"
+		msg = fmt.Sprintf(`✅ 流量恢复正常 [%s]
+
+📍 %s %s%s
+📈 当前: %s/s (基线 %s/s)
+⏱ 持续: %s
+
+⏰ %s`,
+			n.cfg.ServerName,
+			a.Scope, direction, kind,
+			formatBytes(int64(a.Rate)), formatBytes(int64(a.Baseline)),
+			a.Duration.Round(time.Minute),
+			a.Time.In(n.cfg.Timezone).Format("2006-01-02 15:04 MST"),
+		)
+	} else {
+		stage = "start"
+		msg = fmt.Sprintf(`🚨 流量异常 [%s]
+
+📍 %s %s%s
+📈 当前: %s/s (基线 %s/s, z=%.1f)
+
+⏰ %s`,
+			n.cfg.ServerName,
+			a.Scope, direction, kind,
+			formatBytes(int64(a.Rate)), formatBytes(int64(a.Baseline)), a.ZScore,
+			a.Time.In(n.cfg.Timezone).Format("2006-01-02 15:04 MST"),
+		)
+	}
+
+	return n.enqueueTelegram(fmt.Sprintf("anomaly:%d:%s", a.ID, stage), msg, nil)
+}
@@ -0,0 +1,462 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/auth"
+)
+
+// BotDataSource 机器人命令的数据来源（由 API 服务实现，与 HTTP 接口共用计算逻辑）
+type BotDataSource interface {
+	Stats() map[string]interface{}
+	System() (map[string]interface{}, error)
+	PortTraffic() map[string]interface{}
+	Latency() map[string]interface{}
+}
+
+// botPollTimeout getUpdates 长轮询超时
+const botPollTimeout = 30 * time.Second
+
+const botHelp = `可用命令:
+/status - 系统状态
+/traffic - 流量统计
+/ports - 端口流量
+/latency - 延迟与丢包（24 小时）
+/quota - 计费周期用量
+/silence <时长> - 静默报警，如 /silence 2h、/silence 1d、/silence off`
+
+type tgUpdate struct {
+	UpdateID      int64            `json:"update_id"`
+	Message       *tgMessage       `json:"message"`
+	CallbackQuery *tgCallbackQuery `json:"callback_query"`
+}
+
+type tgMessage struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Chat      tgChat `json:"chat"`
+	From      tgUser `json:"from"`
+}
+
+type tgChat struct {
+	ID int64 `json:"id"`
+}
+
+type tgUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type tgCallbackQuery struct {
+	ID      string     `json:"id"`
+	Data    string     `json:"data"`
+	From    tgUser     `json:"from"`
+	Message *tgMessage `json:"message"`
+}
+
+// StartBot 启动 Telegram 命令轮询（仅响应 TELEGRAM_CHAT_ID 会话）
+func (n *Notifier) StartBot(ds BotDataSource) {
+	if !n.cfg.TelegramBotCommands || n.cfg.TelegramBotToken == "" || n.cfg.TelegramChatID == "" {
+		return
+	}
+
+	n.wg.Add(1)
+	go n.pollUpdates(ds)
+	log.Println("Telegram 机器人命令已启用")
+}
+
+// pollUpdates getUpdates 长轮询循环
+func (n *Notifier) pollUpdates(ds BotDataSource) {
+	defer n.wg.Done()
+
+	var offset int64
+	for {
+		if n.ctx.Err() != nil {
+			return
+		}
+
+		var updates []tgUpdate
+		ctx, cancel := context.WithTimeout(n.ctx, botPollTimeout+10*time.Second)
+		err := n.callTelegram(ctx, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         int(botPollTimeout.Seconds()),
+			"allowed_updates": []string{"message", "callback_query"},
+		}, &updates)
+		cancel()
+		if err != nil {
+			if n.ctx.Err() != nil {
+				return
+			}
+			log.Printf("获取 Telegram 更新失败: %v", err)
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			if u.UpdateID >= offset {
+				offset = u.UpdateID + 1
+			}
+			n.handleUpdate(ds, u)
+		}
+	}
+}
+
+// handleUpdate 处理单条更新
+func (n *Notifier) handleUpdate(ds BotDataSource, u tgUpdate) {
+	switch {
+	case u.Message != nil:
+		if !n.isAllowedChat(u.Message.Chat.ID) {
+			log.Printf("忽略来自未授权会话 %d 的 Telegram 消息", u.Message.Chat.ID)
+			return
+		}
+		reply := n.handleCommand(ds, u.Message.Text, u.Message.From)
+		if reply == "" {
+			return
+		}
+		if err := n.sendTelegramMessage(strconv.FormatInt(u.Message.Chat.ID, 10), reply, nil); err != nil {
+			log.Printf("回复 Telegram 命令失败: %v", err)
+		}
+	case u.CallbackQuery != nil:
+		q := u.CallbackQuery
+		if q.Message == nil || !n.isAllowedChat(q.Message.Chat.ID) {
+			return
+		}
+		n.handleCallback(q)
+	}
+}
+
+func (n *Notifier) isAllowedChat(id int64) bool {
+	return strconv.FormatInt(id, 10) == n.cfg.TelegramChatID
+}
+
+// handleCommand 执行命令并返回回复内容
+func (n *Notifier) handleCommand(ds BotDataSource, text string, from tgUser) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	// 群组中命令形如 /status@my_bot
+	cmd := strings.ToLower(strings.SplitN(fields[0], "@", 2)[0])
+	args := fields[1:]
+
+	switch cmd {
+	case "/start", "/help":
+		return botHelp
+	case "/status":
+		return n.cmdStatus(ds)
+	case "/traffic":
+		return n.cmdTraffic(ds)
+	case "/ports":
+		return n.cmdPorts(ds)
+	case "/latency":
+		return n.cmdLatency(ds)
+	case "/quota":
+		return n.cmdQuota(ds)
+	case "/silence":
+		return n.cmdSilence(args, from)
+	default:
+		return "未知命令\n\n" + botHelp
+	}
+}
+
+// handleCallback 处理内联按钮回调（报警确认）
+func (n *Notifier) handleCallback(q *tgCallbackQuery) {
+	answer := "未知操作"
+	if idStr, ok := strings.CutPrefix(q.Data, "ack:"); ok {
+		if id, err := strconv.ParseInt(idStr, 10, 64); err == nil {
+			answer = n.ackAlert(id, q.From)
+			// 移除确认按钮
+			ctx, cancel := context.WithTimeout(n.ctx, 15*time.Second)
+			err := n.callTelegram(ctx, "editMessageReplyMarkup", map[string]interface{}{
+				"chat_id":      q.Message.Chat.ID,
+				"message_id":   q.Message.MessageID,
+				"reply_markup": map[string]interface{}{"inline_keyboard": [][]interface{}{}},
+			}, nil)
+			cancel()
+			if err != nil {
+				log.Printf("更新 Telegram 消息按钮失败: %v", err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, 15*time.Second)
+	defer cancel()
+	if err := n.callTelegram(ctx, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": q.ID,
+		"text":              answer,
+	}, nil); err != nil {
+		log.Printf("应答 Telegram 回调失败: %v", err)
+	}
+}
+
+// telegramActor 审计与确认记录中的操作者
+func telegramActor(u tgUser) string {
+	if u.Username != "" {
+		return "telegram:" + u.Username
+	}
+	return "telegram:" + strconv.FormatInt(u.ID, 10)
+}
+
+// ackAlert 确认报警
+func (n *Notifier) ackAlert(id int64, by tgUser) string {
+	actor := telegramActor(by)
+	res, err := n.db.Exec(
+		"UPDATE alert_records SET acked_at = ?, acked_by = ? WHERE id = ? AND acked_at IS NULL",
+		time.Now().Unix(), actor, id,
+	)
+	if err != nil {
+		return "确认失败"
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return "报警已确认或不存在"
+	}
+	auth.Audit(n.db, auth.AuditEntry{Event: auth.EventAlertAcked, Username: actor, Target: strconv.FormatInt(id, 10)})
+	return "已确认，本计费周期内不再提醒"
+}
+
+type botTraffic struct {
+	Tx    int64 `json:"tx"`
+	Rx    int64 `json:"rx"`
+	Total int64 `json:"total"`
+}
+
+type botStats struct {
+	ServerName     string     `json:"server_name"`
+	CurrentTime    string     `json:"current_time"`
+	Today          botTraffic `json:"today"`
+	Yesterday      botTraffic `json:"yesterday"`
+	ThisMonth      botTraffic `json:"this_month"`
+	LastMonth      botTraffic `json:"last_month"`
+	UsedBytes      int64      `json:"used_bytes"`
+	MonthlyLimitGB int        `json:"monthly_limit_gb"`
+	BillingMode    string     `json:"billing_mode"`
+	BillingStart   string     `json:"billing_start"`
+	BillingEnd     string     `json:"billing_end"`
+}
+
+// remarshal 将 API 返回的通用结构转换为类型化结构
+func remarshal(in interface{}, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (n *Notifier) cmdStatus(ds BotDataSource) string {
+	var st botStats
+	remarshal(ds.Stats(), &st)
+
+	var sys struct {
+		CPU       float64 `json:"cpu_percent"`
+		MemUsed   int64   `json:"mem_used"`
+		MemTotal  int64   `json:"mem_total"`
+		DiskUsed  int64   `json:"disk_used"`
+		DiskTotal int64   `json:"disk_total"`
+		Load1     float64 `json:"load_1"`
+		Load5     float64 `json:"load_5"`
+		Load15    float64 `json:"load_15"`
+	}
+	data, err := ds.System()
+	if err != nil {
+		return "暂无系统数据"
+	}
+	remarshal(data, &sys)
+
+	return fmt.Sprintf(`🖥 %s
+⏰ %s
+
+CPU: %.1f%%
+内存: %s / %s (%.1f%%)
+磁盘: %s / %s (%.1f%%)
+负载: %.2f %.2f %.2f
+
+今日: ↑ %s  ↓ %s`,
+		st.ServerName, st.CurrentTime,
+		sys.CPU,
+		formatBytes(sys.MemUsed), formatBytes(sys.MemTotal), percentOf(sys.MemUsed, sys.MemTotal),
+		formatBytes(sys.DiskUsed), formatBytes(sys.DiskTotal), percentOf(sys.DiskUsed, sys.DiskTotal),
+		sys.Load1, sys.Load5, sys.Load15,
+		formatBytes(st.Today.Tx), formatBytes(st.Today.Rx),
+	)
+}
+
+func (n *Notifier) cmdTraffic(ds BotDataSource) string {
+	var st botStats
+	remarshal(ds.Stats(), &st)
+
+	line := func(name string, t botTraffic) string {
+		return fmt.Sprintf("%s: ↑ %s  ↓ %s  ⇅ %s", name, formatBytes(t.Tx), formatBytes(t.Rx), formatBytes(t.Tx+t.Rx))
+	}
+	return strings.Join([]string{
+		"🌐 流量统计 [" + st.ServerName + "]",
+		"",
+		line("今日", st.Today),
+		line("昨日", st.Yesterday),
+		line("本周期", st.ThisMonth),
+		line("上月", st.LastMonth),
+	}, "\n")
+}
+
+func (n *Notifier) cmdPorts(ds BotDataSource) string {
+	var pt struct {
+		IptablesOK bool `json:"iptables_ok"`
+		Ports      []struct {
+			Port      int        `json:"port"`
+			Name      string     `json:"name"`
+			Today     botTraffic `json:"today"`
+			Yesterday botTraffic `json:"yesterday"`
+			ThisMonth botTraffic `json:"this_month"`
+		} `json:"ports"`
+	}
+	remarshal(ds.PortTraffic(), &pt)
+
+	if len(pt.Ports) == 0 {
+		return "未配置监控端口"
+	}
+	lines := []string{"🔌 端口流量"}
+	for _, p := range pt.Ports {
+		lines = append(lines, "",
+			fmt.Sprintf("%s (%d)", p.Name, p.Port),
+			fmt.Sprintf("  今日: ↑ %s  ↓ %s", formatBytes(p.Today.Tx), formatBytes(p.Today.Rx)),
+			fmt.Sprintf("  昨日: ↑ %s  ↓ %s", formatBytes(p.Yesterday.Tx), formatBytes(p.Yesterday.Rx)),
+			fmt.Sprintf("  本周期: ↑ %s  ↓ %s", formatBytes(p.ThisMonth.Tx), formatBytes(p.ThisMonth.Rx)),
+		)
+	}
+	if !pt.IptablesOK {
+		lines = append(lines, "", "⚠️ iptables 统计规则不完整")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (n *Notifier) cmdLatency(ds BotDataSource) string {
+	var lat struct {
+		Targets []struct {
+			Tag   string `json:"tag"`
+			IP    string `json:"ip"`
+			Stats struct {
+				Avg   float64 `json:"avg"`
+				Min   float64 `json:"min"`
+				Max   float64 `json:"max"`
+				Count int     `json:"count"`
+				Loss  float64 `json:"loss"`
+			} `json:"stats"`
+		} `json:"targets"`
+	}
+	remarshal(ds.Latency(), &lat)
+
+	if len(lat.Targets) == 0 {
+		return "未配置延迟监控目标"
+	}
+	lines := []string{"📡 延迟（最近 24 小时）"}
+	for _, t := range lat.Targets {
+		if t.Stats.Count == 0 {
+			lines = append(lines, fmt.Sprintf("%s: 无数据，丢包 %.1f%%", t.Tag, t.Stats.Loss))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: 平均 %.1f ms (%.1f ~ %.1f)，丢包 %.1f%%",
+			t.Tag, t.Stats.Avg, t.Stats.Min, t.Stats.Max, t.Stats.Loss))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (n *Notifier) cmdQuota(ds BotDataSource) string {
+	var st botStats
+	remarshal(ds.Stats(), &st)
+
+	if st.MonthlyLimitGB <= 0 {
+		return fmt.Sprintf("📦 本周期已用 %s（未设置限额）", formatBytes(st.UsedBytes))
+	}
+	limitBytes := int64(st.MonthlyLimitGB) * 1024 * 1024 * 1024
+	daysLeft := 0
+	if end, err := time.ParseInLocation("2006-01-02", st.BillingEnd, n.cfg.Timezone); err == nil {
+		daysLeft = int(time.Until(end.AddDate(0, 0, 1)).Hours() / 24)
+	}
+	return fmt.Sprintf(`📦 流量配额 [%s]
+
+已用: %s / %d GB (%.1f%%)
+剩余: %s
+模式: %s
+周期: %s ~ %s (%d 天后重置)`,
+		st.ServerName,
+		formatBytes(st.UsedBytes), st.MonthlyLimitGB, percentOf(st.UsedBytes, limitBytes),
+		formatBytes(max(limitBytes-st.UsedBytes, 0)),
+		st.BillingMode,
+		st.BillingStart, st.BillingEnd, daysLeft,
+	)
+}
+
+func (n *Notifier) cmdSilence(args []string, from tgUser) string {
+	if len(args) == 0 {
+		if until := n.silencedUntil(); time.Now().Before(until) {
+			return "🔕 报警静默至 " + until.In(n.cfg.Timezone).Format("2006-01-02 15:04 MST")
+		}
+		return "🔔 报警未静默\n用法: /silence 2h、/silence 1d、/silence off"
+	}
+
+	before := n.silencedUntil()
+	if strings.EqualFold(args[0], "off") {
+		if err := n.setSilence(time.Time{}); err != nil {
+			return "取消静默失败"
+		}
+		n.auditSilence(before, time.Time{}, from)
+		return "🔔 已取消报警静默"
+	}
+
+	d, err := parseSilenceDuration(args[0])
+	if err != nil || d <= 0 {
+		return "无效时长，示例: 30m、2h、1d"
+	}
+	until := time.Now().Add(d)
+	if err := n.setSilence(until); err != nil {
+		return "设置静默失败"
+	}
+	n.auditSilence(before, until, from)
+	return "🔕 报警静默至 " + until.In(n.cfg.Timezone).Format("2006-01-02 15:04 MST")
+}
+
+// auditSilence 记录静默设置的变化
+func (n *Notifier) auditSilence(before, after time.Time, from tgUser) {
+	format := func(t time.Time) string {
+		if t.IsZero() || time.Now().After(t) {
+			return ""
+		}
+		return t.In(n.cfg.Timezone).Format(time.RFC3339)
+	}
+	diff := auth.AuditDiff(map[string]string{"alert_silence_until": format(before)}, map[string]string{"alert_silence_until": format(after)})
+	if diff == "" {
+		return
+	}
+	auth.Audit(n.db, auth.AuditEntry{
+		Event: auth.EventConfigChanged, Username: telegramActor(from), Target: "alert_silence_until", Detail: diff,
+	})
+}
+
+// parseSilenceDuration 解析时长，额外支持天（d）
+func parseSilenceDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		v, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(v) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func percentOf(used, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
@@ -0,0 +1,246 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hh/heliox-mon/internal/config"
+	"github.com/hh/heliox-mon/internal/storage"
+)
+
+// fakeTelegram 模拟 Telegram Bot API
+type fakeTelegram struct {
+	mu       sync.Mutex
+	updates  []tgUpdate
+	sent     []map[string]interface{}
+	answered []string
+	edited   int
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&payload)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var result interface{} = true
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+		offset := int64(payload["offset"].(float64))
+		var pending []tgUpdate
+		for _, u := range f.updates {
+			if u.UpdateID >= offset {
+				pending = append(pending, u)
+			}
+		}
+		if len(pending) == 0 {
+			// 模拟长轮询，避免空转
+			f.mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			f.mu.Lock()
+		}
+		result = pending
+	case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+		f.sent = append(f.sent, payload)
+		result = map[string]interface{}{"message_id": len(f.sent)}
+	case strings.HasSuffix(r.URL.Path, "/answerCallbackQuery"):
+		f.answered = append(f.answered, payload["text"].(string))
+	case strings.HasSuffix(r.URL.Path, "/editMessageReplyMarkup"):
+		f.edited++
+	default:
+		http.Error(w, `{"ok":false,"description":"not found"}`, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
+
+func (f *fakeTelegram) sentTexts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var texts []string
+	for _, p := range f.sent {
+		texts = append(texts, p["text"].(string))
+	}
+	return texts
+}
+
+type fakeDataSource struct{}
+
+func (fakeDataSource) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"server_name":      "test-node",
+		"current_time":     "2026-01-01 00:00:00",
+		"today":            map[string]int64{"tx": 1024, "rx": 2048},
+		"used_bytes":       int64(512) * 1024 * 1024 * 1024,
+		"monthly_limit_gb": 1000,
+		"billing_mode":     "bidirectional",
+		"billing_start":    "2026-01-01",
+		"billing_end":      "2026-01-31",
+	}
+}
+
+func (fakeDataSource) System() (map[string]interface{}, error) {
+	return map[string]interface{}{"cpu_percent": 12.5, "mem_used": 1, "mem_total": 2}, nil
+}
+
+func (fakeDataSource) PortTraffic() map[string]interface{} {
+	return map[string]interface{}{"iptables_ok": true, "ports": []map[string]interface{}{}}
+}
+
+func (fakeDataSource) Latency() map[string]interface{} {
+	return map[string]interface{}{"targets": []map[string]interface{}{}}
+}
+
+func newTestNotifier(t *testing.T, apiBase string) *Notifier {
+	t.Helper()
+	db, err := storage.NewDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &config.Config{
+		ServerName:          "test-node",
+		Timezone:            time.UTC,
+		TelegramBotToken:    "TOKEN",
+		TelegramChatID:      "100",
+		TelegramAPIBase:     apiBase,
+		TelegramBotCommands: true,
+	}
+	return New(cfg, db)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("等待超时")
+}
+
+// TestBotCommands 命令仅响应授权会话
+func TestBotCommands(t *testing.T) {
+	tg := &fakeTelegram{updates: []tgUpdate{
+		{UpdateID: 1, Message: &tgMessage{Text: "/status", Chat: tgChat{ID: 999}}},
+		{UpdateID: 2, Message: &tgMessage{Text: "/quota@heliox_bot", Chat: tgChat{ID: 100}}},
+		{UpdateID: 3, Message: &tgMessage{Text: "/silence 2h", Chat: tgChat{ID: 100}}},
+	}}
+	srv := httptest.NewServer(tg)
+	defer srv.Close()
+
+	n := newTestNotifier(t, srv.URL)
+	n.StartBot(fakeDataSource{})
+	waitFor(t, func() bool { return len(tg.sentTexts()) >= 2 })
+	n.Stop()
+
+	texts := tg.sentTexts()
+	if len(texts) != 2 {
+		t.Fatalf("期望 2 条回复（未授权会话应被忽略），实际 %d: %q", len(texts), texts)
+	}
+	if !strings.Contains(texts[0], "512.00 GB") || !strings.Contains(texts[0], "1000 GB") {
+		t.Errorf("/quota 回复缺少用量信息: %q", texts[0])
+	}
+	if !strings.Contains(texts[1], "静默至") {
+		t.Errorf("/silence 回复异常: %q", texts[1])
+	}
+	if !time.Now().Before(n.silencedUntil()) {
+		t.Error("静默未生效")
+	}
+	for _, p := range tg.sent {
+		if p["chat_id"] != "100" {
+			t.Errorf("回复发送到了错误的会话: %v", p["chat_id"])
+		}
+	}
+}
+
+// TestAlertAck 报警确认按钮
+func TestAlertAck(t *testing.T) {
+	tg := &fakeTelegram{}
+	srv := httptest.NewServer(tg)
+	defer srv.Close()
+
+	n := newTestNotifier(t, srv.URL)
+	if err := n.SendTrafficAlert(800, 1000, 80, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "2026-01-31", 10, 80); err != nil {
+		t.Fatalf("发送报警失败: %v", err)
+	}
+	n.Start()
+	waitFor(t, func() bool { return len(tg.sentTexts()) == 1 })
+	if tg.sent[0]["reply_markup"] == nil {
+		t.Fatalf("报警消息应附带确认按钮: %v", tg.sent)
+	}
+
+	var alertID int64
+	n.db.QueryRow("SELECT id FROM alert_records").Scan(&alertID)
+
+	tg.mu.Lock()
+	tg.updates = []tgUpdate{
+		{UpdateID: 1, CallbackQuery: &tgCallbackQuery{
+			ID:      "cb1",
+			Data:    "ack:" + strconv.FormatInt(alertID, 10),
+			From:    tgUser{ID: 1, Username: "ops"},
+			Message: &tgMessage{MessageID: 1, Chat: tgChat{ID: 100}},
+		}},
+	}
+	tg.mu.Unlock()
+
+	n.StartBot(fakeDataSource{})
+	waitFor(t, func() bool {
+		tg.mu.Lock()
+		defer tg.mu.Unlock()
+		return len(tg.answered) > 0
+	})
+	n.Stop()
+
+	var ackedBy string
+	n.db.QueryRow("SELECT COALESCE(acked_by, '') FROM alert_records WHERE id = ?", alertID).Scan(&ackedBy)
+	if ackedBy != "telegram:ops" {
+		t.Errorf("acked_by = %q, want telegram:ops", ackedBy)
+	}
+	if tg.edited != 1 {
+		t.Errorf("确认后应移除按钮，edited = %d", tg.edited)
+	}
+}
+
+// TestTrafficAlertAckCycle 已确认的报警只在同一计费周期内抑制（31 天的月份与二月）
+func TestTrafficAlertAckCycle(t *testing.T) {
+	utc := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+	cases := []struct {
+		name       string
+		ackedAt    time.Time
+		cycleStart time.Time
+		resetDate  string
+		suppressed bool
+	}{
+		{"31 天月份首日确认", utc(2025, 10, 1, 8), utc(2025, 10, 1, 0), "2025-10-31", true},
+		{"31 天月份上周期确认", utc(2025, 9, 30, 8), utc(2025, 10, 1, 0), "2025-10-31", false},
+		{"二月上周期确认", utc(2026, 1, 30, 8), utc(2026, 2, 1, 0), "2026-02-28", false},
+		{"二月本周期确认", utc(2026, 2, 2, 8), utc(2026, 2, 1, 0), "2026-02-28", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := newTestNotifier(t, "http://127.0.0.1:0")
+			n.db.Exec("INSERT INTO alert_records (ts, threshold, message, acked_at) VALUES (?, 80, 'old', ?)",
+				tc.ackedAt.Unix(), tc.ackedAt.Unix())
+			if err := n.SendTrafficAlert(800, 1000, 80, tc.cycleStart, tc.resetDate, 10, 80); err != nil {
+				t.Fatal(err)
+			}
+			var count int
+			n.db.QueryRow("SELECT COUNT(*) FROM alert_records").Scan(&count)
+			if suppressed := count == 1; suppressed != tc.suppressed {
+				t.Errorf("suppressed = %v, want %v", suppressed, tc.suppressed)
+			}
+		})
+	}
+}
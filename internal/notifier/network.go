@@ -0,0 +1,85 @@
+package notifier
+
+import (
+	"fmt"
+	"time"
+)
+
+// NetworkAlert 网络质量报警（重传率、队列溢出、网卡丢包）
+type NetworkAlert struct {
+	ID        int64
+	Metric    string // tcp_retrans / listen_overflows / iface_drops
+	Scope     string // tcp / iface:X
+	Value     float64
+	Threshold float64
+	Time      time.Time
+
+	// 恢复通知
+	Recovered bool
+	Duration  time.Duration
+}
+
+// networkMetricNames 指标中文名
+var networkMetricNames = map[string]string{
+	"tcp_retrans":      "TCP 重传率",
+	"listen_overflows": "TCP 全连接队列溢出",
+	"iface_drops":      "网卡丢包/错误",
+}
+
+// formatValue 重传率为百分比，其余为每分钟次数
+func (a *NetworkAlert) formatValue(v float64) string {
+	if a.Metric == "tcp_retrans" {
+		return fmt.Sprintf("%.2f%%", v)
+	}
+	return fmt.Sprintf("%.0f/分钟", v)
+}
+
+// SendNetworkAlert 发送网络质量报警或恢复通知（写入通知队列）
+func (n *Notifier) SendNetworkAlert(a *NetworkAlert) error {
+	if n.cfg.TelegramBotToken == "" || n.cfg.TelegramChatID == "" {
+		return nil
+	}
+
+	// 静默期内不发送
+	if until := n.silencedUntil(); time.Now().Before(until) {
+		return nil
+	}
+
+	name := networkMetricNames[a.Metric]
+	if name == "" {
+		name = a.Metric
+	}
+
+	var msg, stage string
+	if a.Recovered {
+		stage = "end"
+		msg = fmt.Sprintf(`✅ 网络恢复正常 [%s]
+
+📍 %s %s
+📉 当前: %s (阈值 %s)
+⏱ 持续: %s
+
+⏰ %s`,
+			n.cfg.ServerName,
+			a.Scope, name,
+			a.formatValue(a.Value), a.formatValue(a.Threshold),
+			a.Duration.Round(time.Minute),
+			a.Time.In(n.cfg.Timezone).Format("2006-01-02 15:04 MST"),
+		)
+	} else {
+		stage = "start"
+		msg = fmt.Sprintf(`⚠️ 网络质量报警 [%s]
+
+📍 %s %s
+📈 当前: %s (阈值 %s)
+
+⏰ %s`,
+			n.cfg.ServerName,
+			a.Scope, name,
+			a.formatValue(a.Value), a.formatValue(a.Threshold),
+			a.Time.In(n.cfg.Timezone).Format("2006-01-02 15:04 MST"),
+		)
+	}
+
+	return n.enqueueTelegram(fmt.Sprintf("network:%d:%s", a.ID, stage), msg, nil)
+}
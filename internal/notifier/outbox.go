@@ -0,0 +1,251 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// 通知队列状态
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+const (
+	outboxBaseBackoff = 10 * time.Second
+	outboxMaxBackoff  = time.Hour
+	outboxRetention   = 30 * 24 * time.Hour
+)
+
+// outboxItem 待发送通知
+type outboxItem struct {
+	id       int64
+	channel  string
+	payload  string
+	attempts int
+}
+
+// Start 启动通知队列发送任务
+func (n *Notifier) Start() {
+	n.wg.Add(1)
+	go n.runOutbox()
+}
+
+// enqueue 写入通知队列，dedupKey 相同的通知只会入队一次
+func (n *Notifier) enqueue(channel, dedupKey string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var key interface{}
+	if dedupKey != "" {
+		key = dedupKey
+	}
+	now := time.Now().Unix()
+	_, err = n.db.Exec(`
+		INSERT OR IGNORE INTO notification_outbox (channel, dedup_key, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, channel, key, string(data), StatusPending, now, now)
+	if err != nil {
+		return fmt.Errorf("写入通知队列失败: %w", err)
+	}
+
+	// 唤醒发送任务
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// enqueueTelegram 将 Telegram 消息写入队列
+func (n *Notifier) enqueueTelegram(dedupKey, text string, markup interface{}) error {
+	payload := map[string]interface{}{
+		"chat_id": n.cfg.TelegramChatID,
+		"text":    text,
+	}
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+	return n.enqueue("telegram", dedupKey, payload)
+}
+
+// runOutbox 队列发送循环
+func (n *Notifier) runOutbox() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	n.drainOutbox()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+			n.drainOutbox()
+		case <-n.wake:
+			n.drainOutbox()
+		case <-cleanup.C:
+			n.pruneOutbox(time.Now())
+		}
+	}
+}
+
+// pruneOutbox 删除超过保留期的已发送与死信通知
+func (n *Notifier) pruneOutbox(now time.Time) {
+	cutoff := now.Add(-outboxRetention).Unix()
+	_, _ = n.db.Exec("DELETE FROM notification_outbox WHERE status = ? AND sent_at < ?", StatusSent, cutoff)
+	_, _ = n.db.Exec("DELETE FROM notification_outbox WHERE status = ? AND created_at < ?", StatusDead, cutoff)
+}
+
+// drainOutbox 发送所有到期的通知（受通道速率限制）
+func (n *Notifier) drainOutbox() {
+	rows, err := n.db.Query(`
+		SELECT id, channel, payload, attempts
+		FROM notification_outbox
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY id
+		LIMIT 50
+	`, StatusPending, time.Now().Unix())
+	if err != nil {
+		log.Printf("读取通知队列失败: %v", err)
+		return
+	}
+	var items []outboxItem
+	for rows.Next() {
+		var it outboxItem
+		if err := rows.Scan(&it.id, &it.channel, &it.payload, &it.attempts); err == nil {
+			items = append(items, it)
+		}
+	}
+	rows.Close()
+
+	for _, it := range items {
+		if n.ctx.Err() != nil {
+			return
+		}
+		// 通道触发限速时等待下一轮
+		if !n.allowSend(it.channel) {
+			continue
+		}
+		n.deliver(it)
+	}
+}
+
+// allowSend 按通道限速（每分钟 NotifyRatePerMinute 条）
+func (n *Notifier) allowSend(channel string) bool {
+	rate := n.cfg.NotifyRatePerMinute
+	if rate <= 0 {
+		return true
+	}
+	interval := time.Minute / time.Duration(rate)
+
+	n.rateMu.Lock()
+	defer n.rateMu.Unlock()
+	if last, ok := n.lastSent[channel]; ok && time.Since(last) < interval {
+		return false
+	}
+	n.lastSent[channel] = time.Now()
+	return true
+}
+
+// deliver 发送单条通知并更新状态
+func (n *Notifier) deliver(it outboxItem) {
+	var err error
+	switch it.channel {
+	case "telegram":
+		ctx, cancel := context.WithTimeout(n.ctx, 15*time.Second)
+		err = n.callTelegram(ctx, "sendMessage", json.RawMessage(it.payload), nil)
+		cancel()
+	default:
+		err = permanentError{fmt.Errorf("未知通知通道: %s", it.channel)}
+	}
+
+	now := time.Now()
+	if err == nil {
+		_, _ = n.db.Exec(
+			"UPDATE notification_outbox SET status = ?, attempts = attempts + 1, sent_at = ?, last_error = NULL WHERE id = ?",
+			StatusSent, now.Unix(), it.id,
+		)
+		return
+	}
+	if n.ctx.Err() != nil {
+		return // 退出中断的请求不计入重试
+	}
+
+	attempts := it.attempts + 1
+	var perm permanentError
+	if errors.As(err, &perm) || attempts >= n.maxAttempts() {
+		log.Printf("通知 #%d 发送失败，进入死信: %v", it.id, err)
+		// 释放 dedup_key，之后相同的通知可以重新入队
+		_, _ = n.db.Exec(
+			"UPDATE notification_outbox SET status = ?, attempts = ?, last_error = ?, dedup_key = NULL WHERE id = ?",
+			StatusDead, attempts, err.Error(), it.id,
+		)
+		return
+	}
+
+	delay := backoffDelay(attempts)
+	var apiErr *telegramError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		delay = time.Duration(apiErr.RetryAfter) * time.Second
+	}
+	log.Printf("通知 #%d 发送失败（第 %d 次），%s 后重试: %v", it.id, attempts, delay, err)
+	_, _ = n.db.Exec(
+		"UPDATE notification_outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		attempts, now.Add(delay).Unix(), err.Error(), it.id,
+	)
+}
+
+func (n *Notifier) maxAttempts() int {
+	if n.cfg.NotifyMaxAttempts <= 0 {
+		return 8
+	}
+	return n.cfg.NotifyMaxAttempts
+}
+
+// backoffDelay 指数退避：10s, 20s, 40s ... 最长 1 小时
+func backoffDelay(attempts int) time.Duration {
+	d := outboxBaseBackoff
+	for i := 1; i < attempts && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > outboxMaxBackoff {
+		d = outboxMaxBackoff
+	}
+	return d
+}
+
+// permanentError 不可重试的错误
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// telegramError Telegram API 错误
+type telegramError struct {
+	StatusCode  int
+	Description string
+	RetryAfter  int
+}
+
+func (e *telegramError) Error() string {
+	return fmt.Sprintf("Telegram API 返回 %d: %s", e.StatusCode, e.Description)
+}
+
+// classifyTelegramError 4xx（429 除外）为不可重试错误
+func classifyTelegramError(e *telegramError) error {
+	if e.StatusCode >= 400 && e.StatusCode < 500 && e.StatusCode != http.StatusTooManyRequests {
+		return permanentError{e}
+	}
+	return e
+}
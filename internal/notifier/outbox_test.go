@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestOutboxDeadLetter 不可重试错误直接进入死信，并按 dedup_key 去重；死信释放 dedup_key 并按保留期清理
+func TestOutboxDeadLetter(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+	}))
+	defer srv.Close()
+
+	n := newTestNotifier(t, srv.URL)
+	for i := 0; i < 2; i++ {
+		if err := n.enqueueTelegram("dup", "hello", nil); err != nil {
+			t.Fatalf("入队失败: %v", err)
+		}
+	}
+	n.drainOutbox()
+
+	var count int
+	n.db.QueryRow("SELECT COUNT(*) FROM notification_outbox").Scan(&count)
+	if count != 1 {
+		t.Errorf("相同 dedup_key 应只入队一次，实际 %d", count)
+	}
+
+	var status, lastErr string
+	var attempts int
+	n.db.QueryRow("SELECT status, attempts, last_error FROM notification_outbox").Scan(&status, &attempts, &lastErr)
+	if status != StatusDead || attempts != 1 || calls != 1 {
+		t.Errorf("status=%s attempts=%d calls=%d，期望 dead/1/1", status, attempts, calls)
+	}
+
+	// 进入死信后相同的通知可以重新入队
+	n.enqueueTelegram("dup", "hello", nil)
+	n.db.QueryRow("SELECT COUNT(*) FROM notification_outbox WHERE status = ?", StatusPending).Scan(&count)
+	if count != 1 {
+		t.Errorf("死信应释放 dedup_key，重新入队 %d 条", count)
+	}
+
+	n.pruneOutbox(time.Now().Add(outboxRetention + time.Hour))
+	n.db.QueryRow("SELECT COUNT(*) FROM notification_outbox WHERE status = ?", StatusDead).Scan(&count)
+	if count != 0 {
+		t.Errorf("超过保留期的死信应被清理，剩余 %d 条", count)
+	}
+}
+
+// TestOutboxRetry 服务端错误按退避重试，429 使用 retry_after
+func TestOutboxRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"ok":false,"description":"Too Many Requests","parameters":{"retry_after":42}}`))
+	}))
+	defer srv.Close()
+
+	n := newTestNotifier(t, srv.URL)
+	n.enqueueTelegram("", "hello", nil)
+	before := time.Now().Unix()
+	n.drainOutbox()
+
+	var status string
+	var next int64
+	n.db.QueryRow("SELECT status, next_attempt_at FROM notification_outbox").Scan(&status, &next)
+	if status != StatusPending {
+		t.Errorf("status = %s, want pending", status)
+	}
+	if d := next - before; d < 41 || d > 43 {
+		t.Errorf("下次重试间隔 = %ds, want 42s", d)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		4:  80 * time.Second,
+		20: time.Hour,
+	}
+	for attempts, want := range tests {
+		if got := backoffDelay(attempts); got != want {
+			t.Errorf("backoffDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
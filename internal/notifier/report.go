@@ -0,0 +1,129 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// Report 定时报告数据
+type Report struct {
+	Kind        string // daily / weekly / cycle
+	Title       string
+	ServerName  string
+	PeriodStart string
+	PeriodEnd   string
+	GeneratedAt string
+
+	// 周期内流量
+	Tx    int64
+	Rx    int64
+	Ports []PortUsage
+
+	// 计费周期用量
+	CycleStart   string
+	CycleEnd     string
+	CycleUsed    int64
+	CycleLimitGB int
+	CyclePercent float64
+	BillingMode  string
+
+	// 延迟与系统
+	Latency []LatencySummary
+	PeakCPU float64
+}
+
+// PortUsage 端口流量
+type PortUsage struct {
+	Port int
+	Name string
+	Tx   int64
+	Rx   int64
+}
+
+// LatencySummary 延迟汇总
+type LatencySummary struct {
+	Tag         string
+	AvgMs       float64
+	HasRTT      bool
+	LossPercent float64
+}
+
+// defaultReportTemplate 默认报告模板（可在模板目录放置 <kind>.tmpl 覆盖）
+const defaultReportTemplate = `{{.Title}} [{{.ServerName}}]
+📅 {{.PeriodStart}}{{if ne .PeriodStart .PeriodEnd}} ~ {{.PeriodEnd}}{{end}}
+
+🌐 流量: ↑ {{bytes .Tx}}  ↓ {{bytes .Rx}}  ⇅ {{bytes (add .Tx .Rx)}}
+{{- range .Ports}}
+  · {{.Name}}: ↑ {{bytes .Tx}}  ↓ {{bytes .Rx}}
+{{- end}}
+
+📦 计费周期: {{bytes .CycleUsed}}{{if gt .CycleLimitGB 0}} / {{.CycleLimitGB}} GB ({{printf "%.1f" .CyclePercent}}%){{end}}
+   {{.CycleStart}} ~ {{.CycleEnd}}
+{{- if .Latency}}
+
+📡 延迟:
+{{- range .Latency}}
+  · {{.Tag}}: {{if .HasRTT}}{{printf "%.1f" .AvgMs}} ms{{else}}-{{end}}, 丢包 {{printf "%.1f" .LossPercent}}%
+{{- end}}
+{{- end}}
+
+🔥 CPU 峰值: {{printf "%.1f" .PeakCPU}}%
+
+⏰ {{.GeneratedAt}}`
+
+var reportFuncs = template.FuncMap{
+	"bytes": formatBytes,
+	"add":   func(a, b int64) int64 { return a + b },
+}
+
+// SendReport 发送定时报告（写入通知队列）
+func (n *Notifier) SendReport(r *Report) error {
+	if n.cfg.TelegramBotToken == "" || n.cfg.TelegramChatID == "" {
+		return nil
+	}
+
+	msg, err := n.renderReport(r)
+	if err != nil {
+		return err
+	}
+	return n.enqueueTelegram(fmt.Sprintf("report:%s:%s:%s", r.Kind, r.PeriodStart, r.PeriodEnd), msg, nil)
+}
+
+// renderReport 渲染报告，优先使用模板目录下的自定义模板
+func (n *Notifier) renderReport(r *Report) (string, error) {
+	text := defaultReportTemplate
+	if n.cfg.ReportTemplateDir != "" {
+		path := filepath.Join(n.cfg.ReportTemplateDir, r.Kind+".tmpl")
+		if data, err := os.ReadFile(path); err == nil {
+			text = string(data)
+		}
+	}
+
+	tmpl, err := template.New(r.Kind).Funcs(reportFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("解析报告模板失败: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r); err != nil {
+		return "", fmt.Errorf("渲染报告模板失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// formatBytes 字节数转可读字符串
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(b)/float64(div), "KMGTP"[exp])
+}
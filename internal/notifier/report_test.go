@@ -0,0 +1,93 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderReport(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "daily.tmpl"), []byte(`{{.ServerName}} {{bytes (add .Tx .Rx)}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "cycle.tmpl"), []byte(`{{.Missing`), 0644)
+
+	n := newTestNotifier(t, "http://127.0.0.1:0")
+	r := &Report{
+		Title:       "📊 每日报告",
+		ServerName:  "test-node",
+		PeriodStart: "2026-03-01",
+		PeriodEnd:   "2026-03-01",
+		Tx:          1536,
+		Rx:          512,
+		Ports:       []PortUsage{{Port: 443, Name: "VLESS", Tx: 1024}},
+	}
+
+	cases := []struct {
+		name     string
+		kind     string
+		dir      string
+		contains []string
+		absent   []string
+		wantErr  bool
+	}{
+		{"默认模板", "daily", "", []string{"📊 每日报告 [test-node]", "📅 2026-03-01\n", "⇅ 2.00 KB", "VLESS: ↑ 1.00 KB"}, []string{"~ 2026-03-01", "📡"}, false},
+		{"自定义模板", "daily", dir, []string{"test-node 2.00 KB"}, []string{"📅"}, false},
+		{"没有对应模板时回退默认", "weekly", dir, []string{"📊 每日报告 [test-node]"}, nil, false},
+		{"模板语法错误", "cycle", dir, nil, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n.cfg.ReportTemplateDir = tc.dir
+			rr := *r
+			rr.Kind = tc.kind
+			msg, err := n.renderReport(&rr)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v", err)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(msg, s) {
+					t.Errorf("缺少 %q:\n%s", s, msg)
+				}
+			}
+			for _, s := range tc.absent {
+				if strings.Contains(msg, s) {
+					t.Errorf("不应包含 %q:\n%s", s, msg)
+				}
+			}
+		})
+	}
+}
+
+// TestSendReportDedup 同一报告重复发送只入队一次
+func TestSendReportDedup(t *testing.T) {
+	n := newTestNotifier(t, "http://127.0.0.1:0")
+	r := &Report{Kind: "weekly", PeriodStart: "2026-02-23", PeriodEnd: "2026-03-01"}
+	for i := 0; i < 2; i++ {
+		if err := n.SendReport(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var count int
+	n.db.QueryRow("SELECT COUNT(*) FROM notification_outbox").Scan(&count)
+	if count != 1 {
+		t.Errorf("入队 %d 条，want 1", count)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:              "0 B",
+		1023:           "1023 B",
+		1024:           "1.00 KB",
+		1536 * 1024:    "1.50 MB",
+		5 << 30:        "5.00 GB",
+		3 << 40:        "3.00 TB",
+		int64(2) << 50: "2.00 PB",
+	}
+	for in, want := range cases {
+		if got := formatBytes(in); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
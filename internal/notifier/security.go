@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SecurityAlert 登录安全报警（连续失败触发锁定）
+type SecurityAlert struct {
+	Key      string // ip:<地址> 或 user:<用户名>
+	Failures int
+	Until    time.Time // 锁定到期时间
+	IP       string    // 最近一次失败的来源
+	Username string    // 最近一次尝试的用户名
+	Time     time.Time
+}
+
+// SendSecurityAlert 发送登录锁定报警（写入通知队列）
+func (n *Notifier) SendSecurityAlert(a *SecurityAlert) error {
+	if n.cfg.TelegramBotToken == "" || n.cfg.TelegramChatID == "" {
+		return nil
+	}
+
+	// 静默期内不发送
+	if until := n.silencedUntil(); time.Now().Before(until) {
+		return nil
+	}
+
+	target := "IP " + strings.TrimPrefix(a.Key, "ip:")
+	if strings.HasPrefix(a.Key, "user:") {
+		target = "用户 " + strings.TrimPrefix(a.Key, "user:")
+	}
+
+	msg := fmt.Sprintf(`🔐 登录失败次数过多 [%s]
+
+🚫 已锁定: %s
+🔢 连续失败: %d 次
+🌐 最近来源: %s（用户名 %s）
+⏳ 解锁时间: %s
+
+⏰ %s`,
+		n.cfg.ServerName,
+		target,
+		a.Failures,
+		a.IP, a.Username,
+		a.Until.In(n.cfg.Timezone).Format("15:04 MST"),
+		a.Time.In(n.cfg.Timezone).Format("2006-01-02 15:04 MST"),
+	)
+
+	return n.enqueueTelegram(fmt.Sprintf("security:%s:%d", a.Key, a.Until.Unix()), msg, nil)
+}
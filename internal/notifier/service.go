@@ -0,0 +1,60 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ServiceAlert 代理服务状态报警
+type ServiceAlert struct {
+	ID       int64
+	Name     string
+	Port     int
+	Event    string // down / up / restart
+	Detail   string
+	Process  string
+	PID      int
+	Unit     string
+	Downtime time.Duration // 恢复时的宕机时长
+	Time     time.Time
+}
+
+// SendServiceAlert 发送服务宕机 / 恢复 / 重启通知（写入通知队列）
+func (n *Notifier) SendServiceAlert(a *ServiceAlert) error {
+	if n.cfg.TelegramBotToken == "" || n.cfg.TelegramChatID == "" {
+		return nil
+	}
+
+	// 静默期内不发送
+	if until := n.silencedUntil(); time.Now().Before(until) {
+		return nil
+	}
+
+	var b strings.Builder
+	switch a.Event {
+	case "down":
+		fmt.Fprintf(&b, "🔴 服务宕机 [%s]\n\n", n.cfg.ServerName)
+	case "up":
+		fmt.Fprintf(&b, "🟢 服务恢复 [%s]\n\n", n.cfg.ServerName)
+	default:
+		fmt.Fprintf(&b, "🔄 服务重启 [%s]\n\n", n.cfg.ServerName)
+	}
+	fmt.Fprintf(&b, "📍 %s (端口 %d)\n", a.Name, a.Port)
+	if a.Process != "" {
+		fmt.Fprintf(&b, "⚙️ 进程: %s (PID %d)", a.Process, a.PID)
+		if a.Unit != "" {
+			fmt.Fprintf(&b, " · %s", a.Unit)
+		}
+		b.WriteString("\n")
+	}
+	if a.Detail != "" {
+		fmt.Fprintf(&b, "📝 %s\n", a.Detail)
+	}
+	if a.Downtime > 0 {
+		fmt.Fprintf(&b, "⏱ 宕机时长: %s\n", a.Downtime.Round(time.Second))
+	}
+	fmt.Fprintf(&b, "\n⏰ %s", a.Time.In(n.cfg.Timezone).Format("2006-01-02 15:04:05 MST"))
+
+	return n.enqueueTelegram(fmt.Sprintf("service:%d:%d", a.Port, a.ID), b.String(), nil)
+}
@@ -3,9 +3,12 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/hh/heliox-mon/internal/config"
@@ -14,21 +17,51 @@ import (
 
 // Notifier 通知发送器
 type Notifier struct {
-	cfg *config.Config
-	db  *storage.DB
+	cfg    *config.Config
+	db     *storage.DB
+	client *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// 通知队列
+	wake     chan struct{}
+	rateMu   sync.Mutex
+	lastSent map[string]time.Time
 }
 
 // New 创建通知器
 func New(cfg *config.Config, db *storage.DB) *Notifier {
-	return &Notifier{cfg: cfg, db: db}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		cfg:      cfg,
+		db:       db,
+		client:   &http.Client{},
+		ctx:      ctx,
+		cancel:   cancel,
+		wake:     make(chan struct{}, 1),
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// Stop 停止后台任务
+func (n *Notifier) Stop() {
+	n.cancel()
+	n.wg.Wait()
 }
 
-// SendTrafficAlert 发送流量报警
-func (n *Notifier) SendTrafficAlert(usedGB, limitGB int, percent float64, resetDate string, daysLeft int, threshold int) error {
+// SendTrafficAlert 发送流量报警，cycleStart 为当前计费周期的开始时间
+func (n *Notifier) SendTrafficAlert(usedGB, limitGB int, percent float64, cycleStart time.Time, resetDate string, daysLeft int, threshold int) error {
 	if n.cfg.TelegramBotToken == "" || n.cfg.TelegramChatID == "" {
 		return nil
 	}
 
+	// 静默期内不发送
+	if until := n.silencedUntil(); time.Now().Before(until) {
+		return nil
+	}
+
 	// 检查冷却期（同级别 24 小时内不重复发送）
 	cutoff := time.Now().Add(-24 * time.Hour).Unix()
 	var count int
@@ -37,6 +70,12 @@ func (n *Notifier) SendTrafficAlert(usedGB, limitGB int, percent float64, resetD
 		return nil // 冷却期内
 	}
 
+	// 已确认的报警在本计费周期内不再提醒
+	n.db.QueryRow("SELECT COUNT(*) FROM alert_records WHERE threshold = ? AND ts >= ? AND acked_at IS NOT NULL", threshold, cycleStart.Unix()).Scan(&count)
+	if count > 0 {
+		return nil
+	}
+
 	// 构造消息
 	msg := fmt.Sprintf(`⚠️ 流量预警 [%s]
 
@@ -52,36 +91,115 @@ func (n *Notifier) SendTrafficAlert(usedGB, limitGB int, percent float64, resetD
 		time.Now().In(n.cfg.Timezone).Format("2006-01-02 15:04 MST"),
 	)
 
-	if err := n.sendTelegram(msg); err != nil {
+	// 记录报警（入队即视为已报警，发送失败由队列重试，避免恢复后重复刷屏）
+	res, err := n.db.Exec("INSERT INTO alert_records (ts, threshold, message) VALUES (?, ?, ?)",
+		time.Now().Unix(), threshold, msg)
+	if err != nil {
 		return err
 	}
+	alertID, _ := res.LastInsertId()
 
-	// 记录报警
-	n.db.Exec("INSERT INTO alert_records (ts, threshold, message) VALUES (?, ?, ?)",
-		time.Now().Unix(), threshold, msg)
+	dedupKey := fmt.Sprintf("traffic:%s:%d:%s", resetDate, threshold, time.Now().In(n.cfg.Timezone).Format("2006-01-02"))
+	if err := n.enqueueTelegram(dedupKey, msg, ackKeyboard(alertID)); err != nil {
+		n.db.Exec("DELETE FROM alert_records WHERE id = ?", alertID)
+		return err
+	}
 
 	return nil
 }
 
-// sendTelegram 发送 Telegram 消息
-func (n *Notifier) sendTelegram(text string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", n.cfg.TelegramBotToken)
+// silencedUntil 返回报警静默截止时间
+func (n *Notifier) silencedUntil() time.Time {
+	var value string
+	if err := n.db.QueryRow("SELECT value FROM config WHERE key = 'alert_silence_until'").Scan(&value); err != nil {
+		return time.Time{}
+	}
+	ts, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
 
-	payload := map[string]string{
-		"chat_id": n.cfg.TelegramChatID,
+// setSilence 设置报警静默截止时间（零值表示取消静默）
+func (n *Notifier) setSilence(until time.Time) error {
+	if until.IsZero() {
+		_, err := n.db.Exec("DELETE FROM config WHERE key = 'alert_silence_until'")
+		return err
+	}
+	_, err := n.db.Exec(`
+		INSERT INTO config (key, value) VALUES ('alert_silence_until', ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, strconv.FormatInt(until.Unix(), 10))
+	return err
+}
+
+// ackKeyboard 报警确认按钮
+func ackKeyboard(alertID int64) interface{} {
+	return map[string]interface{}{
+		"inline_keyboard": [][]map[string]string{{
+			{"text": "✅ 确认", "callback_data": fmt.Sprintf("ack:%d", alertID)},
+		}},
+	}
+}
+
+// sendTelegramMessage 直接发送消息到指定会话（用于机器人即时回复，不经过队列）
+func (n *Notifier) sendTelegramMessage(chatID, text string, markup interface{}) error {
+	payload := map[string]interface{}{
+		"chat_id": chatID,
 		"text":    text,
 	}
-	body, _ := json.Marshal(payload)
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, 15*time.Second)
+	defer cancel()
+	return n.callTelegram(ctx, "sendMessage", payload, nil)
+}
+
+// callTelegram 调用 Telegram Bot API，result 非空时解析返回结果
+func (n *Notifier) callTelegram(ctx context.Context, method string, payload interface{}, result interface{}) error {
+	url := fmt.Sprintf("%s/bot%s/%s", n.cfg.TelegramAPIBase, n.cfg.TelegramBotToken, method)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	resp, err := n.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Telegram API 返回 %d", resp.StatusCode)
+	var apiResp struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil && resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("解析 Telegram 响应失败: %w", err)
 	}
 
+	if resp.StatusCode != http.StatusOK || !apiResp.OK {
+		return classifyTelegramError(&telegramError{
+			StatusCode:  resp.StatusCode,
+			Description: apiResp.Description,
+			RetryAfter:  apiResp.Parameters.RetryAfter,
+		})
+	}
+
+	if result != nil {
+		return json.Unmarshal(apiResp.Result, result)
+	}
 	return nil
 }
@@ -0,0 +1,116 @@
+// Package realtime 实时网速环形缓冲与 SSE 广播
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate 上传/下载速率（字节/秒）
+type Rate struct {
+	Tx float64 `json:"tx"`
+	Rx float64 `json:"rx"`
+}
+
+// Sample 一次实时采样
+type Sample struct {
+	TS     time.Time
+	Total  Rate
+	Ifaces map[string]Rate
+	Ports  map[int]Rate
+}
+
+// Hub 固定容量的环形缓冲，新样本同时广播给所有订阅者
+type Hub struct {
+	mu   sync.RWMutex
+	buf  []Sample
+	next int // 下一个写入位置
+	full bool
+	subs map[chan Sample]struct{}
+}
+
+// NewHub 创建容量为 capacity 个样本的缓冲
+func NewHub(capacity int) *Hub {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Hub{
+		buf:  make([]Sample, capacity),
+		subs: make(map[chan Sample]struct{}),
+	}
+}
+
+// Publish 写入样本并广播，订阅者处理不过来时丢弃该样本而不阻塞采集
+func (h *Hub) Publish(s Sample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf[h.next] = s
+	h.next = (h.next + 1) % len(h.buf)
+	if h.next == 0 {
+		h.full = true
+	}
+
+	for ch := range h.subs {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+}
+
+// Since 返回 since 之后（不含）的样本，按时间升序
+func (h *Hub) Since(since time.Time) []Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n, start := h.next, 0
+	if h.full {
+		n, start = len(h.buf), h.next
+	}
+	var out []Sample
+	for i := 0; i < n; i++ {
+		s := h.buf[(start+i)%len(h.buf)]
+		if s.TS.After(since) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Latest 最新一个样本
+func (h *Hub) Latest() (Sample, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.full && h.next == 0 {
+		return Sample{}, false
+	}
+	return h.buf[(h.next-1+len(h.buf))%len(h.buf)], true
+}
+
+// Subscribe 订阅新样本，返回的函数用于取消订阅（取消后通道关闭）
+func (h *Hub) Subscribe() (<-chan Sample, func()) {
+	ch := make(chan Sample, 16)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Subscribers 当前订阅者数量
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
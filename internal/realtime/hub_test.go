@@ -0,0 +1,66 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+// TestHubRing 测试环形缓冲覆盖与按时间读取
+func TestHubRing(t *testing.T) {
+	h := NewHub(4)
+	if _, ok := h.Latest(); ok {
+		t.Fatal("空缓冲不应有最新样本")
+	}
+
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		h.Publish(Sample{TS: base.Add(time.Duration(i) * 250 * time.Millisecond), Total: Rate{Tx: float64(i)}})
+	}
+
+	// 容量 4，只保留 2~5
+	all := h.Since(time.Time{})
+	if len(all) != 4 || all[0].Total.Tx != 2 || all[3].Total.Tx != 5 {
+		t.Fatalf("Since(零值) = %+v", all)
+	}
+	recent := h.Since(base.Add(750 * time.Millisecond))
+	if len(recent) != 2 || recent[0].Total.Tx != 4 {
+		t.Errorf("Since(750ms) = %+v", recent)
+	}
+	if latest, ok := h.Latest(); !ok || latest.Total.Tx != 5 {
+		t.Errorf("Latest = %+v", latest)
+	}
+}
+
+// TestHubSubscribe 测试广播与取消订阅
+func TestHubSubscribe(t *testing.T) {
+	h := NewHub(8)
+	ch1, cancel1 := h.Subscribe()
+	ch2, cancel2 := h.Subscribe()
+	defer cancel2()
+
+	h.Publish(Sample{Total: Rate{Rx: 1}})
+	for _, ch := range []<-chan Sample{ch1, ch2} {
+		select {
+		case s := <-ch:
+			if s.Total.Rx != 1 {
+				t.Errorf("收到 %+v", s)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("未收到广播")
+		}
+	}
+
+	cancel1()
+	cancel1() // 重复取消不应 panic
+	if _, ok := <-ch1; ok {
+		t.Error("取消订阅后通道应关闭")
+	}
+	if n := h.Subscribers(); n != 1 {
+		t.Errorf("订阅者数 = %d, 期望 1", n)
+	}
+
+	// 订阅者不读取时发布不阻塞
+	for i := 0; i < 100; i++ {
+		h.Publish(Sample{})
+	}
+}
@@ -23,11 +23,12 @@ func NewDB(dataDir string) (*DB, error) {
 	}
 
 	dbPath := filepath.Join(dataDir, "heliox-mon.db")
-	// WAL 模式 + 优化参数
-	// - busy_timeout=10000: 锁等待 10 秒
+	// WAL 模式 + 优化参数（modernc.org/sqlite 只识别 _pragma=name(value) 形式，每个连接建立时执行）
+	// - busy_timeout=10000: 锁等待 10 秒（放在最前，切换 WAL 时也可能需要等锁）
 	// - cache_size=-64000: 64MB 内存缓存
 	// - temp_store=2: 临时表存内存
-	db, err := sql.Open("sqlite", dbPath+"?_journal_mode=WAL&_busy_timeout=10000&_synchronous=NORMAL&cache_size=-64000&temp_store=2")
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"+
+		"&_pragma=cache_size(-64000)&_pragma=temp_store(2)")
 	if err != nil {
 		return nil, fmt.Errorf("打开数据库失败: %w", err)
 	}
@@ -101,6 +102,20 @@ func migrate(db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_latency_ts ON latency_records(ts)`,
 		`CREATE INDEX IF NOT EXISTS idx_latency_target ON latency_records(target)`,
 
+		// 端口连接数（分钟级原始数据，48 小时后降采样为小时级）
+		`CREATE TABLE IF NOT EXISTS port_connections (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ts INTEGER NOT NULL,
+			port INTEGER NOT NULL,
+			tcp REAL NOT NULL,
+			udp REAL NOT NULL,
+			clients REAL NOT NULL,
+			tcp_max INTEGER NOT NULL,
+			udp_max INTEGER NOT NULL,
+			is_aggregated INTEGER DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_port_connections_ts ON port_connections(port, ts)`,
+
 		// 系统资源快照
 		`CREATE TABLE IF NOT EXISTS system_metrics (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -116,6 +131,231 @@ func migrate(db *sql.DB) error {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_system_metrics_ts ON system_metrics(ts)`,
 
+		// 文件系统用量（每分钟）
+		`CREATE TABLE IF NOT EXISTS disk_usage (
+			ts INTEGER NOT NULL,
+			mount TEXT NOT NULL,
+			device TEXT NOT NULL,
+			fstype TEXT NOT NULL,
+			total INTEGER NOT NULL,
+			used INTEGER NOT NULL,
+			avail INTEGER NOT NULL,
+			inodes_total INTEGER NOT NULL,
+			inodes_used INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_disk_usage_ts ON disk_usage(ts, mount)`,
+
+		// 块设备 I/O 速率（与 system_metrics 同频）
+		`CREATE TABLE IF NOT EXISTS disk_io (
+			ts INTEGER NOT NULL,
+			device TEXT NOT NULL,
+			read_bps REAL NOT NULL,
+			write_bps REAL NOT NULL,
+			read_iops REAL NOT NULL,
+			write_iops REAL NOT NULL,
+			util_percent REAL NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_disk_io_ts ON disk_io(ts, device)`,
+
+		// 系统资源历史汇总（tier 为时间桶秒数：60 / 900 / 3600）
+		`CREATE TABLE IF NOT EXISTS system_metrics_rollup (
+			tier INTEGER NOT NULL,
+			ts INTEGER NOT NULL,
+			samples INTEGER NOT NULL,
+			cpu_percent_avg REAL,
+			cpu_percent_max REAL,
+			cpu_iowait_avg REAL,
+			cpu_iowait_max REAL,
+			cpu_steal_avg REAL,
+			cpu_steal_max REAL,
+			cpu_softirq_avg REAL,
+			cpu_softirq_max REAL,
+			mem_used_avg REAL,
+			mem_used_max REAL,
+			swap_used_avg REAL,
+			swap_used_max REAL,
+			load_1_avg REAL,
+			load_1_max REAL,
+			psi_cpu_some_avg10_avg REAL,
+			psi_cpu_some_avg10_max REAL,
+			psi_mem_some_avg10_avg REAL,
+			psi_mem_some_avg10_max REAL,
+			psi_io_some_avg10_avg REAL,
+			psi_io_some_avg10_max REAL,
+			mem_total_max INTEGER,
+			disk_used_max INTEGER,
+			disk_total_max INTEGER,
+			PRIMARY KEY (tier, ts)
+		)`,
+
+		// 进程资源（每分钟，命中规则的进程 + CPU Top-N）
+		`CREATE TABLE IF NOT EXISTS process_metrics (
+			ts INTEGER NOT NULL,
+			pid INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			cmdline TEXT,
+			matched TEXT,
+			cpu_percent REAL NOT NULL,
+			rss INTEGER NOT NULL,
+			threads INTEGER NOT NULL,
+			fds INTEGER NOT NULL,
+			read_bps REAL NOT NULL,
+			write_bps REAL NOT NULL,
+			rchar_bps REAL NOT NULL,
+			wchar_bps REAL NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_process_metrics_ts ON process_metrics(ts, name)`,
+
+		// Docker 容器资源（每分钟）；host 网络模式的容器没有独立的网络计数，网络列为 NULL
+		`CREATE TABLE IF NOT EXISTS container_metrics (
+			ts INTEGER NOT NULL,
+			container_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			image TEXT,
+			cpu_percent REAL NOT NULL,
+			mem_current INTEGER NOT NULL,
+			mem_max INTEGER NOT NULL,
+			io_read_bps REAL NOT NULL,
+			io_write_bps REAL NOT NULL,
+			host_network INTEGER NOT NULL DEFAULT 0,
+			net_rx_bytes INTEGER,
+			net_tx_bytes INTEGER,
+			net_rx_bps REAL,
+			net_tx_bps REAL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_container_metrics_ts ON container_metrics(ts, name)`,
+
+		// 网卡包数 / 错误 / 丢包（每分钟增量）
+		`CREATE TABLE IF NOT EXISTS net_iface_stats (
+			ts INTEGER NOT NULL,
+			iface TEXT NOT NULL,
+			rx_packets INTEGER NOT NULL,
+			tx_packets INTEGER NOT NULL,
+			rx_errs INTEGER NOT NULL,
+			tx_errs INTEGER NOT NULL,
+			rx_drop INTEGER NOT NULL,
+			tx_drop INTEGER NOT NULL,
+			rx_fifo INTEGER NOT NULL,
+			tx_fifo INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_net_iface_stats_ts ON net_iface_stats(ts, iface)`,
+
+		// TCP / UDP 协议栈计数（每分钟增量，来自 /proc/net/snmp 与 /proc/net/netstat）
+		`CREATE TABLE IF NOT EXISTS net_tcp_stats (
+			ts INTEGER PRIMARY KEY,
+			out_segs INTEGER NOT NULL,
+			retrans_segs INTEGER NOT NULL,
+			retrans_percent REAL NOT NULL,
+			in_errs INTEGER NOT NULL,
+			out_rsts INTEGER NOT NULL,
+			estab_resets INTEGER NOT NULL,
+			attempt_fails INTEGER NOT NULL,
+			listen_overflows INTEGER NOT NULL,
+			listen_drops INTEGER NOT NULL,
+			syn_retrans INTEGER NOT NULL,
+			timeouts INTEGER NOT NULL,
+			udp_in_errors INTEGER NOT NULL,
+			udp_rcvbuf_errors INTEGER NOT NULL
+		)`,
+
+		// 网络质量报警记录
+		`CREATE TABLE IF NOT EXISTS net_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ts INTEGER NOT NULL,
+			metric TEXT NOT NULL,
+			scope TEXT NOT NULL,
+			value REAL NOT NULL,
+			threshold REAL NOT NULL,
+			ended_at INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_net_alerts_ts ON net_alerts(ts)`,
+
+		// 登录会话（只保存 Cookie 值的 SHA-256）
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			token_hash TEXT NOT NULL UNIQUE,
+			username TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			last_seen INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL,
+			ip TEXT,
+			user_agent TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_username ON sessions(username)`,
+
+		// 用户（bcrypt 哈希，角色 admin/viewer）
+		`CREATE TABLE IF NOT EXISTS users (
+			username TEXT PRIMARY KEY,
+			password_hash TEXT NOT NULL,
+			role TEXT NOT NULL DEFAULT 'viewer',
+			source TEXT NOT NULL DEFAULT 'local',
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		)`,
+
+		// API Token（只保存 SHA-256，scopes/allow_ips 为逗号分隔）
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			username TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			allow_ips TEXT,
+			created_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL DEFAULT 0,
+			last_used_at INTEGER,
+			last_used_ip TEXT
+		)`,
+
+		// 两步验证（TOTP 密钥；enabled=0 表示已生成但尚未用验证码确认）
+		`CREATE TABLE IF NOT EXISTS user_totp (
+			username TEXT PRIMARY KEY,
+			secret TEXT NOT NULL,
+			enabled INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL,
+			last_step INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+			username TEXT NOT NULL,
+			code_hash TEXT NOT NULL,
+			used_at INTEGER,
+			PRIMARY KEY (username, code_hash)
+		)`,
+
+		// 登录失败计数（key 为 ip:<地址> 或 user:<用户名>）
+		`CREATE TABLE IF NOT EXISTS login_failures (
+			key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL,
+			last_failure INTEGER NOT NULL,
+			blocked_until INTEGER NOT NULL DEFAULT 0
+		)`,
+
+		// 安全审计日志（只允许追加，触发器拒绝修改和删除）
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ts INTEGER NOT NULL,
+			event TEXT NOT NULL,
+			username TEXT,
+			ip TEXT,
+			user_agent TEXT,
+			detail TEXT,
+			target TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_ts ON audit_log(ts)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_event ON audit_log(event, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_username ON audit_log(username, id)`,
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END`,
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END`,
+
+		// 系统资源日汇总（峰值）
+		`CREATE TABLE IF NOT EXISTS system_daily (
+			date TEXT PRIMARY KEY,
+			cpu_max REAL NOT NULL DEFAULT 0,
+			load_max REAL NOT NULL DEFAULT 0
+		)`,
+
 		// 报警记录（用于冷却）
 		`CREATE TABLE IF NOT EXISTS alert_records (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -125,6 +365,106 @@ func migrate(db *sql.DB) error {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_alert_ts ON alert_records(ts)`,
 
+		// 计费周期归档（周期重置后写入，之后不再变化）
+		`CREATE TABLE IF NOT EXISTS billing_cycles (
+			start_date TEXT PRIMARY KEY,
+			end_date TEXT NOT NULL,
+			tx_bytes INTEGER NOT NULL,
+			rx_bytes INTEGER NOT NULL,
+			billed_bytes INTEGER NOT NULL,
+			billing_mode TEXT NOT NULL,
+			limit_gb INTEGER NOT NULL,
+			peak_date TEXT,
+			peak_bytes INTEGER NOT NULL DEFAULT 0,
+			finalized_at INTEGER NOT NULL
+		)`,
+
+		// 计费周期端口明细
+		`CREATE TABLE IF NOT EXISTS billing_cycle_ports (
+			start_date TEXT NOT NULL,
+			port INTEGER NOT NULL,
+			tx_bytes INTEGER NOT NULL,
+			rx_bytes INTEGER NOT NULL,
+			PRIMARY KEY (start_date, port)
+		)`,
+
+		// 定时报告发送记录（用于去重）
+		`CREATE TABLE IF NOT EXISTS report_records (
+			kind TEXT NOT NULL,
+			period TEXT NOT NULL,
+			ts INTEGER NOT NULL,
+			PRIMARY KEY (kind, period)
+		)`,
+
+		// 通知发送队列（持久化，失败按指数退避重试）
+		`CREATE TABLE IF NOT EXISTS notification_outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			channel TEXT NOT NULL,
+			dedup_key TEXT UNIQUE,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at INTEGER NOT NULL,
+			last_error TEXT,
+			created_at INTEGER NOT NULL,
+			sent_at INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_status ON notification_outbox(status, next_attempt_at)`,
+
+		// 流量基线（按周内小时的 EWMA 均值/方差）
+		`CREATE TABLE IF NOT EXISTS traffic_baseline (
+			scope TEXT NOT NULL,
+			direction TEXT NOT NULL,
+			hour_of_week INTEGER NOT NULL,
+			mean REAL NOT NULL,
+			variance REAL NOT NULL,
+			samples INTEGER NOT NULL,
+			PRIMARY KEY (scope, direction, hour_of_week)
+		)`,
+
+		// 流量异常记录
+		`CREATE TABLE IF NOT EXISTS traffic_anomalies (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ts INTEGER NOT NULL,
+			scope TEXT NOT NULL,
+			direction TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			rate REAL NOT NULL,
+			baseline REAL NOT NULL,
+			stddev REAL NOT NULL,
+			zscore REAL NOT NULL,
+			ended_at INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_traffic_anomalies_ts ON traffic_anomalies(ts)`,
+
+		// 代理服务状态（每个端口一行）
+		`CREATE TABLE IF NOT EXISTS service_status (
+			port INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			state TEXT NOT NULL,
+			since INTEGER NOT NULL,
+			last_check INTEGER NOT NULL,
+			pid INTEGER,
+			process TEXT,
+			unit TEXT,
+			started_at INTEGER,
+			restarts INTEGER NOT NULL DEFAULT 0,
+			tcp_listen INTEGER NOT NULL DEFAULT 0,
+			udp_bound INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT
+		)`,
+
+		// 代理服务事件（宕机 / 恢复 / 重启）
+		`CREATE TABLE IF NOT EXISTS service_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ts INTEGER NOT NULL,
+			port INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			event TEXT NOT NULL,
+			detail TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_service_events_ts ON service_events(ts)`,
+
 		// 配置表
 		`CREATE TABLE IF NOT EXISTS config (
 			key TEXT PRIMARY KEY,
@@ -142,5 +482,34 @@ func migrate(db *sql.DB) error {
 	_, _ = db.Exec("ALTER TABLE latency_records ADD COLUMN sent INTEGER DEFAULT 0")
 	_, _ = db.Exec("ALTER TABLE latency_records ADD COLUMN lost INTEGER DEFAULT 0")
 
+	// 兼容旧版本（报警确认字段）
+	_, _ = db.Exec("ALTER TABLE alert_records ADD COLUMN acked_at INTEGER")
+	_, _ = db.Exec("ALTER TABLE alert_records ADD COLUMN acked_by TEXT")
+
+	// 兼容旧版本（审计日志操作对象）
+	_, _ = db.Exec("ALTER TABLE audit_log ADD COLUMN target TEXT")
+
+	// 兼容旧版本（CPU 使用率分解，cpu_cores 为各核心使用率 JSON 数组）
+	for _, col := range []string{"cpu_user", "cpu_system", "cpu_iowait", "cpu_irq", "cpu_softirq", "cpu_steal"} {
+		_, _ = db.Exec("ALTER TABLE system_metrics ADD COLUMN " + col + " REAL DEFAULT 0")
+	}
+	_, _ = db.Exec("ALTER TABLE system_metrics ADD COLUMN cpu_cores TEXT")
+
+	// 兼容旧版本（内存 / swap 明细与 OOM 计数）
+	for _, col := range []string{"mem_buffers", "mem_cached", "mem_dirty", "mem_writeback", "swap_used", "swap_total", "oom_kills"} {
+		_, _ = db.Exec("ALTER TABLE system_metrics ADD COLUMN " + col + " INTEGER DEFAULT 0")
+	}
+
+	// 兼容旧版本（PSI 压力指标，内核不支持时为 NULL）
+	for _, col := range []string{
+		"psi_cpu_some_avg10 REAL", "psi_cpu_some_avg60 REAL", "psi_cpu_some_total INTEGER",
+		"psi_mem_some_avg10 REAL", "psi_mem_some_avg60 REAL", "psi_mem_some_total INTEGER",
+		"psi_mem_full_avg10 REAL", "psi_mem_full_avg60 REAL", "psi_mem_full_total INTEGER",
+		"psi_io_some_avg10 REAL", "psi_io_some_avg60 REAL", "psi_io_some_total INTEGER",
+		"psi_io_full_avg10 REAL", "psi_io_full_avg60 REAL", "psi_io_full_total INTEGER",
+	} {
+		_, _ = db.Exec("ALTER TABLE system_metrics ADD COLUMN " + col)
+	}
+
 	return nil
 }
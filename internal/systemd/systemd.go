@@ -0,0 +1,41 @@
+// Package systemd systemd 集成：套接字激活（LISTEN_FDS）与 sd_notify 状态通知
+package systemd
+
+import (
+	"log"
+	"time"
+)
+
+// Watchdog 按 WATCHDOG_USEC 的一半周期喂狗。check 返回错误时暂停喂狗并把原因写入 STATUS，
+// 持续停滞超过 WatchdogSec 后由 systemd 重启服务；未启用看门狗时直接返回
+func Watchdog(stop <-chan struct{}, status string, check func() error) {
+	interval := WatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if err := check(); err != nil {
+			if healthy {
+				log.Printf("停止喂看门狗: %v", err)
+			}
+			healthy = false
+			Notify("STATUS=" + err.Error())
+			continue
+		}
+		if !healthy {
+			log.Println("采集已恢复，继续喂看门狗")
+			healthy = true
+			Notify("STATUS=" + status)
+		}
+		Notify("WATCHDOG=1")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build darwin
+
+package systemd
+
+import (
+	"net"
+	"time"
+)
+
+// Notify 模拟：macOS 下没有 systemd
+func Notify(state string) error {
+	return nil
+}
+
+// WatchdogInterval 模拟：不启用看门狗
+func WatchdogInterval() time.Duration {
+	return 0
+}
+
+// Listeners 模拟：没有套接字激活
+func Listeners() ([]net.Listener, error) {
+	return nil, nil
+}
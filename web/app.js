@@ -86,9 +86,9 @@ function formatAxisSpeed(value) {
 // 获取仪表盘数据
 async function fetchStats() {
   try {
-    const res = await fetch("/api/stats");
+    const res = await fetch("api/stats");
     if (res.status === 401) {
-      window.location.href = "/login";
+      window.location.href = "login";
       return;
     }
     const data = await res.json();
@@ -234,7 +234,7 @@ function renderTrafficProgress(data) {
 // 获取端口流量
 async function fetchPortTraffic() {
   try {
-    const res = await fetch("/api/traffic/ports");
+    const res = await fetch("api/traffic/ports");
     const data = await res.json();
 
     if (!data.ports || data.ports.length === 0) {
@@ -318,9 +318,9 @@ function renderPortMonthGrid(containerId, ports) {
 // 获取系统资源
 async function fetchSystem() {
   try {
-    const res = await fetch("/api/system", { cache: "no-store" });
+    const res = await fetch("api/system", { cache: "no-store" });
     if (res.status === 401) {
-      window.location.href = "/login";
+      window.location.href = "login";
       return;
     }
 
@@ -560,7 +560,7 @@ function pushRealtimePoint(txSpeed, rxSpeed) {
 }
 
 function connectRealtime() {
-  const eventSource = new EventSource("/api/traffic/realtime");
+  const eventSource = new EventSource("api/traffic/realtime");
 
   eventSource.onmessage = (event) => {
     const data = JSON.parse(event.data);
@@ -646,6 +646,18 @@ function initThemeToggle() {
   });
 }
 
+function initLogout() {
+  const btn = document.getElementById("logout-btn");
+  if (!btn) return;
+  btn.addEventListener("click", async () => {
+    try {
+      await fetch("api/logout", { method: "POST" });
+    } finally {
+      window.location.href = "login";
+    }
+  });
+}
+
 function normalizeRange(startVal, endVal) {
   let start = startVal ? String(startVal).trim() : "";
   let end = endVal ? String(endVal).trim() : "";
@@ -674,7 +686,7 @@ function shiftDateValue(dateStr, offsetDays) {
 
 async function fetchLatency(start = null, end = null) {
   try {
-    let url = "/api/latency";
+    let url = "api/latency";
     const range = normalizeRange(start, end);
     setLatencyRecentActive(!range.start && !range.end);
     if (range.start && range.end) {
@@ -683,7 +695,7 @@ async function fetchLatency(start = null, end = null) {
 
     const res = await fetch(url);
     if (res.status === 401) {
-      window.location.href = "/login";
+      window.location.href = "login";
       return;
     }
     latencyData = await res.json();
@@ -1285,7 +1297,7 @@ function updateTrendToggleState() {
 
 async function fetchMonthlyTrend() {
   try {
-    const res = await fetch("/api/traffic/monthly");
+    const res = await fetch("api/traffic/monthly");
     trendMonthlyData = await res.json();
 
     // 空数据保护
@@ -1303,7 +1315,7 @@ async function fetchMonthlyTrend() {
 async function fetchDailyTrend(rangeType) {
   const range = rangeType === "cycle" ? "cycle" : "30d";
   try {
-    const res = await fetch(`/api/traffic/daily?range=${range}`);
+    const res = await fetch(`api/traffic/daily?range=${range}`);
     const data = await res.json();
 
     if (!data || !Array.isArray(data)) {
@@ -1573,6 +1585,7 @@ document.addEventListener("DOMContentLoaded", () => {
   connectRealtime();
   setupTrendToggle();
   initThemeToggle();
+  initLogout();
 
   // 延迟监控时间选择器
   const latencyEndEl = document.getElementById("latency-end");